@@ -221,3 +221,208 @@ func TestRegexByteMatchNextOverlapping(t *testing.T) {
 		t.Fatalf("Third overlapping match should be at index 7, found %d", matches[2])
 	}
 }
+
+func TestRegexByteMatchSharesInput(t *testing.T) {
+	opcode := MustCompile("\xe8(?<rel>[\x00-\xff]{4})\xc3", syntax.ByteRunes)
+
+	rawdata := []byte{0x90, 0x90, 0xe8, 0x10, 0x20, 0x30, 0x40, 0xc3, 0x90}
+	match, err := opcode.FindBytesMatchStartingAt(rawdata, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil {
+		t.Fatalf("Expected a match")
+	}
+	if match.Index != 2 || match.Length != 6 {
+		t.Fatalf("Expected match at (2, 6), got (%d, %d)", match.Index, match.Length)
+	}
+
+	rel := match.GroupByName("rel")
+	if want, got := []byte{0x10, 0x20, 0x30, 0x40}, rel.Bytes(); string(want) != string(got) {
+		t.Fatalf("Capture wanted %x, got %x", want, got)
+	}
+	if &rel.Bytes()[0] != &rawdata[3] {
+		t.Fatalf("Capture bytes should refer back into the input")
+	}
+	if want, got := "\x10\x20\x30\x40", rel.String(); want != got {
+		t.Fatalf("Capture string wanted %q, got %q", want, got)
+	}
+	if want, got := []rune{0x10, 0x20, 0x30, 0x40}, rel.Runes(); string(want) != string(got) {
+		t.Fatalf("Capture runes wanted %v, got %v", want, got)
+	}
+}
+
+func TestRegexByteMatchPrefixAndBackref(t *testing.T) {
+	// literal prefix uses the Boyer-Moore scanner, the backreference uses refmatch
+	opcode := MustCompile("\xff\xfe([\x00-\xff])\\1", syntax.ByteRunes)
+
+	rawdata := []byte{0xff, 0xfe, 0x01, 0x02, 0xff, 0xfe, 0x80, 0x80}
+	match, err := opcode.FindBytesMatchStartingAt(rawdata, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.Index != 4 {
+		t.Fatalf("Expected match at index 4, got %v", match)
+	}
+
+	match, err = opcode.FindNextMatch(match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match != nil {
+		t.Fatalf("Expected no more matches, got one at %d", match.Index)
+	}
+}
+
+func TestRegexByteMatchRightToLeft(t *testing.T) {
+	opcode := MustCompile("\xca[\x00-\xff]", syntax.ByteRunes|syntax.RightToLeft)
+
+	rawdata := []byte{0xca, 0x01, 0x00, 0xca, 0x02, 0x00}
+	matches := []int{}
+	for match, err := opcode.FindBytesMatchStartingAt(rawdata, -1); match != nil; match, err = opcode.FindNextMatch(match) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches = append(matches, match.Index)
+	}
+
+	if len(matches) != 2 || matches[0] != 3 || matches[1] != 0 {
+		t.Fatalf("Expected right-to-left matches at [3 0], got %v", matches)
+	}
+}
+
+func TestRegexByteMaskMatch(t *testing.T) {
+	// push reg (0x50-0x57) followed by a REX prefix (0x40-0x4f) and mov
+	opcode := MustCompile(`\m{F8:50}+\m{F0:40}\x89`, syntax.ByteRunes)
+
+	rawdata := []byte{0x58, 0x55, 0x53, 0x48, 0x89, 0xe5, 0x57, 0x30, 0x89}
+	match, err := opcode.FindBytesMatchStartingAt(rawdata, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.Index != 1 || match.Length != 4 {
+		t.Fatalf("Expected match at index 1 of length 4, got %v", match)
+	}
+
+	match, err = opcode.FindNextMatch(match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match != nil {
+		t.Fatalf("Expected no more matches, got one at %d", match.Index)
+	}
+}
+
+func TestRegexByteMaskInSet(t *testing.T) {
+	opcode := MustCompile(`[\m{0F:05}\x90]+`, syntax.ByteRunes)
+
+	rawdata := []byte{0x01, 0x15, 0x90, 0xf5, 0x06}
+	match, err := opcode.FindBytesMatchStartingAt(rawdata, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.Index != 1 || match.Length != 3 {
+		t.Fatalf("Expected match at index 1 of length 3, got %v", match)
+	}
+
+	opcode = MustCompile(`[^\m{F0:40}]`, syntax.ByteRunes)
+	match, err = opcode.FindBytesMatchStartingAt([]byte{0x41, 0x4f, 0x50}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.Index != 2 {
+		t.Fatalf("Expected match at index 2, got %v", match)
+	}
+}
+
+func TestRegexByteMaskErrors(t *testing.T) {
+	for _, pattern := range []string{`\m`, `\m{F0}`, `\m{F0:}`, `\m{:50}`, `\m{F0:50`, `\m{F0:55}`, `\m{FFF:50}`, `[\m{F0}]`} {
+		if _, err := Compile(pattern, syntax.ByteRunes); err == nil {
+			t.Errorf("Expected an error compiling %q", pattern)
+		}
+	}
+}
+
+func TestRegexByteIntegerLiteral(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		want    []byte
+	}{
+		{`\u32le{0xdeadbeef}`, []byte{0xef, 0xbe, 0xad, 0xde}},
+		{`\u32be{0xdeadbeef}`, []byte{0xde, 0xad, 0xbe, 0xef}},
+		{`\u16le{4660}`, []byte{0x34, 0x12}},
+		{`\u64be{0x0102030405060708}`, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{`\u64le{0x0102030405060708}`, []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+	} {
+		opcode := MustCompile(test.pattern, ByteRunes|IgnoreCase)
+		rawdata := append([]byte{0x00}, test.want...)
+		match, err := opcode.FindBytesMatchStartingAt(rawdata, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match == nil || match.Index != 1 || match.Length != len(test.want) {
+			t.Fatalf("%v: expected match at 1, got %v", test.pattern, match)
+		}
+	}
+}
+
+func TestRegexByteIntegerRange(t *testing.T) {
+	for _, pattern := range []string{`\u16le{0x10fe-0x2103}`, `\u16be{0x10fe-0x2103}`, `\u32le{0x10fe-0x2103}`, `\u16le{4350-8451}`} {
+		for _, rtl := range []bool{false, true} {
+			opt := RegexOptions(ByteRunes)
+			if rtl {
+				opt |= RightToLeft
+			}
+			opcode := MustCompile(`\A`+pattern+`\z`, opt)
+			bigEndian := pattern[4] == 'b'
+			size := 2
+			if pattern[2] == '3' {
+				size = 4
+			}
+
+			for v := 0x1000; v < 0x2200; v++ {
+				rawdata := make([]byte, size)
+				for i := 0; i < size; i++ {
+					b := byte(v >> (8 * i))
+					if bigEndian {
+						rawdata[size-1-i] = b
+					} else {
+						rawdata[i] = b
+					}
+				}
+				match, err := opcode.FindBytesMatchStartingAt(rawdata, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := v >= 0x10fe && v <= 0x2103; (match != nil) != want {
+					t.Fatalf("%v (rtl %v) on %#x: wanted match %v, got %v", pattern, rtl, v, want, match)
+				}
+			}
+		}
+	}
+}
+
+func TestRegexByteIntegerQuantifier(t *testing.T) {
+	opcode := MustCompile(`\u16be{0x0102-0x0103}{2}`, syntax.ByteRunes)
+	match, err := opcode.FindBytesMatchStartingAt([]byte{0x01, 0x02, 0x01, 0x03, 0x01, 0x04}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match == nil || match.Index != 0 || match.Length != 4 {
+		t.Fatalf("Expected a match of length 4 at 0, got %v", match)
+	}
+}
+
+func TestRegexByteIntegerErrors(t *testing.T) {
+	for _, pattern := range []string{`\u16le{0x10000}`, `\u16le{}`, `\u16le{0x10`, `\u16le{5-3}`, `\u16le{x}`, `\u16le{1-}`} {
+		if _, err := Compile(pattern, syntax.ByteRunes); err == nil {
+			t.Errorf("Expected an error compiling %q", pattern)
+		}
+	}
+
+	// without ByteRunes \u32be is still a unicode escape
+	opcode := MustCompile(`\u32be{0x1}`, None)
+	if ok, _ := opcode.MatchString("\u32be{0x1}"); !ok {
+		t.Fatalf("Expected \\u32be to be a unicode escape")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Binexp-gen generates Go code for the backtracking programs of binexp patterns, so they run as
+straight-line Go rather than being interpreted one instruction at a time.
+
+Usage:
+
+	binexp-gen [flags] [pattern ...]
+
+The flags are:
+
+	-f file
+		read patterns from file, one per line, skipping blank lines and lines starting with #
+	-hex
+		the patterns are YARA-style hex strings, as for binexp.CompileHex
+	-options letters
+		the options to compile the patterns with, as letters: i (IgnoreCase), m (Multiline),
+		n (ExplicitCapture), s (Singleline), x (IgnorePatternWhitespace), r (RightToLeft),
+		e (ECMAScript) and b (ByteRunes)
+	-o file
+		write the generated code to file rather than standard output
+	-pkg name
+		the package of the generated code (default "main")
+
+The generated file registers its programs when its package is initialized.  A pattern compiled
+with the same options plus binexp.Compiled then runs its generated program, with the same
+results and the same limits as the interpreter:
+
+	//go:generate binexp-gen -pkg sigs -options b -f signatures.txt -o signatures_gen.go
+
+	re := binexp.MustCompile(`\x55\x48\x89\xe5(?:\x41[\x54-\x57])+`, binexp.ByteRunes|binexp.Compiled)
+
+A program only applies to the exact program the package compiles a pattern to, so after
+upgrading binexp the code should be generated again, until which the patterns are interpreted.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/polyverse/binexp"
+)
+
+var optionLetters = map[rune]binexp.RegexOptions{
+	'i': binexp.IgnoreCase,
+	'm': binexp.Multiline,
+	'n': binexp.ExplicitCapture,
+	's': binexp.Singleline,
+	'x': binexp.IgnorePatternWhitespace,
+	'r': binexp.RightToLeft,
+	'e': binexp.ECMAScript,
+	'b': binexp.ByteRunes,
+}
+
+func main() {
+	file := flag.String("f", "", "read patterns from `file`, one per line")
+	hex := flag.Bool("hex", false, "the patterns are YARA-style hex strings")
+	options := flag.String("options", "", "the options to compile the patterns with, as `letters` from imnsxreb")
+	out := flag.String("o", "", "write the generated code to `file` rather than standard output")
+	pkg := flag.String("pkg", "main", "the package of the generated code")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: binexp-gen [flags] [pattern ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*file, *hex, *options, *out, *pkg, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "binexp-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, hex bool, options, out, pkg string, patterns []string) error {
+	var opt binexp.RegexOptions
+	for _, letter := range options {
+		o, ok := optionLetters[letter]
+		if !ok {
+			return fmt.Errorf("unknown option %q", letter)
+		}
+		opt |= o
+	}
+
+	if file != "" {
+		read, err := readPatterns(file)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, read...)
+	}
+	if len(patterns) == 0 {
+		return errors.New("no patterns")
+	}
+
+	res := make([]*binexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		var err error
+		if hex {
+			res[i], err = binexp.CompileHex(pattern, opt)
+		} else {
+			res[i], err = binexp.Compile(pattern, opt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	src := &bytes.Buffer{}
+	if err := binexp.GenerateGo(src, pkg, res); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err := os.Stdout.Write(src.Bytes())
+		return err
+	}
+	return os.WriteFile(out, src.Bytes(), 0666)
+}
+
+// readPatterns reads the patterns in file, one per line, skipping blank lines and comments
+func readPatterns(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,282 @@
+/*
+Binexp searches files for a binexp pattern, like grep for binary data, printing the offset
+of each match with a hex dump of it and of the groups it captured.
+
+Usage:
+
+	binexp [flags] pattern [file ...]
+
+With no files, or a file of -, standard input is searched.  Files are read as they're
+searched, a page at a time, rather than loaded whole.  The flags are:
+
+	-hex
+		the pattern is a YARA-style hex string, as for binexp.CompileHex
+	-options letters
+		the options to compile the pattern with, as letters: i (IgnoreCase), m (Multiline),
+		n (ExplicitCapture), s (Singleline), x (IgnorePatternWhitespace), r (RightToLeft),
+		e (ECMAScript) and b (ByteRunes) (default "bs", so . matches any byte)
+	-overlap
+		find overlapping matches, starting each search one byte past the start of the last match
+	-m count
+		stop after count matches in each file
+	-A count, -B count, -C count
+		dump count bytes of context after, before, or both sides of each match
+	-r
+		search the files in directories, recursively
+	-offsets
+		print just the offsets and lengths of the matches, without the hex dumps
+	-color
+		color the matches and their groups in the hex dumps, rather than bracketing them
+	-timeout duration
+		give up on a file once it has been searched for duration, however many matches
+		have been found in it
+
+Each match is printed as its offset and length, prefixed with the file's name when more than
+one file is searched, followed by its hex dump from binexp.Match.HexDump, which brackets the
+match and each capture and then lists the captures:
+
+	$ binexp -C 4 '\xe8(?<rel32>.{4})' sample.bin
+	0x8 length 5
+	00000000              6f 00 01 02 [e8[3f ff ff ff]]48 8b 05 |    o....?...H..|
+	00000010  9d                                                |.               |
+	  rel32 0x9 length 4: 3f ff ff ff
+
+As with grep, the exit status is 0 if there were matches, 1 if there weren't and 2 if there
+was an error.
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/polyverse/binexp"
+)
+
+var optionLetters = map[rune]binexp.RegexOptions{
+	'i': binexp.IgnoreCase,
+	'm': binexp.Multiline,
+	'n': binexp.ExplicitCapture,
+	's': binexp.Singleline,
+	'x': binexp.IgnorePatternWhitespace,
+	'r': binexp.RightToLeft,
+	'e': binexp.ECMAScript,
+	'b': binexp.ByteRunes,
+}
+
+// grep is a search of some files for a pattern
+type grep struct {
+	re      *binexp.Regexp
+	overlap bool
+	max     int
+	before  int
+	after   int
+	offsets bool
+	color   bool
+	// names is true when the matches are prefixed with the name of the file
+	names bool
+	// timeout limits the time spent searching each file, if it's more than 0
+	timeout time.Duration
+
+	stdin  io.Reader
+	w      io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs binexp with the command line arguments args, returning the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("binexp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	hex := flags.Bool("hex", false, "the pattern is a YARA-style hex string")
+	options := flags.String("options", "bs", "the options to compile the pattern with, as `letters` from imnsxreb")
+	overlap := flags.Bool("overlap", false, "find overlapping matches")
+	count := flags.Int("m", -1, "stop after `count` matches in each file")
+	after := flags.Int("A", 0, "dump `count` bytes of context after each match")
+	before := flags.Int("B", 0, "dump `count` bytes of context before each match")
+	around := flags.Int("C", 0, "dump `count` bytes of context around each match")
+	recursive := flags.Bool("r", false, "search the files in directories, recursively")
+	offsets := flags.Bool("offsets", false, "print just the offsets and lengths of the matches")
+	color := flags.Bool("color", false, "color the matches and their groups in the hex dumps")
+	timeout := flags.Duration("timeout", 0, "give up on a file after the search has run for `duration`")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: binexp [flags] pattern [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	re, err := compile(flags.Arg(0), *hex, *options)
+	if err != nil {
+		fmt.Fprintf(stderr, "binexp: %v\n", err)
+		return 2
+	}
+
+	g := &grep{
+		re:      re,
+		overlap: *overlap,
+		max:     *count,
+		before:  max(*before, *around),
+		after:   max(*after, *around),
+		offsets: *offsets,
+		color:   *color,
+		names:   flags.NArg() > 2 || *recursive,
+		timeout: *timeout,
+		stdin:   stdin,
+		w:       stdout,
+		stderr:  stderr,
+	}
+	files := flags.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	matched, failed := false, false
+	for _, file := range files {
+		m, err := g.searchPath(file, *recursive)
+		matched = matched || m
+		if err != nil {
+			failed = true
+		}
+	}
+	switch {
+	case failed:
+		return 2
+	case !matched:
+		return 1
+	}
+	return 0
+}
+
+// compile compiles pattern with the options given as letters
+func compile(pattern string, hex bool, options string) (*binexp.Regexp, error) {
+	var opt binexp.RegexOptions
+	for _, letter := range options {
+		o, ok := optionLetters[letter]
+		if !ok {
+			return nil, fmt.Errorf("unknown option %q", letter)
+		}
+		opt |= o
+	}
+	if hex {
+		return binexp.CompileHex(pattern, opt)
+	}
+	return binexp.Compile(pattern, opt)
+}
+
+// searchPath searches the file at path, or standard input for -, walking it if it's a
+// directory and recursive is set.  Errors are reported as they happen, and the last one
+// is returned once every file has been searched.
+func (g *grep) searchPath(path string, recursive bool) (matched bool, err error) {
+	if path == "-" {
+		// standard input may be a pipe, which can't be read at an offset
+		data, err := io.ReadAll(g.stdin)
+		if err == nil {
+			matched, err = g.search("(standard input)", bytes.NewReader(data), int64(len(data)))
+		}
+		return matched, g.report(err)
+	}
+	if !recursive {
+		matched, err = g.searchFile(path)
+		return matched, g.report(err)
+	}
+
+	var last error
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			var m bool
+			m, err = g.searchFile(p)
+			matched = matched || m
+		}
+		if err != nil {
+			last = g.report(err)
+		}
+		return nil
+	})
+	return matched, last
+}
+
+// searchFile searches the file at path, reading it as the search goes rather than all at once
+func (g *grep) searchFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return false, fmt.Errorf("%v: is a directory", path)
+	}
+	return g.search(path, f, info.Size())
+}
+
+// report prints err, if there is one, and returns it
+func (g *grep) report(err error) error {
+	if err != nil {
+		fmt.Fprintf(g.stderr, "binexp: %v\n", err)
+	}
+	return err
+}
+
+// search prints the matches in the first size bytes of r, which was opened from the file
+// name, as they're found
+func (g *grep) search(name string, r io.ReaderAt, size int64) (bool, error) {
+	if g.max == 0 {
+		return false, nil
+	}
+	ctx := context.Background()
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	count := 0
+	m, err := g.re.FindReaderAtMatchContext(ctx, r, size, -1)
+	for m != nil && err == nil {
+		prefix := ""
+		if g.names {
+			prefix = name + ":"
+		}
+		fmt.Fprintf(g.w, "%v%#x length %v\n", prefix, m.Index, m.Length)
+		if !g.offsets {
+			io.WriteString(g.w, m.HexDump(g.before, g.after, g.color))
+		}
+
+		if count++; count == g.max {
+			break
+		}
+		if g.overlap {
+			m, err = g.re.FindNextOverlappingMatchContext(ctx, m)
+		} else {
+			m, err = g.re.FindNextMatchContext(ctx, m)
+		}
+	}
+	if err != nil {
+		return count > 0, fmt.Errorf("%v: %w", name, err)
+	}
+	return count > 0, nil
+}
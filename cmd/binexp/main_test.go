@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	data := []byte("hello\x00\x01\x02\xe8\x3f\xff\xff\xff\x48\x8b\x05\x9d world\xe8\x01\x02\x03\x04")
+	re, err := compile(`\xe8(?<rel32>.{4})|(o)`, false, "bs")
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	var tests = []struct {
+		g    grep
+		want string
+	}{
+		{grep{max: -1, offsets: true}, "0x4 length 1\n0x8 length 5\n0x13 length 1\n0x17 length 5\n"},
+		{grep{max: 1}, "0x4 length 1\n" +
+			"00000000             [[6f]]                                  |    o           |\n" +
+			"  1 0x4 length 1: 6f\n"},
+		{grep{max: 2, before: 2, after: 9, names: true}, "sample.bin:0x4 length 1\n" +
+			"00000000        6c 6c[[6f]]00 01 02  e8 3f ff ff ff 48       |  llo....?...H  |\n" +
+			"  1 0x4 length 1: 6f\n" +
+			"sample.bin:0x8 length 5\n" +
+			"00000000                    01 02 [e8[3f ff ff ff]]48 8b 05 |      ...?...H..|\n" +
+			"00000010  9d 20 77 6f 72 6c                                 |. worl          |\n" +
+			"  rel32 0x9 length 4: 3f ff ff ff\n"},
+		{grep{max: -1, offsets: true, before: 100, after: 100}, "0x4 length 1\n0x8 length 5\n0x13 length 1\n0x17 length 5\n"},
+	}
+	for i, test := range tests {
+		buf := &bytes.Buffer{}
+		test.g.re, test.g.w = re, buf
+		matched, err := test.g.search("sample.bin", bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%v: unexpected err: %v", i, err)
+		}
+		if !matched || buf.String() != test.want {
+			t.Errorf("%v: expected\n%v\ngot\n%v", i, test.want, buf.String())
+		}
+	}
+
+	// the context stops at the ends of the data
+	buf := &bytes.Buffer{}
+	g := grep{re: re, max: -1, before: 100, after: 100, w: buf}
+	if _, err := g.search("sample.bin", bytes.NewReader(data[:6]), 6); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if want := "0x4 length 1\n" +
+		"00000000  68 65 6c 6c[[6f]]00                                |hello.          |\n" +
+		"  1 0x4 length 1: 6f\n"; buf.String() != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, buf.String())
+	}
+
+	if _, err := compile("a", false, "bq"); err == nil {
+		t.Errorf("Expected an error for an unknown option")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"a.bin":         "xx\xe8abcd",
+		"sub/b.bin":     "\xe8wxyz\xe8",
+		"sub/sub/c.bin": "nothing",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(dir, "a.bin"), filepath.Join(dir, "sub", "b.bin")
+
+	var tests = []struct {
+		args   []string
+		stdin  string
+		status int
+		want   string
+	}{
+		{[]string{"-offsets", `\xe8.{4}`, a}, "", 0, "0x2 length 5\n"},
+		{[]string{"-offsets", `\xe8.{4}`, a, b}, "", 0, a + ":0x2 length 5\n" + b + ":0x0 length 5\n"},
+		{[]string{"-offsets", "-r", `\xe8.{4}`, dir}, "", 0, a + ":0x2 length 5\n" + b + ":0x0 length 5\n"},
+		{[]string{"-offsets", "-r", `\xe8`, filepath.Join(dir, "sub")}, "", 0, b + ":0x0 length 1\n" + b + ":0x5 length 1\n"},
+		{[]string{"-offsets", "-m", "1", "-r", `\xe8`, filepath.Join(dir, "sub")}, "", 0, b + ":0x0 length 1\n"},
+		{[]string{"-offsets", "-m", "0", `\xe8`, a}, "", 1, ""},
+		{[]string{"-offsets", `\xe8`}, "ab\xe8", 0, "0x2 length 1\n"},
+		{[]string{"-offsets", `\xe8`, "-"}, "\xe8", 0, "0x0 length 1\n"},
+		{[]string{"-offsets", "-overlap", "aa"}, "aaaa", 0, "0x0 length 2\n0x1 length 2\n0x2 length 2\n"},
+		{[]string{"-offsets", "-hex", "e8 ?? 62", a}, "", 0, "0x2 length 3\n"},
+		{[]string{"-offsets", `\xe9`, a}, "", 1, ""},
+		{[]string{"-offsets", "-r", `\xe9`, dir}, "", 1, ""},
+		{[]string{"-offsets", `\xe8`, filepath.Join(dir, "missing.bin"), a}, "", 2, a + ":0x2 length 1\n"},
+		{[]string{"-offsets", `\xe8`, dir}, "", 2, ""},
+		{[]string{"-offsets", `(`, a}, "", 2, ""},
+		{[]string{"-options", "q", `a`, a}, "", 2, ""},
+		{[]string{"-offsets"}, "", 2, ""},
+		{[]string{"-unknown", `a`}, "", 2, ""},
+		// the search of the file is given up once the timeout has passed
+		{[]string{"-offsets", "-timeout", "10ms", `(?=.)(.+)*\?`}, strings.Repeat("a", 100), 2, ""},
+	}
+	for i, test := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		status := run(test.args, strings.NewReader(test.stdin), stdout, stderr)
+		if status != test.status || stdout.String() != test.want {
+			t.Errorf("%v: %q expected status %v and\n%v\ngot status %v and\n%v", i, test.args, test.status, test.want, status, stdout.String())
+		}
+		if (status == 2) != (stderr.Len() > 0) {
+			t.Errorf("%v: %q unexpected stderr for status %v: %q", i, test.args, status, stderr.String())
+		}
+	}
+}
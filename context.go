@@ -0,0 +1,128 @@
+package binexp
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// The Context variants of the search methods stop when ctx is done, returning ctx.Err(), so
+// a search over a large input can be abandoned part way through.  The context is checked
+// along with the MatchTimeout, which still applies as well.
+
+// FindStringMatchContext is like FindStringMatchStartingAt but stops when ctx is done
+func (re *Regexp) FindStringMatchContext(ctx context.Context, s string, startAt int) (*Match, error) {
+	return re.findStringMatch(ctx, s, startAt)
+}
+
+// FindBytesMatchContext is like FindBytesMatchStartingAt but stops when ctx is done
+func (re *Regexp) FindBytesMatchContext(ctx context.Context, b []byte, startAt int) (*Match, error) {
+	return re.findBytesMatch(ctx, b, startAt)
+}
+
+// FindReaderAtMatchContext is like FindReaderAtMatchStartingAt but stops when ctx is done
+func (re *Regexp) FindReaderAtMatchContext(ctx context.Context, r io.ReaderAt, size int64, startAt int) (*Match, error) {
+	return re.findReaderAtMatch(ctx, r, size, startAt)
+}
+
+// FindNextMatchContext is like FindNextMatch but stops when ctx is done
+func (re *Regexp) FindNextMatchContext(ctx context.Context, m *Match) (*Match, error) {
+	return re.findNextMatch(ctx, m)
+}
+
+// FindNextOverlappingMatchContext is like FindNextOverlappingMatch but stops when ctx is done
+func (re *Regexp) FindNextOverlappingMatchContext(ctx context.Context, m *Match) (*Match, error) {
+	return re.findNextOverlappingMatch(ctx, m)
+}
+
+// MatchStringContext is like MatchString but stops when ctx is done
+func (re *Regexp) MatchStringContext(ctx context.Context, s string) (bool, error) {
+	return re.matchString(ctx, s)
+}
+
+// FindAllStringMatchContext is like FindAllStringMatch but stops when ctx is done
+func (re *Regexp) FindAllStringMatchContext(ctx context.Context, s string, n int, overlapping bool) ([]*Match, error) {
+	var matches []*Match
+	err := re.eachMatch(ctx, getRunes(s), nil, n, overlapping, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// FindAllBytesMatchContext is like FindAllBytesMatch but stops when ctx is done
+func (re *Regexp) FindAllBytesMatchContext(ctx context.Context, b []byte, n int, overlapping bool) ([]*Match, error) {
+	var matches []*Match
+	err := re.eachMatch(ctx, re.bytesToRunes(b), b, n, overlapping, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// AllStringMatchesContext is like AllStringMatches but stops when ctx is done, ending the
+// sequence with ctx.Err()
+func (re *Regexp) AllStringMatchesContext(ctx context.Context, s string, n int, overlapping bool) iter.Seq2[*Match, error] {
+	return re.allMatches(ctx, getRunes(s), nil, n, overlapping)
+}
+
+// AllBytesMatchesContext is like AllBytesMatches but stops when ctx is done, ending the
+// sequence with ctx.Err()
+func (re *Regexp) AllBytesMatchesContext(ctx context.Context, b []byte, n int, overlapping bool) iter.Seq2[*Match, error] {
+	return re.allMatches(ctx, re.bytesToRunes(b), b, n, overlapping)
+}
+
+// FindReaderMatchesContext is like FindReaderMatches but stops when ctx is done
+func (re *Regexp) FindReaderMatchesContext(ctx context.Context, rd io.Reader, chunkSize, window int, fn func(*Match) bool) error {
+	return re.findReaderMatches(ctx, rd, chunkSize, window, fn)
+}
+
+// ReplaceContext is like Replace but stops when ctx is done
+func (re *Regexp) ReplaceContext(ctx context.Context, input, replacement string, startAt, count int) (string, error) {
+	data, err := re.replacerData(replacement)
+	if err != nil {
+		return "", err
+	}
+	return replace(ctx, re, data, nil, input, startAt, count)
+}
+
+// ReplaceFuncContext is like ReplaceFunc but stops when ctx is done
+func (re *Regexp) ReplaceFuncContext(ctx context.Context, input string, evaluator MatchEvaluator, startAt, count int) (string, error) {
+	return replace(ctx, re, nil, evaluator, input, startAt, count)
+}
+
+// SplitContext is like Split but stops when ctx is done
+func (re *Regexp) SplitContext(ctx context.Context, input string, count, startAt int) ([]string, error) {
+	return re.splitString(ctx, input, count, startAt)
+}
+
+// SplitBytesContext is like SplitBytes but stops when ctx is done
+func (re *Regexp) SplitBytesContext(ctx context.Context, input []byte, count, startAt int) ([][]byte, error) {
+	return re.splitBytes(ctx, input, count, startAt)
+}
+
+// FindStringMatchesContext is like FindStringMatches but stops when ctx is done
+func (s *RegexSet) FindStringMatchesContext(ctx context.Context, input string, fn func(pattern int, m *Match) bool) error {
+	return s.each(ctx, getRunes(input), nil, false, fn)
+}
+
+// FindBytesMatchesContext is like FindBytesMatches but stops when ctx is done
+func (s *RegexSet) FindBytesMatchesContext(ctx context.Context, b []byte, fn func(pattern int, m *Match) bool) error {
+	return s.findBytesMatches(ctx, b, fn)
+}
+
+// MatchStringContext is like MatchString but stops when ctx is done
+func (s *RegexSet) MatchStringContext(ctx context.Context, input string) ([]int, error) {
+	return s.matched(ctx, getRunes(input), nil)
+}
+
+// MatchBytesContext is like MatchBytes but stops when ctx is done
+func (s *RegexSet) MatchBytesContext(ctx context.Context, b []byte) ([]int, error) {
+	return s.matchBytes(ctx, b)
+}
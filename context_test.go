@@ -0,0 +1,160 @@
+package binexp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextCancelledBeforeSearch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// one pattern for the backtracking engine and one for the NFA
+	for _, pattern := range []string{`(\w)\1`, `\w+`} {
+		re := MustCompile(pattern, ByteRunes)
+
+		if _, err := re.FindStringMatchContext(ctx, "aa", -1); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: FindStringMatchContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.FindBytesMatchContext(ctx, []byte("aa"), 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: FindBytesMatchContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.FindReaderAtMatchContext(ctx, bytes.NewReader([]byte("aa")), 2, -1); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: FindReaderAtMatchContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.MatchStringContext(ctx, "aa"); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: MatchStringContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.FindAllBytesMatchContext(ctx, []byte("aa"), -1, false); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: FindAllBytesMatchContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.ReplaceContext(ctx, "aa", "b", -1, -1); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: ReplaceContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.SplitContext(ctx, "aa", -1, 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: SplitContext expected context.Canceled, got %v", pattern, err)
+		}
+		if _, err := re.SplitBytesContext(ctx, []byte("aa"), -1, 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: SplitBytesContext expected context.Canceled, got %v", pattern, err)
+		}
+		var seqErr error
+		for _, seqErr = range re.AllStringMatchesContext(ctx, "aa", -1, true) {
+		}
+		if !errors.Is(seqErr, context.Canceled) {
+			t.Errorf("%q: AllStringMatchesContext expected context.Canceled, got %v", pattern, seqErr)
+		}
+		for _, seqErr = range re.AllBytesMatchesContext(ctx, []byte("aa"), -1, false) {
+		}
+		if !errors.Is(seqErr, context.Canceled) {
+			t.Errorf("%q: AllBytesMatchesContext expected context.Canceled, got %v", pattern, seqErr)
+		}
+		m, err := re.FindStringMatch("aa")
+		if err != nil || m == nil {
+			t.Fatalf("%q: Expected a match, got %v, %v", pattern, m, err)
+		}
+		if _, err := re.FindNextOverlappingMatchContext(ctx, m); !errors.Is(err, context.Canceled) {
+			t.Errorf("%q: FindNextOverlappingMatchContext expected context.Canceled, got %v", pattern, err)
+		}
+	}
+
+	set := MustCompileSet([]string{`a`, `(\w)\1`}, 0)
+	if _, err := set.MatchStringContext(ctx, "aa"); !errors.Is(err, context.Canceled) {
+		t.Errorf("RegexSet.MatchStringContext expected context.Canceled, got %v", err)
+	}
+}
+
+func TestContextDeadlineStopsBacktracking(t *testing.T) {
+	// the lookahead keeps this on the backtracking engine, where it takes exponential time
+	re := MustCompile(`(?=.)(.+)*\?`, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := re.FindStringMatchContext(ctx, strings.Repeat("a", 100), -1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Search took %v to stop", elapsed)
+	}
+}
+
+func TestContextCancelledDuringIteration(t *testing.T) {
+	re := MustCompile(`a`, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	_, err := re.ReplaceFuncContext(ctx, "aaaa", func(m Match) string {
+		calls++
+		cancel()
+		return "b"
+	}, -1, -1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReplaceFuncContext expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the evaluator to be called once, got %v", calls)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	calls = 0
+	err = re.FindReaderMatchesContext(ctx, strings.NewReader("xaxxaxxxaxxxxa"), 4, 0, func(m *Match) bool {
+		calls++
+		cancel()
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FindReaderMatchesContext expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected fn to be called once, got %v", calls)
+	}
+}
+
+func TestContextBackground(t *testing.T) {
+	re := MustCompile(`(a)(b)?`, 0)
+	got, err := re.ReplaceContext(context.Background(), "ab a c", "[$1$2]", -1, -1)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if want := "[ab] [a] c"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// the three kinds of input take a starting point the same way
+	for _, find := range []func(startAt int) (*Match, error){
+		func(startAt int) (*Match, error) {
+			return re.FindStringMatchContext(context.Background(), "xab a", startAt)
+		},
+		func(startAt int) (*Match, error) {
+			return re.FindBytesMatchContext(context.Background(), []byte("xab a"), startAt)
+		},
+		func(startAt int) (*Match, error) {
+			return re.FindReaderAtMatchContext(context.Background(), strings.NewReader("xab a"), 5, startAt)
+		},
+	} {
+		if m, err := find(2); err != nil || m == nil || m.Index != 4 {
+			t.Errorf("Expected a match at 4, got %v, %v", m, err)
+		}
+	}
+	if _, err := re.FindStringMatchContext(context.Background(), "xéb a", 2); err == nil {
+		t.Errorf("Expected an error for a startAt in the middle of a rune")
+	}
+
+	m, err := re.FindStringMatchContext(context.Background(), "xab a", -1)
+	for i := 0; m != nil; i++ {
+		if i == 2 {
+			t.Fatalf("Expected two matches")
+		}
+		m, err = re.FindNextMatchContext(context.Background(), m)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+}
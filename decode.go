@@ -0,0 +1,142 @@
+package binexp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ReadBytes returns the captured text with each rune taken as one byte, which is how
+// binary input is matched.  Unlike Bytes, it returns an error if the text can't be read
+// back from the io.ReaderAt the match was made against, and for a match against a string
+// it gives the runes as bytes rather than UTF-8 encoding them, returning an error if there
+// are runes past 0xFF.
+func (c *Capture) ReadBytes() ([]byte, error) {
+	start := c.Index - c.offset
+	if c.paged != nil {
+		return c.paged.readAt(start, start+c.Length)
+	}
+	if c.text != nil && c.src == nil {
+		b, i := runeBytes(c.text[start : start+c.Length])
+		if i >= 0 {
+			return nil, fmt.Errorf("capture holds rune %U at %v which isn't a byte", c.text[start+i], c.Index+i)
+		}
+		return b, nil
+	}
+	return c.Bytes(), nil
+}
+
+// fixedBytes returns the captured bytes if there are exactly n of them
+func (c *Capture) fixedBytes(n int) ([]byte, error) {
+	if c.Length != n {
+		return nil, fmt.Errorf("capture is %v bytes long, wanted %v", c.Length, n)
+	}
+	return c.ReadBytes()
+}
+
+// Uint8 returns the captured byte.  It returns an error unless exactly one byte was captured.
+func (c *Capture) Uint8() (uint8, error) {
+	b, err := c.fixedBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// Int8 returns the captured byte as a signed integer
+func (c *Capture) Int8() (int8, error) {
+	v, err := c.Uint8()
+	return int8(v), err
+}
+
+// Uint16LE decodes the captured bytes as a little-endian uint16.  It returns an
+// error unless exactly 2 bytes were captured.
+func (c *Capture) Uint16LE() (uint16, error) {
+	b, err := c.fixedBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// Uint16BE decodes the captured bytes as a big-endian uint16
+func (c *Capture) Uint16BE() (uint16, error) {
+	b, err := c.fixedBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// Int16LE decodes the captured bytes as a little-endian int16
+func (c *Capture) Int16LE() (int16, error) {
+	v, err := c.Uint16LE()
+	return int16(v), err
+}
+
+// Int16BE decodes the captured bytes as a big-endian int16
+func (c *Capture) Int16BE() (int16, error) {
+	v, err := c.Uint16BE()
+	return int16(v), err
+}
+
+// Uint32LE decodes the captured bytes as a little-endian uint32.  It returns an
+// error unless exactly 4 bytes were captured.
+func (c *Capture) Uint32LE() (uint32, error) {
+	b, err := c.fixedBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// Uint32BE decodes the captured bytes as a big-endian uint32
+func (c *Capture) Uint32BE() (uint32, error) {
+	b, err := c.fixedBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// Int32LE decodes the captured bytes as a little-endian int32
+func (c *Capture) Int32LE() (int32, error) {
+	v, err := c.Uint32LE()
+	return int32(v), err
+}
+
+// Int32BE decodes the captured bytes as a big-endian int32
+func (c *Capture) Int32BE() (int32, error) {
+	v, err := c.Uint32BE()
+	return int32(v), err
+}
+
+// Uint64LE decodes the captured bytes as a little-endian uint64.  It returns an
+// error unless exactly 8 bytes were captured.
+func (c *Capture) Uint64LE() (uint64, error) {
+	b, err := c.fixedBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// Uint64BE decodes the captured bytes as a big-endian uint64
+func (c *Capture) Uint64BE() (uint64, error) {
+	b, err := c.fixedBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// Int64LE decodes the captured bytes as a little-endian int64
+func (c *Capture) Int64LE() (int64, error) {
+	v, err := c.Uint64LE()
+	return int64(v), err
+}
+
+// Int64BE decodes the captured bytes as a big-endian int64
+func (c *Capture) Int64BE() (int64, error) {
+	v, err := c.Uint64BE()
+	return int64(v), err
+}
@@ -0,0 +1,129 @@
+package binexp
+
+import (
+	"testing"
+)
+
+func TestCapture_Decode(t *testing.T) {
+	re := MustCompile(`\xe8(?<rel>[\x00-\xff]{4})(?<b>[\x00-\xff])(?<w>[\x00-\xff]{2})(?<q>[\x00-\xff]{8})`, ByteRunes)
+	data := []byte{0x90, 0xe8, 0xfb, 0xff, 0xff, 0xff, 0x80, 0x34, 0x12, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	m, err := re.FindBytesMatchStartingAt(data, 0)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+
+	rel := m.GroupByName("rel")
+	if v, err := rel.Int32LE(); err != nil || v != -5 {
+		t.Fatalf("Int32LE: wanted -5, got %v, %v", v, err)
+	}
+	if v, err := rel.Uint32LE(); err != nil || v != 0xfffffffb {
+		t.Fatalf("Uint32LE: wanted 0xfffffffb, got %#x, %v", v, err)
+	}
+	if v, err := rel.Uint32BE(); err != nil || v != 0xfbffffff {
+		t.Fatalf("Uint32BE: wanted 0xfbffffff, got %#x, %v", v, err)
+	}
+	if v, err := m.GroupByName("b").Int8(); err != nil || v != -128 {
+		t.Fatalf("Int8: wanted -128, got %v, %v", v, err)
+	}
+	if v, err := m.GroupByName("w").Uint16LE(); err != nil || v != 0x1234 {
+		t.Fatalf("Uint16LE: wanted 0x1234, got %#x, %v", v, err)
+	}
+	if v, err := m.GroupByName("w").Int16BE(); err != nil || v != 0x3412 {
+		t.Fatalf("Int16BE: wanted 0x3412, got %#x, %v", v, err)
+	}
+	if v, err := m.GroupByName("q").Uint64LE(); err != nil || v != 0x0807060504030201 {
+		t.Fatalf("Uint64LE: wanted 0x0807060504030201, got %#x, %v", v, err)
+	}
+	if v, err := m.GroupByName("q").Uint64BE(); err != nil || v != 0x0102030405060708 {
+		t.Fatalf("Uint64BE: wanted 0x0102030405060708, got %#x, %v", v, err)
+	}
+
+	// the length has to fit
+	if _, err := rel.Uint16LE(); err == nil {
+		t.Fatalf("Expected an error decoding 4 bytes as a uint16")
+	}
+	if _, err := rel.Uint64LE(); err == nil {
+		t.Fatalf("Expected an error decoding 4 bytes as a uint64")
+	}
+}
+
+func TestCapture_DecodeRunes(t *testing.T) {
+	re := MustCompile(`(..)`, None)
+
+	// bytes searched as runes still decode
+	m, err := re.FindBytesMatchStartingAt([]byte{0xff, 0x01}, 0)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if v, err := m.GroupByNumber(1).Uint16LE(); err != nil || v != 0x01ff {
+		t.Fatalf("Uint16LE: wanted 0x01ff, got %#x, %v", v, err)
+	}
+
+	// but a string with wide runes doesn't
+	m, err = re.FindStringMatch("é!")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if _, err := m.GroupByNumber(1).Uint16LE(); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	m, err = re.FindStringMatch("€!")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if _, err := m.GroupByNumber(1).Uint16LE(); err == nil {
+		t.Fatalf("Expected an error decoding a rune past 0xFF")
+	}
+}
+
+func TestCapture_BytesAndString(t *testing.T) {
+	data := []byte{0x41, 0xff, 0xe8}
+
+	// bytes come back as they were however they were searched, and String follows the input
+	for _, test := range []struct {
+		opt RegexOptions
+		str string
+	}{
+		{ByteRunes, "A\xff\xe8"},
+		{None, "Aÿè"},
+	} {
+		re := MustCompile(`A.(.)`, test.opt|Singleline)
+		m, err := re.FindBytesMatchStartingAt(append(data, data...), 0)
+		if err != nil || m == nil {
+			t.Fatalf("opt %v: expected a match, got %v, %v", test.opt, m, err)
+		}
+		if b := m.Bytes(); string(b) != string(data) {
+			t.Errorf("opt %v: Bytes wanted %x, got %x", test.opt, data, b)
+		}
+		if s := m.String(); s != test.str {
+			t.Errorf("opt %v: String wanted %q, got %q", test.opt, test.str, s)
+		}
+
+		// so do the groups' and the next match's
+		if b := m.GroupByNumber(1).Bytes(); string(b) != "\xe8" {
+			t.Errorf("opt %v: group Bytes wanted e8, got %x", test.opt, b)
+		}
+		if m, err = re.FindNextMatch(m); err != nil || m == nil {
+			t.Fatalf("opt %v: expected a second match, got %v, %v", test.opt, m, err)
+		}
+		if b, err := m.ReadBytes(); string(b) != string(data) || err != nil {
+			t.Errorf("opt %v: ReadBytes wanted %x, got %x, %v", test.opt, data, b, err)
+		}
+	}
+
+	// a string's text comes back UTF-8 encoded, while ReadBytes takes each rune as a byte
+	m, err := MustCompile(`..`, None).FindStringMatch("ÿ€")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if b := m.Bytes(); string(b) != "ÿ€" {
+		t.Errorf("Bytes wanted the UTF-8 of the text, got %x", b)
+	}
+	if _, err := m.ReadBytes(); err == nil {
+		t.Errorf("Expected ReadBytes to fail on a rune past 0xFF")
+	}
+	if s := m.String(); s != "ÿ€" {
+		t.Errorf("String wanted the text, got %q", s)
+	}
+}
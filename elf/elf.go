@@ -0,0 +1,150 @@
+/*
+Package elf searches the sections and segments of ELF executables with a binexp Regexp.
+
+Matches come back annotated with where they were found, so they can be tied back to the
+file and to the running program:
+
+	f, _ := goelf.Open("/bin/ls")
+	re := binexp.MustCompileHex("E8 ?? ?? ?? ??", 0)
+	elf.ScanSections(f, re, elf.ExecutableSections, func(m *elf.Match) bool {
+		fmt.Printf("%v %#x: call\n", m.Section, m.Addr)
+		return true
+	})
+*/
+package elf
+
+import (
+	"bytes"
+	goelf "debug/elf"
+	"io"
+
+	"github.com/polyverse/binexp"
+)
+
+// Match is a match found in an ELF file.  The embedded Match's Index is relative to
+// the start of the section or segment that was searched.
+type Match struct {
+	*binexp.Match
+
+	// Section is the name of the section the match was found in, or "" when scanning segments
+	Section string
+	// Segment is the index of the program header the match was found in, or -1 when scanning sections
+	Segment int
+	// Offset is the position of the match in the file, or -1 if the section is compressed
+	Offset int64
+	// Addr is the virtual address of the match
+	Addr uint64
+}
+
+// SectionFilter selects the sections to search
+type SectionFilter func(*goelf.Section) bool
+
+// SegmentFilter selects the segments to search
+type SegmentFilter func(*goelf.Prog) bool
+
+// ExecutableSections selects the sections holding code
+func ExecutableSections(s *goelf.Section) bool {
+	return s.Flags&goelf.SHF_EXECINSTR != 0
+}
+
+// ExecutableSegments selects the loadable segments that are mapped executable
+func ExecutableSegments(p *goelf.Prog) bool {
+	return p.Type == goelf.PT_LOAD && p.Flags&goelf.PF_X != 0
+}
+
+// ScanSections searches every section of f selected by filter (all of them if filter is nil),
+// in the order they appear in the section table, calling fn with each match until it returns false.
+// Sections without any data in the file, such as .bss, are skipped.
+//
+// Sections are read from f a page at a time as they're searched, and the text of a match is
+// read back from f when it's asked for, so f must stay open while the matches are in use.
+func ScanSections(f *goelf.File, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) error {
+	for _, s := range f.Sections {
+		if s.Type == goelf.SHT_NOBITS || s.Type == goelf.SHT_NULL || (filter != nil && !filter(s)) {
+			continue
+		}
+
+		r, size, offset := s.ReaderAt, int64(s.Size), int64(s.Offset)
+		if s.Flags&goelf.SHF_COMPRESSED != 0 || r == nil {
+			data, err := s.Data()
+			if err != nil {
+				return err
+			}
+			r, size, offset = bytes.NewReader(data), int64(len(data)), -1
+		}
+
+		more, err := scan(re, r, size, func(m *binexp.Match) bool {
+			em := &Match{
+				Match:   m,
+				Section: s.Name,
+				Segment: -1,
+				Offset:  -1,
+				Addr:    s.Addr + uint64(m.Index),
+			}
+			if offset >= 0 {
+				em.Offset = offset + int64(m.Index)
+			}
+			return fn(em)
+		})
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanSegments searches every segment of f selected by filter (all of them if filter is nil),
+// in the order of the program headers, calling fn with each match until it returns false.
+// Only the part of each segment stored in the file is searched.  As with ScanSections, f must
+// stay open while the matches are in use.
+func ScanSegments(f *goelf.File, re *binexp.Regexp, filter SegmentFilter, fn func(*Match) bool) error {
+	for i, p := range f.Progs {
+		if p.Filesz == 0 || (filter != nil && !filter(p)) {
+			continue
+		}
+
+		more, err := scan(re, p.ReaderAt, int64(p.Filesz), func(m *binexp.Match) bool {
+			return fn(&Match{
+				Match:   m,
+				Segment: i,
+				Offset:  int64(p.Off) + int64(m.Index),
+				Addr:    p.Vaddr + uint64(m.Index),
+			})
+		})
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindAllSections returns all the matches ScanSections would find
+func FindAllSections(f *goelf.File, re *binexp.Regexp, filter SectionFilter) ([]*Match, error) {
+	var matches []*Match
+	err := ScanSections(f, re, filter, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return matches, err
+}
+
+// FindAllSegments returns all the matches ScanSegments would find
+func FindAllSegments(f *goelf.File, re *binexp.Regexp, filter SegmentFilter) ([]*Match, error) {
+	var matches []*Match
+	err := ScanSegments(f, re, filter, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return matches, err
+}
+
+// scan calls fn with each match in the first size bytes of r, returning false if fn asked to stop
+func scan(re *binexp.Regexp, r io.ReaderAt, size int64, fn func(*binexp.Match) bool) (bool, error) {
+	m, err := re.FindReaderAtMatchStartingAt(r, size, -1)
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		if !fn(m) {
+			return false, nil
+		}
+	}
+	return true, err
+}
@@ -0,0 +1,114 @@
+package elf
+
+import (
+	goelf "debug/elf"
+	"testing"
+
+	"github.com/polyverse/binexp"
+)
+
+// testdata/hello is built from testdata/hello.s with
+//
+//	as -o hello.o hello.s && ld -s -z max-page-size=0x10 -z noseparate-code -o hello hello.o
+func openHello(t *testing.T) *goelf.File {
+	f, err := goelf.Open("testdata/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestScanSections(t *testing.T) {
+	f := openHello(t)
+	re := binexp.MustCompileHex("EF BE AD DE", 0)
+
+	ms, err := FindAllSections(f, re, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("Expected 2 matches, got %v", len(ms))
+	}
+	if m := ms[0]; m.Section != ".text" || m.Segment != -1 || m.Offset != 0xc4 || m.Addr != 0x4000c4 || m.Index != 0x14 {
+		t.Fatalf("Unexpected .text match %+v", *m)
+	}
+	if m := ms[1]; m.Section != ".data" || m.Offset != 0xd0 || m.Addr != 0x4000d0 || m.Index != 0 {
+		t.Fatalf("Unexpected .data match %+v", *m)
+	}
+
+	ms, err = FindAllSections(f, re, ExecutableSections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].Section != ".text" {
+		t.Fatalf("Expected only the .text match, got %v", ms)
+	}
+}
+
+func TestScanSections_Captures(t *testing.T) {
+	f := openHello(t)
+
+	// mov eax, imm32
+	re := binexp.MustCompile(`\xb8(?<imm>[\x00-\xff]{4})`, binexp.ByteRunes)
+
+	var imms []uint32
+	err := ScanSections(f, re, ExecutableSections, func(m *Match) bool {
+		imm, err := m.GroupByName("imm").Uint32LE()
+		if err != nil {
+			t.Fatal(err)
+		}
+		imms = append(imms, imm)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imms) != 2 || imms[0] != 60 || imms[1] != 0xdeadbeef {
+		t.Fatalf("Expected immediates [60 0xdeadbeef], got %#x", imms)
+	}
+}
+
+func TestScanSegments(t *testing.T) {
+	f := openHello(t)
+	re := binexp.MustCompileHex("EF BE AD DE", 0)
+
+	ms, err := FindAllSegments(f, re, ExecutableSegments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 {
+		t.Fatalf("Expected 1 match, got %v", len(ms))
+	}
+	if m := ms[0]; m.Section != "" || m.Segment != 0 || m.Offset != 0xc4 || m.Addr != 0x4000c4 || m.Index != 0xc4 {
+		t.Fatalf("Unexpected match %+v", *m)
+	}
+	if s := ms[0].String(); s != "\xef\xbe\xad\xde" {
+		t.Fatalf("Unexpected match text %q", s)
+	}
+
+	ms, err = FindAllSegments(f, re, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[1].Segment != 1 || ms[1].Offset != 0xd0 || ms[1].Addr != 0x4000d0 {
+		t.Fatalf("Expected the data segment's match too, got %v", ms)
+	}
+}
+
+func TestScanSegments_Stop(t *testing.T) {
+	f := openHello(t)
+	re := binexp.MustCompileHex("EF BE AD DE", 0)
+
+	count := 0
+	err := ScanSegments(f, re, nil, func(m *Match) bool {
+		count++
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected the scan to stop after 1 match, got %v", count)
+	}
+}
@@ -0,0 +1,18 @@
+	.text
+	.globl _start
+_start:
+	push %rbp
+	mov %rsp, %rbp
+	call f
+	mov $60, %eax
+	xor %edi, %edi
+	syscall
+f:
+	push %rbx
+	mov $0xdeadbeef, %eax
+	pop %rbx
+	ret
+	.data
+magic:
+	.long 0xdeadbeef
+	.ascii "binexp"
@@ -0,0 +1,194 @@
+package binexp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+// The binary encodings of a Regexp and a RegexSet start with a magic string and the
+// version of the encoding, like that of the program (see syntax.Code.MarshalBinary).
+const (
+	regexpMagic   = "binexp regexp"
+	regexSetMagic = "binexp set"
+	encodeVersion = 1
+)
+
+// MarshalBinary encodes the compiled Regexp, so it can be loaded with UnmarshalBinary
+// without parsing and compiling the pattern again.  MatchTimeout and the other limits
+// aren't included.
+func (re *Regexp) MarshalBinary() ([]byte, error) {
+	code, err := re.code.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &syntax.Encoder{}
+	e.String(regexpMagic)
+	e.Int(encodeVersion)
+	e.String(re.pattern)
+	e.Int(int(re.options))
+
+	// map order is random, so the names are sorted to keep the encoding stable
+	e.Bool(re.capnames != nil)
+	names := make([]string, 0, len(re.capnames))
+	for name := range re.capnames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	e.Int(len(names))
+	for _, name := range names {
+		e.String(name)
+		e.Int(re.capnames[name])
+	}
+
+	e.Bool(re.capslist != nil)
+	e.Int(len(re.capslist))
+	for _, name := range re.capslist {
+		e.String(name)
+	}
+
+	e.String(string(code))
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary loads a Regexp encoded by MarshalBinary, replacing re.  It returns an
+// error if the data was encoded by a different version of the package, or is damaged.  It
+// isn't safe to load data from an untrusted source.  The exported fields, MatchTimeout,
+// MatchStepLimit, MatchMemoryLimit, ReaderAtPageSize and ReaderAtPageCount, are set to their
+// defaults.
+func (re *Regexp) UnmarshalBinary(data []byte) error {
+	d := syntax.NewDecoder(data)
+	if d.String() != regexpMagic {
+		return errors.New("not an encoded Regexp")
+	}
+	if v := d.Int(); v != encodeVersion {
+		return fmt.Errorf("unsupported Regexp encoding version %v, expected %v", v, encodeVersion)
+	}
+
+	pattern := d.String()
+	options := RegexOptions(d.Int())
+	if options&^(IgnoreCase|Multiline|ExplicitCapture|Compiled|Singleline|IgnorePatternWhitespace|
+		RightToLeft|Debug|ECMAScript|ByteRunes) != 0 {
+		d.Fail("invalid options")
+	}
+
+	var capnames map[string]int
+	hasNames := d.Bool()
+	n := d.Len()
+	if hasNames {
+		capnames = make(map[string]int, n)
+	} else if n > 0 {
+		d.Fail("unexpected group names")
+		n = 0
+	}
+	for i := 0; i < n; i++ {
+		name := d.String()
+		capnames[name] = d.Int()
+	}
+
+	var capslist []string
+	hasList := d.Bool()
+	n = d.Len()
+	if hasList {
+		capslist = make([]string, n)
+	} else if n > 0 {
+		d.Fail("unexpected group list")
+	}
+	for i := range capslist {
+		capslist[i] = d.String()
+	}
+
+	encoded := d.String()
+	if err := d.Err(); err != nil {
+		return fmt.Errorf("invalid encoded Regexp: %w", err)
+	}
+
+	code := &syntax.Code{}
+	if err := code.UnmarshalBinary([]byte(encoded)); err != nil {
+		return err
+	}
+	if code.RightToLeft != (options&RightToLeft != 0) {
+		return errors.New("invalid encoded Regexp: options don't match the program")
+	}
+
+	re.pattern = pattern
+	re.options = options
+	re.caps = code.Caps
+	re.capnames = capnames
+	re.capslist = capslist
+	re.capsize = code.Capsize
+	re.code = code
+	re.MatchTimeout = DefaultMatchTimeout
+	re.MatchStepLimit = 0
+	re.MatchMemoryLimit = 0
+	re.ReaderAtPageSize = 0
+	re.ReaderAtPageCount = 0
+	if err := re.setCompiled(); err != nil {
+		return err
+	}
+
+	// runners hold on to the program they were made for
+	re.muRun.Lock()
+	re.runner = nil
+	re.muRun.Unlock()
+	return nil
+}
+
+// MarshalBinary encodes the compiled patterns of the set, so it can be loaded with
+// UnmarshalBinary without compiling them all again
+func (s *RegexSet) MarshalBinary() ([]byte, error) {
+	e := &syntax.Encoder{}
+	e.String(regexSetMagic)
+	e.Int(encodeVersion)
+	e.Int(len(s.regexps))
+	for _, re := range s.regexps {
+		b, err := re.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		e.String(string(b))
+	}
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary loads a set encoded by MarshalBinary, replacing s, in the same way as
+// Regexp.UnmarshalBinary
+func (s *RegexSet) UnmarshalBinary(data []byte) error {
+	d := syntax.NewDecoder(data)
+	if d.String() != regexSetMagic {
+		return errors.New("not an encoded RegexSet")
+	}
+	if v := d.Int(); v != encodeVersion {
+		return fmt.Errorf("unsupported RegexSet encoding version %v, expected %v", v, encodeVersion)
+	}
+
+	encoded := make([]string, d.Len())
+	for i := range encoded {
+		encoded[i] = d.String()
+	}
+	if err := d.Err(); err != nil {
+		return fmt.Errorf("invalid encoded RegexSet: %w", err)
+	}
+
+	regexps := make([]*Regexp, len(encoded))
+	for i, b := range encoded {
+		re := &Regexp{}
+		if err := re.UnmarshalBinary([]byte(b)); err != nil {
+			return fmt.Errorf("pattern %v: %w", i, err)
+		}
+		if re.RightToLeft() {
+			return errors.New("RightToLeft is not supported in a RegexSet")
+		}
+		regexps[i] = re
+	}
+
+	*s = RegexSet{
+		MatchTimeout: DefaultMatchTimeout,
+		regexps:      regexps,
+	}
+	s.buildPrefilter()
+	return nil
+}
@@ -0,0 +1,168 @@
+package binexp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+var encodeTests = []struct {
+	pattern string
+	opt     RegexOptions
+	input   string
+}{
+	{`(?<word>\w+)\s(?<num>\d+)`, None, "abc 123 def 45"},
+	{`(?<5>a)(?<2>b)?`, None, "ab a"},
+	{`[a-z-[aeiou]]+`, None, "strength"},
+	{`\p{Lu}\p{Ll}*`, None, "Hello World"},
+	{`(?i)key=(\w+)`, None, "KEY=abc Key=def"},
+	{`(\d)\1`, None, "1223"},
+	{`(?<=a)b`, None, "ab cb"},
+	{`^\w+$`, Multiline, "one\ntwo three"},
+	{`ab+`, RightToLeft, "abbb ab"},
+	{`.{2}\xE8..\xC3`, ByteRunes | Singleline, "\x00\x01\x02\xe8\x03\x04\xc3"},
+}
+
+func TestRegexpMarshalBinary(t *testing.T) {
+	for _, test := range encodeTests {
+		re := MustCompile(test.pattern, test.opt)
+		data, err := re.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.pattern, err)
+		}
+
+		loaded := &Regexp{}
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.pattern, err)
+		}
+		if loaded.String() != re.String() || loaded.options != re.options {
+			t.Errorf("%q: loaded as %q with options %v", test.pattern, loaded.String(), loaded.options)
+		}
+		if !reflect.DeepEqual(loaded.GetGroupNames(), re.GetGroupNames()) || !reflect.DeepEqual(loaded.GetGroupNumbers(), re.GetGroupNumbers()) {
+			t.Errorf("%q: loaded groups %v %v, expected %v %v", test.pattern, loaded.GetGroupNames(), loaded.GetGroupNumbers(),
+				re.GetGroupNames(), re.GetGroupNumbers())
+		}
+		if loaded.code.Dump() != re.code.Dump() {
+			t.Errorf("%q: loaded program\n%v\nexpected\n%v", test.pattern, loaded.code.Dump(), re.code.Dump())
+		}
+		if (loaded.code.NFA == nil) != (re.code.NFA == nil) {
+			t.Errorf("%q: expected the NFA to be loaded too", test.pattern)
+		}
+
+		// the encoding is stable
+		again, err := loaded.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.pattern, err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("%q: encoding changed after loading", test.pattern)
+		}
+
+		if got, want := allCaptures(t, loaded, test.input), allCaptures(t, re, test.input); got != want {
+			t.Errorf("%q: loaded Regexp found %v, expected %v", test.pattern, got, want)
+		}
+	}
+}
+
+func TestRegexpUnmarshalBinaryReused(t *testing.T) {
+	data, err := MustCompile(`(?<word>\w+)\s(\d+)`, None).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// load over a Regexp that has been searched with, with its fields set
+	re := MustCompile(`(a)(b)(c)(d)`, RightToLeft)
+	re.MatchTimeout = time.Second
+	re.MatchStepLimit = 1000
+	re.MatchMemoryLimit = 1 << 20
+	re.ReaderAtPageSize = 4
+	re.ReaderAtPageCount = 2
+	if m, err := re.FindReaderAtMatchStartingAt(strings.NewReader("abcd"), 4, -1); err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if err := re.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	fresh := &Regexp{}
+	if err := fresh.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if re.MatchTimeout != fresh.MatchTimeout || re.MatchStepLimit != 0 || re.MatchMemoryLimit != 0 ||
+		re.ReaderAtPageSize != 0 || re.ReaderAtPageCount != 0 {
+		t.Errorf("Expected the exported fields to be reset, got %v %v %v %v %v", re.MatchTimeout, re.MatchStepLimit,
+			re.MatchMemoryLimit, re.ReaderAtPageSize, re.ReaderAtPageCount)
+	}
+	if re.RightToLeft() || !reflect.DeepEqual(re.GetGroupNames(), fresh.GetGroupNames()) {
+		t.Errorf("Expected the loaded options and groups, got %v %v", re.options, re.GetGroupNames())
+	}
+	if got, want := allCaptures(t, re, "ab 12 cd 3"), allCaptures(t, fresh, "ab 12 cd 3"); got != want {
+		t.Errorf("Reused Regexp found %v, expected %v", got, want)
+	}
+}
+
+func TestRegexpUnmarshalBinaryErrors(t *testing.T) {
+	data, err := MustCompile(`(?<x>a+)[b-d]\1|e(?=f)`, None).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	// every truncation and every damaged byte is rejected
+	for i := 0; i < len(data); i++ {
+		if err := (&Regexp{}).UnmarshalBinary(data[:i]); err == nil {
+			t.Fatalf("Expected an error loading %v of %v bytes", i, len(data))
+		}
+		for _, b := range []byte{0, 1, 0x7f, 0x80, 0xff} {
+			if data[i] == b {
+				continue
+			}
+			damaged := append([]byte(nil), data...)
+			damaged[i] = b
+			if err := (&Regexp{}).UnmarshalBinary(damaged); err == nil {
+				t.Fatalf("Expected an error with byte %v set to %v", i, b)
+			}
+		}
+	}
+
+	if err := (&Regexp{}).UnmarshalBinary([]byte("not a regexp")); err == nil || !strings.Contains(err.Error(), "not an encoded Regexp") {
+		t.Errorf("Expected an error for data that isn't a Regexp, got %v", err)
+	}
+
+	e := &syntax.Encoder{}
+	e.String(regexpMagic)
+	e.Int(encodeVersion + 1)
+	if err := (&Regexp{}).UnmarshalBinary(e.Bytes()); err == nil || !strings.Contains(err.Error(), "unsupported Regexp encoding version") {
+		t.Errorf("Expected an error for a different version, got %v", err)
+	}
+}
+
+func TestRegexSetMarshalBinary(t *testing.T) {
+	patterns := []string{`foo\d+`, `(?i)bar`, `[xy]{2}`, `\bq`}
+	set := MustCompileSet(patterns, 0)
+	data, err := set.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	loaded := &RegexSet{}
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if loaded.Len() != len(patterns) {
+		t.Fatalf("Expected %v patterns, got %v", len(patterns), loaded.Len())
+	}
+
+	input := "a foo12 BAR xy q"
+	want, _ := set.MatchString(input)
+	got, err := loaded.MatchString(input)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,73 @@
+package binexp
+
+import (
+	"fmt"
+	"time"
+)
+
+// MatchTimeoutError is returned when a search runs for longer than the MatchTimeout.
+// Positions are in runes, or in bytes when the input is searched as bytes.
+type MatchTimeoutError struct {
+	Pattern string        // the pattern being searched for
+	Timeout time.Duration // the MatchTimeout that was exceeded
+	Start   int           // where the search started
+	Pos     int           // where the search had got to
+}
+
+func (e *MatchTimeoutError) Error() string {
+	return fmt.Sprintf("match timeout after %v on pattern `%v`, searching from %v, at %v", e.Timeout, e.Pattern, e.Start, e.Pos)
+}
+
+// MatchStepLimitError is returned when a search takes more steps than the MatchStepLimit
+type MatchStepLimitError struct {
+	Pattern string // the pattern being searched for
+	Limit   int    // the MatchStepLimit that was exceeded
+	Start   int    // where the search started
+	Pos     int    // where the search had got to
+}
+
+func (e *MatchStepLimitError) Error() string {
+	return fmt.Sprintf("match step limit of %v exceeded on pattern `%v`, searching from %v, at %v", e.Limit, e.Pattern, e.Start, e.Pos)
+}
+
+// SetTimeoutError is returned when a RegexSet's search runs for longer than its MatchTimeout
+// while it's scanning the input for places to try its patterns, rather than running one of
+// them, which returns a *MatchTimeoutError
+type SetTimeoutError struct {
+	Timeout time.Duration // the MatchTimeout that was exceeded
+	Pos     int           // where the scan had got to
+}
+
+func (e *SetTimeoutError) Error() string {
+	return fmt.Sprintf("set match timeout after %v, scanning at %v", e.Timeout, e.Pos)
+}
+
+// MatchMemoryLimitError is returned when the backtracking stacks would need more memory
+// than the MatchMemoryLimit
+type MatchMemoryLimitError struct {
+	Pattern string // the pattern being searched for
+	Limit   int    // the MatchMemoryLimit that would have been exceeded, in bytes
+	Start   int    // where the search started
+	Pos     int    // where the search had got to
+}
+
+func (e *MatchMemoryLimitError) Error() string {
+	return fmt.Sprintf("match memory limit of %v bytes exceeded on pattern `%v`, searching from %v, at %v", e.Limit, e.Pattern, e.Start, e.Pos)
+}
+
+// rebaseErr moves the positions in a MatchTimeoutError, MatchStepLimitError or
+// MatchMemoryLimitError by offset, like Match.rebase, and returns err
+func rebaseErr(err error, offset int) error {
+	switch e := err.(type) {
+	case *MatchTimeoutError:
+		e.Start += offset
+		e.Pos += offset
+	case *MatchStepLimitError:
+		e.Start += offset
+		e.Pos += offset
+	case *MatchMemoryLimitError:
+		e.Start += offset
+		e.Pos += offset
+	}
+	return err
+}
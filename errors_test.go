@@ -0,0 +1,156 @@
+package binexp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchTimeoutError(t *testing.T) {
+	// the lookahead keeps this on the backtracking engine, where it takes exponential time
+	pattern := `(?=.)(.+)*\?`
+	re := MustCompile(pattern, ByteRunes)
+	re.MatchTimeout = time.Millisecond
+
+	input := append([]byte{0, 0xff, 0x90}, bytes.Repeat([]byte("a"), 1<<20)...)
+	_, err := re.FindBytesMatchStartingAt(input, 3)
+
+	var timeout *MatchTimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("Expected a *MatchTimeoutError, got %v", err)
+	}
+	if timeout.Pattern != pattern || timeout.Timeout != time.Millisecond || timeout.Start != 3 {
+		t.Errorf("Unexpected error fields %+v", timeout)
+	}
+	if timeout.Pos < 3 || timeout.Pos > len(input) {
+		t.Errorf("Expected Pos to be in the input, got %v", timeout.Pos)
+	}
+	if msg := err.Error(); len(msg) > 200 || strings.Contains(msg, "aaaa") {
+		t.Errorf("Expected the error message not to include the input, got %q", msg)
+	}
+}
+
+func TestMatchTimeoutErrorEveryPath(t *testing.T) {
+	re := MustCompile(`(?=.)(.+)*\?`, 0)
+	re.MatchTimeout = time.Millisecond
+	input := strings.Repeat("a", 100)
+
+	for name, search := range map[string]func() error{
+		"FindStringMatch": func() error {
+			_, err := re.FindStringMatch(input)
+			return err
+		},
+		"MatchString": func() error {
+			_, err := re.MatchString(input)
+			return err
+		},
+		"FindAllStringMatch": func() error {
+			_, err := re.FindAllStringMatch(input, -1, false)
+			return err
+		},
+		"Replace": func() error {
+			_, err := re.Replace(input, "x", -1, -1)
+			return err
+		},
+		"FindReaderMatches": func() error {
+			return re.FindReaderMatches(strings.NewReader(input), 0, 0, func(*Match) bool { return true })
+		},
+	} {
+		var timeout *MatchTimeoutError
+		if err := search(); !errors.As(err, &timeout) {
+			t.Errorf("%v: expected a *MatchTimeoutError, got %v", name, err)
+		}
+	}
+}
+
+func TestMatchStepLimit(t *testing.T) {
+	// the lookahead keeps this on the backtracking engine, where it takes exponential time
+	re := MustCompile(`(?=.)(.+)*\?`, 0)
+	re.MatchStepLimit = 100000
+	input := strings.Repeat("a", 100)
+
+	var first *MatchStepLimitError
+	for i := 0; i < 2; i++ {
+		_, err := re.FindStringMatch(input)
+		var limit *MatchStepLimitError
+		if !errors.As(err, &limit) {
+			t.Fatalf("Expected a *MatchStepLimitError, got %v", err)
+		}
+		var timeout *MatchTimeoutError
+		if errors.As(err, &timeout) {
+			t.Fatalf("Expected the step limit error to be distinct from a timeout")
+		}
+		if limit.Limit != 100000 || limit.Pattern != re.String() {
+			t.Errorf("Unexpected error fields %+v", limit)
+		}
+		// the search is cut off at the same place every time
+		if first == nil {
+			first = limit
+		} else if *limit != *first {
+			t.Errorf("Expected the same error both times, got %+v and %+v", first, limit)
+		}
+	}
+
+	re.MatchStepLimit = 0
+	if m, err := re.FindStringMatch("ab?"); err != nil || m == nil {
+		t.Errorf("Expected a match without a step limit, got %v, %v", m, err)
+	}
+}
+
+func TestMatchStepLimitLinear(t *testing.T) {
+	re := MustCompile(`a+b`, 0)
+	re.MatchStepLimit = 50
+
+	if m, err := re.FindStringMatch(strings.Repeat("a", 20) + "b"); err != nil || m == nil {
+		t.Errorf("Expected a match within the limit, got %v, %v", m, err)
+	}
+
+	var limit *MatchStepLimitError
+	if _, err := re.FindStringMatch(strings.Repeat("a", 100) + "b"); !errors.As(err, &limit) {
+		t.Fatalf("Expected a *MatchStepLimitError, got %v", err)
+	}
+
+	set := MustCompileSet([]string{`a+b`, `c`}, 0)
+	set.MatchStepLimit = 50
+	if _, err := set.MatchString(strings.Repeat("a", 100) + "b"); !errors.As(err, &limit) {
+		t.Fatalf("Expected a *MatchStepLimitError from the set, got %v", err)
+	}
+}
+
+func TestMatchMemoryLimit(t *testing.T) {
+	// the lookahead keeps this on the backtracking engine, which remembers every
+	// iteration of the loop in case it has to backtrack into it
+	re := MustCompile(`(?=.)(?:a|b)*c`, 0)
+	input := strings.Repeat("ab", 50000) + "c"
+
+	m, err := re.FindStringMatch(input)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	peak := m.Stats().PeakStackBytes
+	if peak < 100000 {
+		t.Errorf("Expected the stacks to hold at least a word per iteration, got %v bytes", peak)
+	}
+
+	re = MustCompile(`(?=.)(?:a|b)*c`, 0)
+	re.MatchMemoryLimit = 64 * 1024
+	_, err = re.FindStringMatch(input)
+	var limit *MatchMemoryLimitError
+	if !errors.As(err, &limit) {
+		t.Fatalf("Expected a *MatchMemoryLimitError, got %v", err)
+	}
+	if limit.Limit != 64*1024 || limit.Pos <= 0 {
+		t.Errorf("Unexpected error fields %+v", limit)
+	}
+
+	// the limit doesn't get in the way of smaller searches
+	m, err = re.FindStringMatch("abababc")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if peak := m.Stats().PeakStackBytes; peak <= 0 || peak > 64*1024 {
+		t.Errorf("Unexpected peak stack use %v", peak)
+	}
+}
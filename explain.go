@@ -0,0 +1,15 @@
+package binexp
+
+import "github.com/polyverse/binexp/syntax"
+
+// Explain parses pattern with the options and describes it for people reviewing it, as a
+// tree of Explanations with a description of each node in words, such as "byte 0xe8, then
+// any 4 bytes captured as 'rel32'".  The Explanation's String method gives the description
+// of the whole pattern along with an outline of its nodes, and it can be marshaled as JSON.
+func Explain(pattern string, opt RegexOptions) (*syntax.Explanation, error) {
+	tree, err := syntax.Parse(pattern, syntax.RegexOptions(opt))
+	if err != nil {
+		return nil, err
+	}
+	return tree.Explain(), nil
+}
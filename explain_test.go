@@ -0,0 +1,98 @@
+package binexp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+func TestExplain(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		opt     RegexOptions
+		want    string
+	}{
+		{`\xe8(?<rel32>.{4})`, ByteRunes | Singleline, "byte 0xe8, then any 4 bytes captured as 'rel32'"},
+		{`\x55\x48\x89\xe5(?:\x41[\x54-\x57])+?`, ByteRunes,
+			"bytes 0x55 0x48 0x89 0xe5, then (byte 0x41, then a byte in [0x54-0x57]) at least once, as few as possible"},
+		{`[\x00-\x1f\m{F0:40}][^\x00]{2,}.?`, ByteRunes,
+			"a byte in [0x00-0x1f (bits under 0xf0 are 0x40 (0x40-0x4f))], then any byte but 0x00 at least 2 times, then any byte but 0x0a optionally"},
+		{`\m{f0:30}\m{0f:05}|a(?!)|(?<!)`, ByteRunes,
+			"either (a byte whose bits under 0xf0 are 0x30 (0x30-0x3f), then a byte whose bits under 0x0f are 0x05 (16 bytes from 0x05 to 0xf5)), " +
+				"or (byte 0x61, then a lookaround that never matches), or a lookaround that never matches"},
+		{`(?i)abc|d*|(a)\1`, None,
+			`either the text "abc" in any case, or character 'd' in any case any number of times, or ` +
+				`(character 'a' in any case captured as 1, then the same text as group 1 in any case)`},
+		{`^(?=foo)(?<!x)\bbar(?>a+)$`, None,
+			`the start of the input, then followed by the text "foo", then not preceded by character 'x', then a word boundary, ` +
+				`then the text "bar", then character 'a' at least once without backtracking into it, then the end of the input or a final newline`},
+		{`(?<o>\()?[^()]+(?(o)\))|(?<c-o>z)`, None,
+			`either (character '(' captured as 'o' optionally, then a character in [^\(\)] at least once, ` +
+				`then (if group 'o' has captured then character ')')), or character 'z' captured as 'c', and removing the last capture of 'o'`},
+		{`(?(?=a)b|c)`, None, "if followed by character 'a' then character 'b' else character 'c'"},
+		{`(?=a)b`, RightToLeft, "followed by character 'a', then character 'b'"},
+		{`x(?<=a[bc]d)`, None, "character 'x', then preceded by (character 'a', then a character in [bc], then character 'd')"},
+	}
+	for _, test := range tests {
+		e, err := Explain(test.pattern, test.opt)
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.pattern, err)
+		}
+		if e.Description != test.want {
+			t.Errorf("%q: expected\n%v\ngot\n%v", test.pattern, test.want, e.Description)
+		}
+	}
+
+	if _, err := Explain(`(a`, None); err == nil {
+		t.Errorf("Expected an error for a pattern that doesn't parse")
+	}
+}
+
+func TestExplainNodes(t *testing.T) {
+	e, err := Explain(`\xe8(?<rel32>.{4})`, ByteRunes|Singleline)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+
+	want := "" +
+		"byte 0xe8, then any 4 bytes captured as 'rel32'\n" +
+		"sequence: 2 in a row\n" +
+		"  char: byte 0xe8\n" +
+		"  capture: captured as 'rel32'\n" +
+		"    repeat: 4 times\n" +
+		"      any: any byte\n"
+	if e.String() != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, e.String())
+	}
+	capture := e.Children[1]
+	if capture.Group != "rel32" || capture.Children[0].Repeat == nil || *capture.Children[0].Repeat != (syntax.ExplainRepeat{Min: 4, Max: 4}) {
+		t.Errorf("Unexpected capture %+v", capture)
+	}
+
+	// the JSON form has the same nodes
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if !strings.Contains(string(data), `"repeat":{"min":4,"max":4}`) || !strings.Contains(string(data), `"group":"rel32"`) {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	var loaded syntax.Explanation
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if !reflect.DeepEqual(&loaded, e) {
+		t.Errorf("Expected the JSON to load as %+v, got %+v", e, loaded)
+	}
+
+	e, err = Explain(`a*?`, None)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if *e.Repeat != (syntax.ExplainRepeat{Min: 0, Max: -1, Lazy: true}) {
+		t.Errorf("Unexpected repeat %+v", *e.Repeat)
+	}
+}
@@ -0,0 +1,139 @@
+package binexp
+
+import (
+	"context"
+	"iter"
+)
+
+// FindAllStringMatch returns up to n successive matches of the Regexp in the input string
+// (all of them if n < 0).  If overlapping is true each search starts one character past the
+// start of the previous match (or, for RightToLeft, one character before its end), otherwise
+// it starts where the previous match ended (as with FindNextMatch).  A nil slice is returned
+// if there are no matches.
+func (re *Regexp) FindAllStringMatch(s string, n int, overlapping bool) ([]*Match, error) {
+	var matches []*Match
+	err := re.eachMatch(context.Background(), getRunes(s), nil, n, overlapping, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// FindAllBytesMatch is like FindAllStringMatch but searches a byte slice, each byte being
+// matched as a single rune.  If the Regexp was compiled with ByteRunes the bytes are
+// searched in place.
+func (re *Regexp) FindAllBytesMatch(b []byte, n int, overlapping bool) ([]*Match, error) {
+	var matches []*Match
+	err := re.eachMatch(context.Background(), re.bytesToRunes(b), b, n, overlapping, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// AllStringMatches returns an iterator over the same matches FindAllStringMatch would return.
+// The matches are found as the iteration goes, so stopping early saves the rest of the search.
+// If the search fails (for example on a MatchTimeout) the sequence ends with a nil Match and
+// the error.
+func (re *Regexp) AllStringMatches(s string, n int, overlapping bool) iter.Seq2[*Match, error] {
+	return re.allMatches(context.Background(), getRunes(s), nil, n, overlapping)
+}
+
+// AllBytesMatches is like AllStringMatches but searches a byte slice, in the same way as FindAllBytesMatch
+func (re *Regexp) AllBytesMatches(b []byte, n int, overlapping bool) iter.Seq2[*Match, error] {
+	return re.allMatches(context.Background(), re.bytesToRunes(b), b, n, overlapping)
+}
+
+// allMatches returns an iterator over the matches eachMatch finds, ending with its error
+func (re *Regexp) allMatches(ctx context.Context, text []rune, b []byte, n int, overlapping bool) iter.Seq2[*Match, error] {
+	return func(yield func(*Match, error) bool) {
+		stopped := false
+		err := re.eachMatch(ctx, text, b, n, overlapping, func(m *Match) bool {
+			stopped = !yield(m, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// nextOverlappingStart returns where the search for the match overlapping m starts in its
+// input: one character past the start of m, or for RightToLeft one character before its end
+func (re *Regexp) nextOverlappingStart(m *Match) int {
+	if re.RightToLeft() {
+		return m.Index - m.offset + m.Length - 1
+	}
+	return m.Index - m.offset + 1
+}
+
+// bytesToRunes converts b to runes, one per byte, unless it's going to be searched in place
+func (re *Regexp) bytesToRunes(b []byte) []rune {
+	if re.options&ByteRunes != 0 {
+		return nil
+	}
+	runes := make([]rune, len(b))
+	for idx, bi := range b {
+		runes[idx] = rune(bi)
+	}
+	return runes
+}
+
+// eachMatch calls yield with up to n successive matches in the input, stopping early if it
+// returns false.  The input is text, or b when text is nil; when both are given text was
+// converted from b.  A single runner is used for the whole search rather than one being
+// fetched from the cache for every match.
+func (re *Regexp) eachMatch(ctx context.Context, text []rune, b []byte, n int, overlapping bool, yield func(*Match) bool) error {
+	if n == 0 {
+		return nil
+	}
+
+	// get a cached runner
+	runner := re.getRunner()
+	defer re.putRunner(runner)
+
+	runner.setInput(text, b)
+	runner.ctx = ctx
+
+	rtl := re.RightToLeft()
+	startAt := 0
+	if rtl {
+		startAt = runner.runtextend
+	}
+
+	for count := 0; n < 0 || count < n; count++ {
+		m, err := runner.scan(startAt, false, re.MatchTimeout)
+		if err != nil || m == nil {
+			return err
+		}
+		if !yield(m) {
+			return nil
+		}
+
+		// pick the next starting point, always moving by at least one
+		// character so an empty match can't be found again
+		if overlapping {
+			startAt = re.nextOverlappingStart(m)
+		} else {
+			startAt = m.textpos
+			if m.Length == 0 {
+				if rtl {
+					startAt--
+				} else {
+					startAt++
+				}
+			}
+		}
+		if startAt < 0 || startAt > runner.runtextend {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package binexp
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func matchStrings(ms []*Match) []string {
+	var s []string
+	for _, m := range ms {
+		s = append(s, m.String())
+	}
+	return s
+}
+
+func TestFindAllStringMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern     string
+		opt         RegexOptions
+		input       string
+		n           int
+		overlapping bool
+		want        []string
+	}{
+		{"a+", None, "baaxaxxaaa", -1, false, []string{"aa", "a", "aaa"}},
+		{"a+", None, "baaxaxxaaa", 2, false, []string{"aa", "a"}},
+		{"a+", None, "baaxaxxaaa", -1, true, []string{"aa", "a", "a", "aaa", "aa", "a"}},
+		{"a*", None, "bab", -1, false, []string{"", "a", "", ""}},
+		{"a+", RightToLeft, "baaxa", -1, false, []string{"a", "aa"}},
+		{"a+", RightToLeft, "baaxa", -1, true, []string{"a", "aa", "a"}},
+		{"x", None, "aaa", -1, false, nil},
+		{"a", None, "aaa", 0, false, nil},
+	} {
+		ms, err := MustCompile(test.pattern, test.opt).FindAllStringMatch(test.input, test.n, test.overlapping)
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if got := matchStrings(ms); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q on %q (n %v, overlapping %v): wanted %q, got %q", test.pattern, test.input, test.n, test.overlapping, test.want, got)
+		}
+	}
+}
+
+func TestFindAllStringMatch_SameAsFindNextMatch(t *testing.T) {
+	re := MustCompile(`(\w)(\d)?`, None)
+	input := "a1 b c3 dé"
+
+	var want []string
+	for m, _ := re.FindStringMatch(input); m != nil; m, _ = re.FindNextMatch(m) {
+		want = append(want, m.String())
+	}
+
+	ms, err := re.FindAllStringMatch(input, -1, false)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if got := matchStrings(ms); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %q, got %q", want, got)
+	}
+	if ms[1].GroupByNumber(2).Length != 0 || ms[2].GroupByNumber(2).String() != "3" {
+		t.Fatalf("captures are wrong: %v", ms[2].dump())
+	}
+}
+
+func TestFindAllBytesMatch(t *testing.T) {
+	data := []byte{0xe8, 0x01, 0x00, 0xe8, 0xff, 0xe8}
+	for _, opt := range []RegexOptions{None, ByteRunes} {
+		ms, err := MustCompile(`\xe8[\x00-\xff]`, opt).FindAllBytesMatch(data, -1, false)
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if len(ms) != 2 || ms[0].Index != 0 || ms[1].Index != 3 {
+			t.Fatalf("opt %v: unexpected matches %v", opt, ms)
+		}
+		if opt == ByteRunes && !bytes.Equal(ms[1].Bytes(), []byte{0xe8, 0xff}) {
+			t.Fatalf("opt %v: unexpected matches %v", opt, ms)
+		}
+	}
+}
+
+func TestAllStringMatches(t *testing.T) {
+	re := MustCompile("a", None)
+
+	var got []int
+	for m, err := range re.AllStringMatches("abaaba", -1, false) {
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		got = append(got, m.Index)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if want := []int{0, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %v, got %v", want, got)
+	}
+
+	got = nil
+	for m, err := range MustCompile("a", ByteRunes).AllBytesMatches([]byte("aab"), 1, false) {
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		got = append(got, m.Index)
+	}
+	if want := []int{0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %v, got %v", want, got)
+	}
+
+	// a search that fails ends the sequence with its error
+	re = MustCompile("(a|aa)*b", None)
+	re.MatchStepLimit = 100
+	got = nil
+	var last error
+	for m, err := range re.AllStringMatches("ab"+strings.Repeat("a", 300), -1, false) {
+		if m != nil {
+			got = append(got, m.Index)
+		}
+		last = err
+	}
+	if want := []int{0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %v, got %v", want, got)
+	}
+	if _, ok := last.(*MatchStepLimitError); !ok {
+		t.Fatalf("Expected the sequence to end with a *MatchStepLimitError, got %v", last)
+	}
+}
+
+func TestFindNextOverlappingMatchSameAsAll(t *testing.T) {
+	for _, opt := range []RegexOptions{None, RightToLeft} {
+		for _, pattern := range []string{"aba", "a*", "(?=ab)"} {
+			re := MustCompile(pattern, opt)
+			input := "ababa xabab"
+
+			all, err := re.FindAllStringMatch(input, -1, true)
+			if err != nil {
+				t.Fatalf("Unexpected err: %v", err)
+			}
+			var want, got []string
+			for _, m := range all {
+				want = append(want, fmt.Sprintf("%v+%v", m.Index, m.Length))
+			}
+			for m, err := re.FindStringMatch(input); m != nil || err != nil; m, err = re.FindNextOverlappingMatch(m) {
+				if err != nil {
+					t.Fatalf("Unexpected err: %v", err)
+				}
+				got = append(got, fmt.Sprintf("%v+%v", m.Index, m.Length))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%q opt %v: FindNextOverlappingMatch found %v, FindAllStringMatch %v", pattern, opt, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,523 @@
+package binexp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+// GenerateGo writes a Go source file for package pkg with the backtracking programs of res
+// as Go code, which is what binexp-gen does.  Each program becomes a function with a label
+// for each instruction, doing what runner.execute does for the instruction with its operands
+// filled in, so it runs without looking up and dispatching on every instruction, and only
+// takes a step against the limits when it backtracks or jumps back.  The file
+// registers the programs with RegisterProgram when its package is initialized, after which
+// they're used by the patterns compiled with the Compiled option that they were generated
+// from.  Patterns matched in linear time don't run their backtracking program, so nothing
+// is generated for them.
+func GenerateGo(w io.Writer, pkg string, res []*Regexp) error {
+	var funcs, registers, notes bytes.Buffer
+	usesUnicode := false
+
+	for i, re := range res {
+		if re.code.NFA != nil {
+			fmt.Fprintf(&notes, "// %v is matched in linear time, so it has no generated program.\n", commentQuote(re.pattern))
+			continue
+		}
+
+		key, err := re.ProgramKey()
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("program%d", i)
+		g := &programGen{code: re.code, ecma: re.options&ECMAScript != 0}
+		body, err := g.generate()
+		if err != nil {
+			return fmt.Errorf("pattern %v: %w", i, err)
+		}
+		usesUnicode = usesUnicode || g.unicode
+
+		fmt.Fprintf(&registers, "binexp.RegisterProgram(%q, %v)\n", key, name)
+		fmt.Fprintf(&funcs, "\n// %v is the program for %v", name, commentQuote(re.pattern))
+		if re.options != None {
+			fmt.Fprintf(&funcs, " with options %#x", int(re.options))
+		}
+		fmt.Fprintf(&funcs, ".\nfunc %v(m *binexp.Machine) error {\n%v}\n", name, body)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by binexp-gen. DO NOT EDIT.\n\npackage %v\n\n", pkg)
+	if registers.Len() > 0 {
+		buf.WriteString("import (\n")
+		if usesUnicode {
+			buf.WriteString("\"unicode\"\n\n")
+		}
+		buf.WriteString("\"github.com/polyverse/binexp\"\n)\n\n")
+	}
+	buf.Write(notes.Bytes())
+	if registers.Len() > 0 {
+		fmt.Fprintf(buf, "\nfunc init() {\n%v}\n", registers.String())
+	}
+	buf.Write(funcs.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// commentQuote quotes a pattern to go in a comment on a single line
+func commentQuote(s string) string {
+	for _, ch := range s {
+		if ch >= 0x80 {
+			return strconv.QuoteToASCII(s)
+		}
+	}
+	return quote(s)
+}
+
+// programGen generates the Go code for a backtracking program
+type programGen struct {
+	code    *syntax.Code
+	ecma    bool
+	unicode bool // true if the code uses the unicode package
+}
+
+// genBlock is the code for one way into an instruction: running it, or backtracking into it
+type genBlock struct {
+	label    string
+	code     bytes.Buffer
+	refs     []string // the labels it jumps to
+	terminal bool     // true if it never carries on to the next instruction
+}
+
+func (b *genBlock) add(format string, args ...interface{}) {
+	fmt.Fprintf(&b.code, format, args...)
+	b.code.WriteByte('\n')
+}
+
+// line adds a line of code that's already been put together
+func (b *genBlock) line(s string) {
+	b.code.WriteString(s)
+	b.code.WriteByte('\n')
+}
+
+func (b *genBlock) ref(label string) string {
+	b.refs = append(b.refs, label)
+	return label
+}
+
+func instLabel(pc int) string {
+	return fmt.Sprintf("L%d", pc)
+}
+
+// generate returns the body of the function running the program.  The instructions are
+// laid out in order, so each runs on into the next, with the code for backtracking into
+// them after, reached through a switch on the code position popped off the stack.
+func (g *programGen) generate() (string, error) {
+	codes := g.code.Codes
+	fwd := map[int]*genBlock{}
+	var starts []int
+	var backs []*genBlock
+	var cases []string
+
+	for pc := 0; pc < len(codes); pc += syntax.OpcodeSize(syntax.InstOp(codes[pc])) {
+		f, b, b2 := g.instruction(pc)
+		fwd[pc] = f
+		starts = append(starts, pc)
+		if b != nil {
+			b.label = instLabel(pc) + "Back"
+			backs = append(backs, b)
+			cases = append(cases, fmt.Sprintf("case %d:\ngoto %v", pc, b.label))
+		}
+		if b2 != nil {
+			if pc == 0 {
+				return "", errors.New("can't backtrack a second way into the first instruction")
+			}
+			b2.label = instLabel(pc) + "Back2"
+			backs = append(backs, b2)
+			cases = append(cases, fmt.Sprintf("case %d:\ngoto %v", -pc, b2.label))
+		}
+	}
+
+	// find the instructions that can be reached, by running on from the one before or by
+	// jumping to them, so there's no dead code or unused labels
+	reached := map[int]bool{}
+	backtracks := false
+	var visit func(pc int)
+	visit = func(pc int) {
+		f := fwd[pc]
+		if f == nil || reached[pc] {
+			return
+		}
+		reached[pc] = true
+		follow(f.refs, &backtracks, visit)
+		if !f.terminal {
+			visit(pc + syntax.OpcodeSize(syntax.InstOp(codes[pc])))
+		}
+	}
+	visit(0)
+	if backtracks {
+		for _, b := range backs {
+			follow(b.refs, &backtracks, visit)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, pc := range starts {
+		if reached[pc] {
+			for _, ref := range fwd[pc].refs {
+				used[ref] = true
+			}
+		}
+	}
+	if backtracks {
+		for _, b := range backs {
+			for _, ref := range b.refs {
+				used[ref] = true
+			}
+		}
+	}
+
+	body := &bytes.Buffer{}
+	for _, pc := range starts {
+		if !reached[pc] {
+			continue
+		}
+		if used[instLabel(pc)] {
+			fmt.Fprintf(body, "%v:\n", instLabel(pc))
+		}
+		body.Write(fwd[pc].code.Bytes())
+	}
+
+	if backtracks {
+		body.WriteString("\nbacktrack:\nback = m.Backtrack()\n")
+		body.WriteString("if back < codepos && -back < codepos {\nm.EnsureStorage()\n}\n")
+		body.WriteString(stepCheck)
+		fmt.Fprintf(body, "switch back {\n%v\n}\nreturn m.UnknownState()\n", strings.Join(cases, "\n"))
+		for _, b := range backs {
+			fmt.Fprintf(body, "\n%v:\n", b.label)
+			body.Write(b.code.Bytes())
+		}
+	}
+	return declareVars(body.String()), nil
+}
+
+var (
+	genVar     = regexp.MustCompile(`\b(codepos|back|pos|mark|count|c|i)\b`)
+	genComment = regexp.MustCompile(`(?m)^//.*$`)
+	genChar    = regexp.MustCompile(`'[^']*'`)
+)
+
+// declareVars adds a declaration of the local variables body uses to the start of it
+func declareVars(body string) string {
+	code := genChar.ReplaceAllString(genComment.ReplaceAllString(body, ""), "")
+	seen := map[string]bool{}
+	var vars []string
+	for _, v := range genVar.FindAllString(code, -1) {
+		if !seen[v] {
+			seen[v] = true
+			vars = append(vars, v)
+		}
+	}
+	if len(vars) == 0 {
+		return body
+	}
+	sort.Strings(vars)
+	return fmt.Sprintf("var %v int\n\n%v", strings.Join(vars, ", "), body)
+}
+
+// follow visits the instructions refs jump to, noting whether one of them is to backtrack
+func follow(refs []string, backtracks *bool, visit func(int)) {
+	for _, ref := range refs {
+		if ref == "backtrack" {
+			*backtracks = true
+		} else if pc, err := strconv.Atoi(strings.TrimPrefix(ref, "L")); err == nil {
+			visit(pc)
+		}
+	}
+}
+
+// stepCheck takes a step of the search, which a program does whenever it backtracks or jumps
+// back, as it can only run on through the instructions between them
+const stepCheck = "if err := m.Step(); err != nil {\nreturn err\n}\n"
+
+// instruction returns the code for running the instruction at pc, and for backtracking into
+// it in the first and second ways, which are nil for instructions that don't backtrack.
+// Each follows the case for the instruction in runner.execute.
+func (g *programGen) instruction(pc int) (f, b, b2 *genBlock) {
+	codes := g.code.Codes
+	op := syntax.InstOp(codes[pc])
+	rtl := op&syntax.Rtl != 0
+	ci := op&syntax.Ci != 0
+	next := pc + syntax.OpcodeSize(op)
+	operand := func(i int) int {
+		return codes[pc+i+1]
+	}
+
+	f = &genBlock{}
+	f.add("// %v", commentEscape(g.code.OpcodeDescription(pc)))
+
+	// the pieces of the code, which depend on the direction and case sensitivity
+	fail := func(b *genBlock) string {
+		return fmt.Sprintf("codepos = %d\ngoto %v", pc, b.ref("backtrack"))
+	}
+	jump := func(b *genBlock, target int) string {
+		if target < pc {
+			return fmt.Sprintf("m.EnsureStorage()\n%vgoto %v", stepCheck, b.ref(instLabel(target)))
+		}
+		return "goto " + b.ref(instLabel(target))
+	}
+	forwardchars := "m.End()-m.Pos()"
+	forwardcharnext := "m.Next()"
+	backwardnext := "m.SetPos(m.Pos() - 1)"
+	bump, negBump := "+1", "-1"
+	if rtl {
+		forwardchars = "m.Pos()"
+		forwardcharnext = "m.Prev()"
+		backwardnext = "m.SetPos(m.Pos() + 1)"
+		bump, negBump = "-1", "+1"
+	}
+	if ci {
+		forwardcharnext = "unicode.ToLower(" + forwardcharnext + ")"
+	}
+	mismatch := func() string {
+		switch op & syntax.Mask {
+		case syntax.Onerep, syntax.Oneloop, syntax.Onelazy:
+			return fmt.Sprintf("%v != %v", forwardcharnext, charLit(operand(0)))
+		case syntax.Notonerep, syntax.Notoneloop, syntax.Notonelazy:
+			return fmt.Sprintf("%v == %v", forwardcharnext, charLit(operand(0)))
+		default:
+			return fmt.Sprintf("!m.InSet(%d, %v)", operand(0), forwardcharnext)
+		}
+	}
+	usesChars := func() {
+		if ci {
+			g.unicode = true
+		}
+	}
+
+	switch op & syntax.Mask {
+	case syntax.Stop:
+		f.add("return nil")
+		f.terminal = true
+
+	case syntax.Nothing:
+		f.line(fail(f))
+		f.terminal = true
+
+	case syntax.Goto:
+		f.line(jump(f, operand(0)))
+		f.terminal = true
+
+	case syntax.Testref:
+		f.add("if !m.IsMatched(%d) {\n%v\n}", operand(0), fail(f))
+
+	case syntax.Lazybranch:
+		f.add("m.TrackPush1(%d, m.Pos())", pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(1)\nm.SetPos(m.TrackPeek(0))\n%v", jump(b, operand(0)))
+
+	case syntax.Setmark, syntax.Nullmark:
+		if op&syntax.Mask == syntax.Setmark {
+			f.add("m.StackPush(m.Pos())")
+		} else {
+			f.add("m.StackPush(-1)")
+		}
+		f.add("m.TrackPush(%d)", pc)
+		b = &genBlock{}
+		b.add("m.StackPop(1)\n%v", fail(b))
+
+	case syntax.Getmark:
+		f.add("m.StackPop(1)\nm.TrackPush1(%d, m.StackPeek(0))\nm.SetPos(m.StackPeek(0))", pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(1)\nm.StackPush(m.TrackPeek(0))\n%v", fail(b))
+
+	case syntax.Capturemark:
+		if operand(1) != -1 {
+			f.add("if !m.IsMatched(%d) {\n%v\n}", operand(1), fail(f))
+			f.add("m.StackPop(1)\nm.Capture(%d, %d, m.StackPeek(0), m.Pos())", operand(0), operand(1))
+		} else {
+			f.add("m.StackPop(1)\nm.Capture(%d, -1, m.StackPeek(0), m.Pos())", operand(0))
+		}
+		f.add("m.TrackPush1(%d, m.StackPeek(0))", pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(1)\nm.StackPush(m.TrackPeek(0))\nm.Uncapture()")
+		if operand(0) != -1 && operand(1) != -1 {
+			b.add("m.Uncapture()")
+		}
+		b.line(fail(b))
+
+	case syntax.Branchmark:
+		f.add("m.StackPop(1)\nif m.Pos() != m.StackPeek(0) {\nm.TrackPush2(%d, m.StackPeek(0), m.Pos())\nm.StackPush(m.Pos())\n%v\n}",
+			pc, jump(f, operand(0)))
+		f.add("m.TrackPush1(%d, m.StackPeek(0))", -pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(2)\nm.StackPop(1)\nm.SetPos(m.TrackPeek(1))\nm.TrackPush1(%d, m.TrackPeek(0))\n%v", -pc, jump(b, next))
+		b2 = &genBlock{}
+		b2.add("m.TrackPop(1)\nm.StackPush(m.TrackPeek(0))\n%v", fail(b2))
+
+	case syntax.Lazybranchmark:
+		f.add("m.StackPop(1)\nmark = m.StackPeek(0)\nif m.Pos() != mark {\nif mark != -1 {\nm.TrackPush2(%d, mark, m.Pos())\n} else {\nm.TrackPush2(%d, m.Pos(), m.Pos())\n}\n} else {\nm.StackPush(mark)\nm.TrackPush1(%d, mark)\n}",
+			pc, pc, -pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(2)\npos = m.TrackPeek(1)\nm.TrackPush1(%d, m.TrackPeek(0))\nm.StackPush(pos)\nm.SetPos(pos)\n%v", -pc, jump(b, operand(0)))
+		b2 = &genBlock{}
+		b2.add("m.StackPop(1)\nm.TrackPop(1)\nm.StackPush(m.TrackPeek(0))\n%v", fail(b2))
+
+	case syntax.Setcount, syntax.Nullcount:
+		if op&syntax.Mask == syntax.Setcount {
+			f.add("m.StackPush2(m.Pos(), %d)", operand(0))
+		} else {
+			f.add("m.StackPush2(-1, %d)", operand(0))
+		}
+		f.add("m.TrackPush(%d)", pc)
+		b = &genBlock{}
+		b.add("m.StackPop(2)\n%v", fail(b))
+
+	case syntax.Branchcount:
+		f.add("m.StackPop(2)\nmark = m.StackPeek(0)\ncount = m.StackPeek(1)\nif count >= %d || m.Pos() == mark && count >= 0 {\nm.TrackPush2(%d, mark, count)\n} else {\nm.TrackPush1(%d, mark)\nm.StackPush2(m.Pos(), count+1)\n%v\n}",
+			operand(1), -pc, pc, jump(f, operand(0)))
+		b = &genBlock{}
+		b.add("m.TrackPop(1)\nm.StackPop(2)\nif m.StackPeek(1) > 0 {\nm.SetPos(m.StackPeek(0))\nm.TrackPush2(%d, m.TrackPeek(0), m.StackPeek(1)-1)\n%v\n}\nm.StackPush2(m.TrackPeek(0), m.StackPeek(1)-1)\n%v",
+			-pc, jump(b, next), fail(b))
+		b2 = &genBlock{}
+		b2.add("m.TrackPop(2)\nm.StackPush2(m.TrackPeek(0), m.TrackPeek(1))\n%v", fail(b2))
+
+	case syntax.Lazybranchcount:
+		f.add("m.StackPop(2)\nmark = m.StackPeek(0)\ncount = m.StackPeek(1)\nif count < 0 {\nm.TrackPush1(%d, mark)\nm.StackPush2(m.Pos(), count+1)\n%v\n}\nm.TrackPush3(%d, mark, count, m.Pos())",
+			-pc, jump(f, operand(0)), pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(3)\nmark = m.TrackPeek(0)\npos = m.TrackPeek(2)\nif m.TrackPeek(1) < %d && pos != mark {\nm.SetPos(pos)\nm.StackPush2(pos, m.TrackPeek(1)+1)\nm.TrackPush1(%d, mark)\n%v\n}\nm.StackPush2(m.TrackPeek(0), m.TrackPeek(1))\n%v",
+			operand(1), -pc, jump(b, operand(0)), fail(b))
+		b2 = &genBlock{}
+		b2.add("m.TrackPop(1)\nm.StackPop(2)\nm.StackPush2(m.TrackPeek(0), m.StackPeek(1)-1)\n%v", fail(b2))
+
+	case syntax.Setjump:
+		f.add("m.StackPush2(m.Trackpos(), m.Crawlpos())\nm.TrackPush(%d)", pc)
+		b = &genBlock{}
+		b.add("m.StackPop(2)\n%v", fail(b))
+
+	case syntax.Backjump:
+		f.add("m.StackPop(2)\nm.Trackto(m.StackPeek(0))\nfor m.Crawlpos() != m.StackPeek(1) {\nm.Uncapture()\n}\n%v", fail(f))
+		f.terminal = true
+
+	case syntax.Forejump:
+		f.add("m.StackPop(2)\nm.Trackto(m.StackPeek(0))\nm.TrackPush1(%d, m.StackPeek(1))", pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(1)\nfor m.Crawlpos() != m.TrackPeek(0) {\nm.Uncapture()\n}\n%v", fail(b))
+
+	case syntax.Bol:
+		f.add("if m.Pos() > 0 && m.CharAt(m.Pos()-1) != '\\n' {\n%v\n}", fail(f))
+
+	case syntax.Eol:
+		f.add("if m.Pos() < m.End() && m.CharAt(m.Pos()) != '\\n' {\n%v\n}", fail(f))
+
+	case syntax.Boundary:
+		f.add("if !m.IsBoundary(m.Pos(), false) {\n%v\n}", fail(f))
+
+	case syntax.Nonboundary:
+		f.add("if m.IsBoundary(m.Pos(), false) {\n%v\n}", fail(f))
+
+	case syntax.ECMABoundary:
+		f.add("if !m.IsBoundary(m.Pos(), true) {\n%v\n}", fail(f))
+
+	case syntax.NonECMABoundary:
+		f.add("if m.IsBoundary(m.Pos(), true) {\n%v\n}", fail(f))
+
+	case syntax.Beginning:
+		f.add("if m.Pos() > 0 {\n%v\n}", fail(f))
+
+	case syntax.Start:
+		f.add("if m.Pos() != m.Start() {\n%v\n}", fail(f))
+
+	case syntax.EndZ:
+		f.add("if m.End()-m.Pos() > 1 || m.End()-m.Pos() == 1 && m.CharAt(m.Pos()) != '\\n' {\n%v\n}", fail(f))
+
+	case syntax.End:
+		f.add("if m.Pos() < m.End() {\n%v\n}", fail(f))
+
+	case syntax.One:
+		usesChars()
+		f.add("if %v < 1 || %v != %v {\n%v\n}", forwardchars, forwardcharnext, charLit(operand(0)), fail(f))
+
+	case syntax.Notone:
+		usesChars()
+		f.add("if %v < 1 || %v == %v {\n%v\n}", forwardchars, forwardcharnext, charLit(operand(0)), fail(f))
+
+	case syntax.Set:
+		usesChars()
+		f.add("if %v < 1 || !m.InSet(%d, %v) {\n%v\n}", forwardchars, operand(0), forwardcharnext, fail(f))
+
+	case syntax.Multi:
+		f.add("if !m.MatchString(%d, %v, %v) {\n%v\n}", operand(0), rtl, ci, fail(f))
+
+	case syntax.Ref:
+		if g.ecma {
+			// an undefined group matches the empty string
+			f.add("if m.IsMatched(%d) && !m.MatchRef(%d, %v, %v) {\n%v\n}", operand(0), operand(0), rtl, ci, fail(f))
+		} else {
+			f.add("if !m.IsMatched(%d) || !m.MatchRef(%d, %v, %v) {\n%v\n}", operand(0), operand(0), rtl, ci, fail(f))
+		}
+
+	case syntax.Onerep, syntax.Notonerep, syntax.Setrep:
+		usesChars()
+		f.add("if %v < %d {\n%v\n}\nfor i = %d; i > 0; i-- {\nif %v {\n%v\n}\n}",
+			forwardchars, operand(1), fail(f), operand(1), mismatch(), fail(f))
+
+	case syntax.Oneloop, syntax.Notoneloop, syntax.Setloop:
+		usesChars()
+		f.add("c = %d\nif c > %v {\nc = %v\n}\nfor i = c; i > 0; i-- {\nif %v {\n%v\nbreak\n}\n}\nif c > i {\nm.TrackPush2(%d, c-i-1, m.Pos()%v)\n}",
+			operand(1), forwardchars, forwardchars, mismatch(), backwardnext, pc, negBump)
+		b = &genBlock{}
+		b.add("m.TrackPop(2)\ni = m.TrackPeek(0)\npos = m.TrackPeek(1)\nm.SetPos(pos)\nif i > 0 {\nm.TrackPush2(%d, i-1, pos%v)\n}\n%v",
+			pc, negBump, jump(b, next))
+
+	case syntax.Onelazy, syntax.Notonelazy, syntax.Setlazy:
+		usesChars()
+		f.add("c = %d\nif c > %v {\nc = %v\n}\nif c > 0 {\nm.TrackPush2(%d, c-1, m.Pos())\n}",
+			operand(1), forwardchars, forwardchars, pc)
+		b = &genBlock{}
+		b.add("m.TrackPop(2)\npos = m.TrackPeek(1)\nm.SetPos(pos)\nif %v {\n%v\n}\ni = m.TrackPeek(0)\nif i > 0 {\nm.TrackPush2(%d, i-1, pos%v)\n}\n%v",
+			mismatch(), fail(b), pc, bump, jump(b, next))
+
+	default:
+		// Prune, which the interpreter doesn't run either
+		f.add("return m.UnknownState()")
+		f.terminal = true
+	}
+
+	return f, b, b2
+}
+
+// charLit returns a Go literal for the character ch
+func charLit(ch int) string {
+	if ch >= ' ' && ch <= '~' && ch != '\'' && ch != '\\' {
+		return "'" + string(rune(ch)) + "'"
+	}
+	return fmt.Sprintf("%#x", ch)
+}
+
+// commentEscape makes s safe to put in a line comment, escaping anything but printable ASCII
+func commentEscape(s string) string {
+	buf := &strings.Builder{}
+	for _, ch := range s {
+		if ch >= ' ' && ch <= '~' {
+			buf.WriteRune(ch)
+		} else {
+			q := strconv.QuoteRuneToASCII(ch)
+			buf.WriteString(q[1 : len(q)-1])
+		}
+	}
+	return buf.String()
+}
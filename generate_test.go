@@ -0,0 +1,274 @@
+package binexp
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+var updateGenerated = flag.Bool("update", false, "update program_gen_test.go")
+
+// generatedTests are the patterns with programs generated in program_gen_test.go.  Between
+// them they have every kind of instruction that instructionTests do, which
+// TestGeneratedInstructions checks, so the generated code for each is run.
+var generatedTests = []struct {
+	pattern string
+	opt     RegexOptions
+}{
+	{`(?i:(?<q>['"])[a-z]+?\k<q>|hello world|x[^y]y)|(?:ab){0,4}?c|(?:a[bc]){2,}d|(a*?)+?b|(a?)*b|(?>a+|ab)c|(?<!foo)bar\b|` +
+		`^(?:(?<open>\()|[^()]|(?<close-open>\)))+(?(open)(?!))$|(?m:^#\w+$)|\Gab|cd\z|"[^"]*"|[^x]{3}z|[^a]*?d|b\B.|<.*?>`, None},
+	{`\d+-\w{2,3}?(?:ab){2,}?|a+|(?<=(a+))b|c(?=(d+))|(?i)abc[x-z]*|"[^"]*"|q{2}|(?<r>a)\k<r>`, RightToLeft},
+	{`(a)|\1b|\bfoo\B|\x55\x48\x89\xe5(?:\x41[\x54-\x57])+`, ECMAScript | ByteRunes},
+}
+
+// instructionTests are patterns that between them have every kind of instruction the parser
+// produces.  Patterns that could be matched in linear time are given a lookahead to keep them
+// on the backtracking engine.
+var instructionTests = []struct {
+	pattern string
+	opt     RegexOptions
+}{
+	{`(?<w>\w+)\s\k<w>`, None},
+	{`(?i)(?<q>['"])[a-z]+?\k<q>`, None},
+	{`(?=.)(a|ab)(c|bcd)(d*)`, None},
+	{`(?=.)(?:ab){2,4}?c`, None},
+	{`(?=.)(?:a[bc]){2,}d`, None},
+	{`(?=.)(a+)+b`, None},
+	{`(?=.)(a*?)+?b`, None},
+	{`(?=.)(a?)*b|(?:a?){2,5}c`, None},
+	{`(?>a+|ab)c|(?>x*)x`, None},
+	{`(?<=\d{2})[a-f]+`, None},
+	{`(?<!foo)bar\b`, None},
+	{`(?<o>\()?[^()]+(?(o)\))`, None},
+	{`^(?:(?<open>\()|[^()]|(?<close-open>\)))+(?(open)(?!))$`, None},
+	{`(?m)(?=.)^\s*#\w+$`, None},
+	{`(?=.)\Gab|\Aab|cd\z|ef\Z`, None},
+	{`(?=.)"[^"]*"|'[^']*?'|[^x]{3}z`, None},
+	{`(?=.)a{3}|a*?b|[ab]*?c|[^a]*?d`, None},
+	{`(?i)(?=.)hello world|x[^y]y`, None},
+	{`(?=.)a(?!)|b\B.`, None},
+	{`(?=.)(\w)\1*`, None},
+	{`(?=.)<.*?>|(?s)\n.`, None},
+	{`\d+-\w{2,3}?(?:ab){2,}?`, RightToLeft},
+	{`(?i)abc[x-z]*|"[^"]*"|q{2}|(a)\1`, RightToLeft},
+	{`(?<=(a+))b|c(?=(d+))`, RightToLeft},
+	{`(a)|\1b`, ECMAScript},
+	{`(?=.)\bfoo\B|(?i:bar)`, ECMAScript},
+	{`(?=.)\x55\x48\x89\xe5(?:\x41[\x54-\x57])+`, ByteRunes},
+	{`(?=.)\m{F8:50}+\xc3`, ByteRunes},
+}
+
+var generatedInputs = []string{
+	"the the cat cat 'ab' \"Cd\" 'x\"",
+	"abcd abbcd ababc abababababc abacd aaab aaaa ab acacad",
+	"(a(b)c) (x (y)) ((z)",
+	"12abcdef 3x foobar bar barn\n#tag\n  #tag2\n",
+	"abxab cd\nef\n",
+	"\"a\"b\"c\" 'x''y' uvwz aaa bbbc abd xxd",
+	"hello world HeLLo WoRLD xzy xyy",
+	"aaab xxxxxc b a\n<a\nb> <c> 12-ab 345-abcd-ababab 6-xyabab",
+	"\x55\x48\x89\xe5\x41\x54\x41\x57 \x50\x51\xc3 \x55\x48\x89\xe5\x41",
+}
+
+func compileGenerated(t *testing.T, opt RegexOptions) []*Regexp {
+	res := make([]*Regexp, len(generatedTests))
+	for i, test := range generatedTests {
+		res[i] = MustCompile(test.pattern, test.opt|opt)
+	}
+	return res
+}
+
+func TestGenerateGo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := GenerateGo(buf, "binexp_test", compileGenerated(t, None)); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if *updateGenerated {
+		if err := os.WriteFile("program_gen_test.go", buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	existing, err := os.ReadFile("program_gen_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(existing, buf.Bytes()) {
+		t.Errorf("program_gen_test.go is out of date, run go test -run TestGenerateGo -update")
+	}
+}
+
+// instructionKinds returns the kinds of instruction in the program of re, which are their
+// opcodes without the backtracking bits and balancing groups' Capturemark apart from the
+// plain one, each with the description of one of them
+func instructionKinds(re *Regexp) map[int]string {
+	kinds := map[int]string{}
+	codes := re.code.Codes
+	for pc := 0; pc < len(codes); pc += syntax.OpcodeSize(syntax.InstOp(codes[pc])) {
+		kind := codes[pc] &^ (syntax.Back | syntax.Back2)
+		if kind&syntax.Mask == syntax.Capturemark && codes[pc+2] != -1 {
+			kind |= syntax.Back
+		}
+		kinds[kind] = re.code.OpcodeDescription(pc)
+	}
+	return kinds
+}
+
+func TestGeneratedInstructions(t *testing.T) {
+	generated := map[int]string{}
+	for _, re := range compileGenerated(t, None) {
+		if re.code.NFA != nil {
+			t.Errorf("%q: expected a backtracking program", re.pattern)
+		}
+		for kind, desc := range instructionKinds(re) {
+			generated[kind] = desc
+		}
+	}
+
+	for _, test := range instructionTests {
+		re := MustCompile(test.pattern, test.opt)
+		if re.code.NFA != nil {
+			t.Errorf("%q: expected a backtracking program", test.pattern)
+		}
+		if err := GenerateGo(io.Discard, "binexp_test", []*Regexp{re}); err != nil {
+			t.Errorf("%q: unexpected err: %v", test.pattern, err)
+		}
+		for kind, desc := range instructionKinds(re) {
+			if _, ok := generated[kind]; !ok {
+				t.Errorf("%q has %v, which none of generatedTests have", test.pattern, desc)
+				generated[kind] = desc
+			}
+		}
+	}
+}
+
+// generatedResult describes every match of re in the input and every capture of every group,
+// along with how much of the stacks the matches took and the error that stopped the search
+func generatedResult(re *Regexp, input string) string {
+	var sb strings.Builder
+	var m *Match
+	var err error
+	if re.options&ByteRunes != 0 {
+		m, err = re.FindBytesMatchStartingAt([]byte(input), 0)
+	} else {
+		m, err = re.FindStringMatch(input)
+	}
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		for _, g := range m.Groups() {
+			fmt.Fprintf(&sb, "%v:", g.Name)
+			for _, c := range g.Captures {
+				fmt.Fprintf(&sb, "(%v,%v)", c.Index, c.Length)
+			}
+		}
+		fmt.Fprintf(&sb, " %v, ", m.Stats().PeakStackBytes)
+	}
+	fmt.Fprint(&sb, err)
+	return sb.String()
+}
+
+func TestGeneratedPrograms(t *testing.T) {
+	interpreted := compileGenerated(t, None)
+	compiled := compileGenerated(t, Compiled)
+
+	for i, re := range compiled {
+		if re.generatedProgram() == nil {
+			t.Errorf("%q: no generated program", re.pattern)
+			continue
+		}
+		if interpreted[i].generatedProgram() != nil {
+			t.Errorf("%q: the generated program should only be used with the Compiled option", re.pattern)
+		}
+
+		matched, cutOff := false, false
+		for _, input := range generatedInputs {
+			want, got := generatedResult(interpreted[i], input), generatedResult(re, input)
+			if got != want {
+				t.Errorf("%q on %q: generated program found\n%v\nexpected\n%v", re.pattern, input, got, want)
+			}
+			matched = matched || !strings.HasPrefix(want, "<nil>")
+
+			// the generated program only takes a step when it backtracks or jumps back, so it
+			// finishes any search the interpreter finishes within a step limit
+			interpreted[i].MatchStepLimit, re.MatchStepLimit = 40, 40
+			limited, got := generatedResult(interpreted[i], input), generatedResult(re, input)
+			re.MatchStepLimit = 1
+			cut := generatedResult(re, input)
+			interpreted[i].MatchStepLimit, re.MatchStepLimit = 0, 0
+			if limited == want && got != want {
+				t.Errorf("%q on %q with a step limit: generated program found\n%v\nexpected\n%v", re.pattern, input, got, want)
+			}
+			cutOff = cutOff || strings.Contains(cut, "step limit of 1 exceeded")
+		}
+		if !matched {
+			t.Errorf("%q: no matches in any of the inputs", re.pattern)
+		}
+		if !cutOff {
+			t.Errorf("%q: expected a step limit to cut the generated program off", re.pattern)
+		}
+	}
+}
+
+func TestGeneratedProgramKey(t *testing.T) {
+	key := func(pattern string, opt RegexOptions) string {
+		k, err := MustCompile(pattern, opt).ProgramKey()
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		return k
+	}
+
+	if key(`(a)\1`, None) != key(`(a)\1`, Compiled) {
+		t.Errorf("Expected the Compiled option not to change the key")
+	}
+	if key(`(a)\1`, None) == key(`(a)\1`, ECMAScript) {
+		t.Errorf("Expected ECMAScript, which changes how the program runs, to change the key")
+	}
+	if key(`(a)\1`, None) == key(`(b)\1`, None) {
+		t.Errorf("Expected different programs to have different keys")
+	}
+
+	// a program loaded with UnmarshalBinary finds its generated program too
+	data, err := MustCompile(generatedTests[0].pattern, generatedTests[0].opt|Compiled).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	loaded := &Regexp{}
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if loaded.generatedProgram() == nil {
+		t.Errorf("Expected the loaded Regexp to use the generated program")
+	}
+}
+
+// BenchmarkGeneratedPrograms compares the generated programs with the interpreter running
+// the same programs
+func BenchmarkGeneratedPrograms(b *testing.B) {
+	for i, test := range generatedTests {
+		for _, opt := range []RegexOptions{None, Compiled} {
+			name := fmt.Sprintf("%v/Interpreted", i)
+			if opt == Compiled {
+				name = fmt.Sprintf("%v/Compiled", i)
+			}
+			b.Run(name, func(b *testing.B) {
+				re := MustCompile(test.pattern, test.opt|opt)
+				inputs := make([][]byte, len(generatedInputs))
+				for j, input := range generatedInputs {
+					inputs[j] = []byte(input)
+				}
+				for b.Loop() {
+					for _, input := range inputs {
+						if _, err := re.FindAllBytesMatch(input, -1, false); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
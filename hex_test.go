@@ -0,0 +1,101 @@
+package binexp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompileHex_Signature(t *testing.T) {
+	re := MustCompileHex("CA ?? ?? [2-4] (E8 | E9) ?? ?? ?? ??", None)
+
+	data := []byte{0x00, 0xca, 0x01, 0x02, 0x03, 0x04, 0x05, 0xe9, 0x10, 0x20, 0x30, 0x40, 0xff}
+	m, err := re.FindBytesMatchStartingAt(data, 0)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if m == nil || m.Index != 1 || !bytes.Equal(m.Bytes(), data[1:12]) {
+		t.Fatalf("Expected match at 1 of %x, got %v", data[1:12], m)
+	}
+	if len(m.Groups()) != 1 {
+		t.Fatalf("Expected alternations not to capture, got %v groups", len(m.Groups()))
+	}
+
+	// jump too long
+	data = []byte{0xca, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0xe8, 0x10, 0x20, 0x30, 0x40}
+	if m, _ := re.FindBytesMatchStartingAt(data, 0); m != nil {
+		t.Fatalf("Expected no match, got %v", m)
+	}
+}
+
+func TestCompileHex_Forms(t *testing.T) {
+	for _, test := range []struct {
+		hex   string
+		input []byte
+		match bool
+	}{
+		{"cafe", []byte{0xca, 0xfe}, true},
+		{"CA FE", []byte{0xca, 0xff}, false},
+		{"C? FE", []byte{0xc7, 0xfe}, true},
+		{"C? FE", []byte{0xd7, 0xfe}, false},
+		{"?A", []byte{0x5a}, true},
+		{"?A", []byte{0x5b}, false},
+		{"~00", []byte{0x00}, false},
+		{"~00", []byte{0x01}, true},
+		{"~C?", []byte{0xc1}, false},
+		{"~C?", []byte{0xd1}, true},
+		{"01 [2] 02", []byte{0x01, 0xff, 0xff, 0x02}, true},
+		{"01 [2] 02", []byte{0x01, 0xff, 0x02}, false},
+		{"01 [1-] 02", []byte{0x01, 0xff, 0xff, 0xff, 0x02}, true},
+		{"01 [1-] 02", []byte{0x01, 0x02}, false},
+		{"01 [-] 02", []byte{0x01, 0x02}, true},
+		{"01 ( 02 | 03 (04 | 05) ) 06", []byte{0x01, 0x03, 0x05, 0x06}, true},
+		{"01 ( 02 | 03 (04 | 05) ) 06", []byte{0x01, 0x03, 0x06}, false},
+	} {
+		re, err := CompileHex(test.hex, None)
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.hex, err)
+		}
+		m, err := re.FindBytesMatchStartingAt(test.input, 0)
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", test.hex, err)
+		}
+		if (m != nil) != test.match {
+			t.Errorf("%q on %x: wanted match %v, got %v", test.hex, test.input, test.match, m)
+		}
+	}
+}
+
+func TestCompileHex_SameAsRegex(t *testing.T) {
+	hex := MustCompileHex("E8 ?? ?? ?? ?? C3", RightToLeft)
+	re := MustCompile(`\xe8[\x00-\xff]{4}\xc3`, ByteRunes|RightToLeft)
+
+	if want, got := re.code.Dump(), hex.code.Dump(); want != got {
+		t.Fatalf("Expected the same code as the regex\nwanted:\n%v\ngot:\n%v", want, got)
+	}
+	if hex.code.BmPrefix == nil {
+		t.Fatalf("Expected a Boyer-Moore prefix")
+	}
+}
+
+func TestCompileHex_Errors(t *testing.T) {
+	for _, hex := range []string{
+		"CA F",
+		"CA FG",
+		"CA (FE",
+		"CA FE)",
+		"CA [",
+		"CA [x]",
+		"CA []",
+		"CA [4-2]",
+		"CA [2-x]",
+		"~??",
+		"CA \\xfe",
+		"CA | FE",
+		"(CA) | FE",
+		"CA (FE | E8) | 90",
+	} {
+		if re, err := CompileHex(hex, None); err == nil {
+			t.Errorf("%q: expected an error, got %v", hex, re.code.Dump())
+		}
+	}
+}
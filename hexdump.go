@@ -0,0 +1,273 @@
+package binexp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the ANSI escapes HexDump colors the match and its groups with.  The match is shown in
+// bold and the groups cycle through the colors.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+)
+
+var ansiGroupColors = []string{"\x1b[1;31m", "\x1b[1;32m", "\x1b[1;33m", "\x1b[1;34m", "\x1b[1;35m", "\x1b[1;36m"}
+
+// hexDumpLegendMax is the most characters of a capture shown in hex after the dump
+const hexDumpLegendMax = 16
+
+// HexDump renders the match as a classic hex dump, with the offset, hex values and ASCII of
+// 16 characters a line, along with up to before characters of context ahead of it and after
+// characters following it.  The match and each capture of its groups are bracketed in the hex
+// column, or if color is true shown in color with ANSI escapes, and a line for each capture
+// follows the dump, giving its group, position, length and (the start of) its value in hex.
+//
+// The offsets are positions in the input, like Capture.Index, so for a match in a byte slice
+// or an io.ReaderAt they're byte offsets.  Characters above 0xff, which only come from
+// searching a string, are shown as ?? and aren't printable.
+func (m *Match) HexDump(before, after int, color bool) string {
+	start := max(m.Index-before, m.offset)
+	end := min(m.Index+m.Length+after, m.offset+m.textLen())
+	chars := m.chars(start, end)
+
+	// the match and every capture, outermost first
+	var spans []hexSpan
+	for i, g := range m.Groups() {
+		for _, c := range g.Captures {
+			spans = append(spans, hexSpan{c.Index, c.Index + c.Length, i})
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		if spans[i].end != spans[j].end {
+			return spans[i].end > spans[j].end
+		}
+		return spans[i].group < spans[j].group
+	})
+
+	d := &hexDumper{spans: clipSpans(spans, start, end), color: color}
+	var hexCols, asciiCols []string
+	var widths []int
+	width := 0
+	for line := start &^ 15; line < end || (line == start && start == end); line += 16 {
+		hex, w := d.hexColumn(line, start, end, chars)
+		hexCols = append(hexCols, hex)
+		asciiCols = append(asciiCols, d.asciiColumn(line, start, end, chars))
+		widths = append(widths, w)
+		width = max(width, w)
+	}
+
+	sb := &strings.Builder{}
+	for i := range hexCols {
+		fmt.Fprintf(sb, "%08x %v%v|%v|\n", (start&^15)+i*16, hexCols[i], strings.Repeat(" ", width-widths[i]), asciiCols[i])
+	}
+
+	// the groups, which are the spans other than the match
+	for _, s := range spans {
+		if s.group == 0 {
+			continue
+		}
+		name := m.regex.GroupNameFromNumber(s.group)
+		if color {
+			name = d.colorOf(s.group) + name + ansiReset
+		}
+		fmt.Fprintf(sb, "  %v %#x length %v:", name, s.start, s.end-s.start)
+		for _, ch := range m.chars(s.start, min(s.end, s.start+hexDumpLegendMax)) {
+			sb.WriteByte(' ')
+			sb.WriteString(hexChar(ch))
+		}
+		if s.end-s.start > hexDumpLegendMax {
+			sb.WriteString(" ...")
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// hexSpan is the match or a capture of one of its groups, from start up to end
+type hexSpan struct {
+	start, end int
+	group      int
+}
+
+// clipSpans returns the spans that show in a dump of the characters from start up to end,
+// cut down to fit in it.  Those that only touch its ends are left out, unless they're empty.
+func clipSpans(spans []hexSpan, start, end int) []hexSpan {
+	var clipped []hexSpan
+	for _, s := range spans {
+		if s.start == s.end && (s.start < start || s.start > end) ||
+			s.start != s.end && (s.end <= start || s.start >= end) {
+			continue
+		}
+		clipped = append(clipped, hexSpan{max(s.start, start), min(s.end, end), s.group})
+	}
+	return clipped
+}
+
+// hexDumper writes the columns of the lines of a HexDump
+type hexDumper struct {
+	// spans are clipped to the characters dumped
+	spans []hexSpan
+	color bool
+}
+
+// hexColumn returns the hex values of the 16 characters from line, of which those from start
+// up to end are shown, along with how wide the column is on screen
+func (d *hexDumper) hexColumn(line, start, end int, chars []rune) (string, int) {
+	sb := &strings.Builder{}
+	width := 0
+	write := func(s string, w int) {
+		sb.WriteString(s)
+		width += w
+	}
+
+	current := ""
+	for i := line; i <= line+16; i++ {
+		if i == line+8 {
+			write(" ", 1)
+		}
+
+		// the gap before the character, where the spans ending and starting here are
+		// bracketed.  Those going on past the end of a line are closed there and opened again
+		// at the start of the next, and empty captures are kept inside the match.
+		gap := ""
+		if !d.color && i >= start && i <= end {
+			first := i == max(line, start) && i < line+16
+			closes, empties, closeMatch, opens := "", "", "", ""
+			for j := len(d.spans) - 1; j >= 0; j-- {
+				if s := d.spans[j]; s.start < i && i > line && (s.end == i || i == line+16 && s.end > i) {
+					if s.group == 0 {
+						closeMatch = "]"
+					} else {
+						closes += "]"
+					}
+				}
+			}
+			for _, s := range d.spans {
+				if s.start == i && i < line+16 {
+					if s.end == i {
+						empties += "[]"
+					} else {
+						opens += "["
+					}
+				} else if first && s.start < i && s.end > i {
+					opens += "["
+				}
+			}
+			gap = closes + empties + closeMatch + opens
+		}
+		if gap == "" {
+			gap = " "
+		}
+		write(gap, len(gap))
+		if i == line+16 {
+			break
+		}
+
+		if i < start || i >= end {
+			write("  ", 2)
+			continue
+		}
+		if d.color {
+			if c := d.colorAt(i); c != current {
+				sb.WriteString(ansiReset + c)
+				current = c
+			}
+		}
+		write(hexChar(chars[i-start]), 2)
+	}
+	if current != "" {
+		sb.WriteString(ansiReset)
+	}
+	return sb.String(), width
+}
+
+// asciiColumn returns the characters from line that are shown as ASCII, with the unprintable
+// ones as dots
+func (d *hexDumper) asciiColumn(line, start, end int, chars []rune) string {
+	sb := &strings.Builder{}
+	current := ""
+	for i := line; i < line+16; i++ {
+		if i < start || i >= end {
+			if current != "" {
+				sb.WriteString(ansiReset)
+				current = ""
+			}
+			sb.WriteByte(' ')
+			continue
+		}
+		if d.color {
+			if c := d.colorAt(i); c != current {
+				sb.WriteString(ansiReset + c)
+				current = c
+			}
+		}
+		if ch := chars[i-start]; ch >= 0x20 && ch < 0x7f {
+			sb.WriteRune(ch)
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	if current != "" {
+		sb.WriteString(ansiReset)
+	}
+	return sb.String()
+}
+
+// colorAt returns the escape for the innermost span holding the character at i, or "" if
+// it's just context
+func (d *hexDumper) colorAt(i int) string {
+	c := ""
+	for _, s := range d.spans {
+		if s.start <= i && i < s.end {
+			c = d.colorOf(s.group)
+		}
+	}
+	return c
+}
+
+func (d *hexDumper) colorOf(group int) string {
+	if group == 0 {
+		return ansiBold
+	}
+	return ansiGroupColors[(group-1)%len(ansiGroupColors)]
+}
+
+// hexChar formats ch as two hex digits, or ?? if it doesn't fit in a byte
+func hexChar(ch rune) string {
+	if ch < 0 || ch > 0xff {
+		return "??"
+	}
+	return fmt.Sprintf("%02x", ch)
+}
+
+// chars returns the characters of the input from start up to end, which are positions in
+// the input like Capture.Index.  Those that can't be read back from an io.ReaderAt are -1,
+// which is shown as ??.
+func (c *Capture) chars(start, end int) []rune {
+	start -= c.offset
+	end -= c.offset
+	if c.bytes == nil && c.paged == nil {
+		return c.text[start:end]
+	}
+
+	var b []byte
+	if c.bytes != nil {
+		b = c.bytes[start:end]
+	} else {
+		b, _ = c.paged.readAt(start, end)
+	}
+	runes := make([]rune, end-start)
+	for i := range runes {
+		if i < len(b) {
+			runes[i] = rune(b[i])
+		} else {
+			runes[i] = -1
+		}
+	}
+	return runes
+}
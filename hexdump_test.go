@@ -0,0 +1,154 @@
+package binexp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMatchHexDump(t *testing.T) {
+	data := []byte("hello\x00\x01\x02\xe8\x3f\xff\xff\xff\x48\x8b\x05\x9d world, this is filler text\xe8\x01\x02\x03\x04")
+	re := MustCompile(`\xe8(?<rel32>.(.).{2})`, ByteRunes|Singleline)
+	m, err := re.FindBytesMatchStartingAt(data, 0)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want := "" +
+		"00000000        6c 6c 6f 00 01 02 [e8[3f[ff]ff ff]]48 8b 05 |  llo....?...H..|\n" +
+		"00000010  9d 20 77 6f 72 6c                                 |. worl          |\n" +
+		"  rel32 0x9 length 4: 3f ff ff ff\n" +
+		"  1 0xa length 1: ff\n"
+	if got := m.HexDump(6, 9, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+
+	// the context stops at the ends of the input, and a match across lines is closed at the end of
+	// each and opened again at the start of the next
+	m, err = re.FindNextMatch(m)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want = "" +
+		"00000000  68 65 6c 6c 6f 00 01 02  e8 3f ff ff ff 48 8b 05   |hello....?...H..|\n" +
+		"00000010  9d 20 77 6f 72 6c 64 2c  20 74 68 69 73 20 69 73   |. world, this is|\n" +
+		"00000020  20 66 69 6c 6c 65 72 20  74 65 78 74[e8[01[02]03]] | filler text....|\n" +
+		"00000030 [[04]]                                              |.               |\n" +
+		"  rel32 0x2d length 4: 01 02 03 04\n" +
+		"  1 0x2e length 1: 02\n"
+	if got := m.HexDump(100, 100, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+
+	// a match read through an io.ReaderAt is dumped the same
+	paged, err := re.FindReaderAtMatchStartingAt(bytes.NewReader(data), int64(len(data)), 10)
+	if err != nil || paged == nil {
+		t.Fatalf("Expected a match, got %v %v", paged, err)
+	}
+	if got := paged.HexDump(100, 100, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+
+	// in color the brackets are left out, and the match and its groups are colored
+	got := m.HexDump(0, 0, true)
+	for _, s := range []string{ansiBold + "e8", ansiGroupColors[1] + "01", ansiGroupColors[0] + "02",
+		ansiGroupColors[1] + "03", ansiGroupColors[1] + "rel32" + ansiReset} {
+		if !strings.Contains(got, s) {
+			t.Errorf("Expected %q in\n%q", s, got)
+		}
+	}
+	if plain := stripANSI(got); strings.ContainsAny(plain, "[]") {
+		t.Errorf("Expected no brackets in\n%v", plain)
+	}
+}
+
+func TestMatchHexDumpString(t *testing.T) {
+	// repeated and empty captures, and characters that don't fit in a byte
+	m, err := MustCompile(`(a)+(b*)c|ĉ()`, 0).FindStringMatch("xxaaacyy")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want := "" +
+		"00000000  78 78[[61][61][61][]63]79 79                          |xxaaacyy        |\n" +
+		"  1 0x2 length 1: 61\n" +
+		"  1 0x3 length 1: 61\n" +
+		"  1 0x4 length 1: 61\n" +
+		"  2 0x5 length 0:\n"
+	if got := m.HexDump(2, 2, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+
+	m, err = m.regex.FindNextMatch(m)
+	if err != nil || m != nil {
+		t.Fatalf("Expected no more matches, got %v %v", m, err)
+	}
+	m, err = MustCompile(`(a)+(b*)c|ĉ()`, 0).FindStringMatch("zĉ")
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want = "" +
+		"00000000  7a[??[]]                                           |z.              |\n" +
+		"  3 0x2 length 0:\n"
+	if got := m.HexDump(2, 2, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestMatchHexDumpClipped(t *testing.T) {
+	// captures in lookarounds that reach outside the dump are cut off at its ends
+	m, err := MustCompile(`(?<=(a))b(?=(c))`, ByteRunes).FindBytesMatchStartingAt([]byte("abc"), 0)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want := "" +
+		"00000000    [62]                                           | b              |\n" +
+		"  1 0x0 length 1: 61\n" +
+		"  2 0x2 length 1: 63\n"
+	if got := m.HexDump(0, 0, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+
+	// a capture started before the dump is opened at its start
+	m, err = MustCompile(`(?<=(a.))b`, ByteRunes|Singleline).FindBytesMatchStartingAt([]byte("axb"), 0)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v %v", m, err)
+	}
+	want = "" +
+		"00000000    [78][62]                                        | xb             |\n" +
+		"  1 0x0 length 2: 61 78\n"
+	if got := m.HexDump(1, 0, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestMatchHexDumpReadError(t *testing.T) {
+	// what can't be read back for the dump is shown as ??
+	data := []byte("xxabccx")
+	r := &shrinkingReaderAt{bytes.NewReader(data), int64(len(data))}
+	m, err := MustCompile("abc(c)", ByteRunes).FindReaderAtMatchStartingAt(r, int64(len(data)), -1)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+
+	r.size = 4
+	want := "" +
+		"00000000     78[61 62 ??[??]]??                             | xab...         |\n" +
+		"  1 0x5 length 1: ??\n"
+	if got := m.HexDump(1, 1, false); got != want {
+		t.Errorf("Expected\n%v\ngot\n%v", want, got)
+	}
+}
+
+// stripANSI removes the escapes HexDump colors with
+func stripANSI(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
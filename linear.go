@@ -0,0 +1,568 @@
+package binexp
+
+import (
+	"encoding/binary"
+	"sort"
+	"unicode"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+// Patterns that don't need backtracking are compiled to an NFA as well as to the
+// backtracking program (see syntax.NFA), and are matched by simulating it instead, which
+// takes time linear in the length of the text however the pattern is written.
+//
+// The NFA is simulated one character at a time with a Pike VM, which keeps a thread for
+// every way the pattern could be matching, in priority order, so it finds the same match
+// the backtracking program would.  A lazily built DFA first checks whether there's a match
+// at all, which is all a quick match needs.
+
+// capHistory is the captures made along a thread's path through the NFA, newest first.
+// Threads share the part of their history they have in common.
+type capHistory struct {
+	cap, start, end int
+	prev            *capHistory
+}
+
+type pikeThread struct {
+	pc     int
+	caps   *capHistory
+	starts []int // where each open group started, shared between threads until it's written
+}
+
+// pikeList is a list of threads in priority order, holding at most one per instruction
+type pikeList struct {
+	sparse  []int
+	threads []pikeThread
+}
+
+func (l *pikeList) contains(pc int) bool {
+	i := l.sparse[pc]
+	return i < len(l.threads) && l.threads[i].pc == pc
+}
+
+type pikeVM struct {
+	clist, nlist pikeList
+	captures     bool // whether the captures are wanted, or just whether there's a match
+}
+
+// maxDFAStates limits how many states of the DFA are built.  Past that the NFA is
+// simulated directly.
+const maxDFAStates = 4096
+
+// dfaContext describes the text either side of a position, which is all the zero width
+// assertions need to know
+type dfaContext uint16
+
+const (
+	ctxBeginning dfaContext = 1 << iota // at the beginning of the text
+	ctxStart                            // where the search started
+	ctxAfterNewline
+	ctxAfterWord
+	ctxAfterECMAWord
+	ctxEnd // at the end of the text
+	ctxBeforeNewline
+	ctxBeforeFinalNewline // before a newline that's the last character
+	ctxBeforeWord
+	ctxBeforeECMAWord
+)
+
+// afterContext is the context after ch, as far as ch can tell
+func afterContext(ch rune) dfaContext {
+	var ctx dfaContext
+	if ch == '\n' {
+		ctx |= ctxAfterNewline
+	}
+	if word, ecma := wordChar(ch); word {
+		ctx |= ctxAfterWord
+		if ecma {
+			ctx |= ctxAfterECMAWord
+		}
+	}
+	return ctx
+}
+
+// beforeContext is the context before ch, as far as ch can tell
+func beforeContext(ch rune) dfaContext {
+	var ctx dfaContext
+	if ch == '\n' {
+		ctx |= ctxBeforeNewline
+	}
+	if word, ecma := wordChar(ch); word {
+		ctx |= ctxBeforeWord
+		if ecma {
+			ctx |= ctxBeforeECMAWord
+		}
+	}
+	return ctx
+}
+
+// wordChar returns whether ch is a word character for \b, and for \b in ECMAScript
+func wordChar(ch rune) (word, ecma bool) {
+	if ch < 0x80 {
+		word = 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_'
+		return word, word
+	}
+	if !syntax.IsWordChar(ch) {
+		return false, false
+	}
+	return true, syntax.IsECMAWordChar(ch)
+}
+
+// satisfies returns true if the assertion holds in the context, in the same way as nfaAssert
+func (c dfaContext) satisfies(a syntax.NFAAssertion) bool {
+	is := func(flag dfaContext) bool { return c&flag != 0 }
+	switch a {
+	case syntax.AssertBol:
+		return is(ctxBeginning) || is(ctxAfterNewline)
+	case syntax.AssertEol:
+		return is(ctxEnd) || is(ctxBeforeNewline)
+	case syntax.AssertBoundary:
+		return is(ctxAfterWord) != is(ctxBeforeWord)
+	case syntax.AssertNonBoundary:
+		return is(ctxAfterWord) == is(ctxBeforeWord)
+	case syntax.AssertECMABoundary:
+		return is(ctxAfterECMAWord) != is(ctxBeforeECMAWord)
+	case syntax.AssertNonECMABoundary:
+		return is(ctxAfterECMAWord) == is(ctxBeforeECMAWord)
+	case syntax.AssertBeginning:
+		return is(ctxBeginning)
+	case syntax.AssertStart:
+		return is(ctxStart)
+	case syntax.AssertEndZ:
+		return is(ctxEnd) || is(ctxBeforeFinalNewline)
+	case syntax.AssertEnd:
+		return is(ctxEnd)
+	}
+	return false
+}
+
+// dfaState is a set of NFA instructions the search could be at, before following the
+// instructions that don't consume anything, since the assertions among them depend on
+// the next character.  The context after the last character is part of the state.
+type dfaState struct {
+	pcs     []int
+	ctx     dfaContext
+	initial bool // only the start of the NFA, so nothing is matching yet
+	next    [256]*dfaState
+}
+
+// dfaMatched is where a transition leads when the NFA reaches a match before the character
+var dfaMatched = &dfaState{}
+
+// lazyDFA is the DFA for an unanchored search of an NFA, built as it's needed.  Transitions on
+// characters up to 0xFF are cached in the states; others are worked out each time.
+type lazyDFA struct {
+	nfa    *syntax.NFA
+	states map[string]*dfaState
+	starts [ctxEnd]*dfaState // by the context before the start
+	full   bool              // too many states have been built
+
+	// scratch space for building states
+	seen    []bool
+	visited []int
+	pcs     []int
+	stack   []int
+	key     []byte
+}
+
+func newLazyDFA(nfa *syntax.NFA) *lazyDFA {
+	return &lazyDFA{
+		nfa:    nfa,
+		states: map[string]*dfaState{},
+		seen:   make([]bool, len(nfa.Insts)),
+	}
+}
+
+// closure follows the instructions that don't consume anything from the instructions in
+// s, in the context ctx, and returns the character instructions it reaches, or false if
+// it reaches a match
+func (d *lazyDFA) closure(s *dfaState, ctx dfaContext) ([]int, bool) {
+	chars := d.pcs[:0]
+	matched := false
+	d.stack = append(d.stack[:0], s.pcs...)
+	for len(d.stack) > 0 && !matched {
+		pc := d.stack[len(d.stack)-1]
+		d.stack = d.stack[:len(d.stack)-1]
+		if d.seen[pc] {
+			continue
+		}
+		d.seen[pc] = true
+		d.visited = append(d.visited, pc)
+
+		inst := &d.nfa.Insts[pc]
+		switch inst.Op {
+		case syntax.NFASplit:
+			d.stack = append(d.stack, inst.Out1, inst.Out)
+		case syntax.NFACaptureStart, syntax.NFACaptureEnd:
+			d.stack = append(d.stack, inst.Out)
+		case syntax.NFAAssert:
+			if ctx.satisfies(inst.Assertion) {
+				d.stack = append(d.stack, inst.Out)
+			}
+		case syntax.NFAChar, syntax.NFANotChar, syntax.NFASet:
+			chars = append(chars, pc)
+		case syntax.NFAMatch:
+			matched = true
+		}
+	}
+	d.unsee()
+	d.pcs = chars
+	return chars, !matched
+}
+
+func (d *lazyDFA) unsee() {
+	for _, pc := range d.visited {
+		d.seen[pc] = false
+	}
+	d.visited = d.visited[:0]
+}
+
+// state returns the state for the instructions in pcs, building it if it's new
+func (d *lazyDFA) state(pcs []int, ctx dfaContext) *dfaState {
+	if !d.nfa.Asserts {
+		// the context doesn't matter
+		ctx = 0
+	}
+	sort.Ints(pcs)
+
+	d.key = binary.AppendUvarint(d.key[:0], uint64(ctx))
+	for _, pc := range pcs {
+		d.key = binary.AppendUvarint(d.key, uint64(pc))
+	}
+	if s, ok := d.states[string(d.key)]; ok {
+		return s
+	}
+	if len(d.states) >= maxDFAStates {
+		d.full = true
+		return nil
+	}
+
+	s := &dfaState{
+		pcs:     append([]int(nil), pcs...),
+		ctx:     ctx,
+		initial: len(pcs) == 1 && pcs[0] == d.nfa.Start,
+	}
+	d.states[string(d.key)] = s
+	return s
+}
+
+// start returns the state for starting a match in the context ctx
+func (d *lazyDFA) start(ctx dfaContext) *dfaState {
+	if d.starts[ctx] == nil {
+		d.starts[ctx] = d.state(append(d.pcs[:0], d.nfa.Start), ctx)
+	}
+	return d.starts[ctx]
+}
+
+// step returns the state after s on ch, dfaMatched if there's a match before ch, or nil if
+// there are too many states.  finalNewline is true if ch is a newline at the end of the text.
+func (d *lazyDFA) step(s *dfaState, ch rune, finalNewline bool) *dfaState {
+	cache := ch <= 0xff && !(finalNewline && d.nfa.Asserts)
+	if cache && s.next[ch] != nil {
+		return s.next[ch]
+	}
+
+	ctx := s.ctx
+	if d.nfa.Asserts {
+		ctx |= beforeContext(ch)
+		if finalNewline {
+			ctx |= ctxBeforeFinalNewline
+		}
+	}
+	chars, ok := d.closure(s, ctx)
+	if !ok {
+		if cache {
+			s.next[ch] = dfaMatched
+		}
+		return dfaMatched
+	}
+
+	var pcs []int
+	for _, pc := range chars {
+		if inst := &d.nfa.Insts[pc]; nfaCharMatches(inst, ch) && !d.seen[inst.Out] {
+			d.seen[inst.Out] = true
+			d.visited = append(d.visited, inst.Out)
+			pcs = append(pcs, inst.Out)
+		}
+	}
+	// a match can start at any position
+	if !d.seen[d.nfa.Start] {
+		pcs = append(pcs, d.nfa.Start)
+	}
+	d.unsee()
+
+	var after dfaContext
+	if d.nfa.Asserts {
+		after = afterContext(ch)
+	}
+	next := d.state(pcs, after)
+	if cache && next != nil {
+		s.next[ch] = next
+	}
+	return next
+}
+
+// matchesAtEnd returns true if the NFA reaches a match from s at the end of the text
+func (d *lazyDFA) matchesAtEnd(s *dfaState) bool {
+	_, ok := d.closure(s, s.ctx|ctxEnd)
+	return !ok
+}
+
+// nfaCharMatches returns true if the character instruction inst matches ch
+func nfaCharMatches(inst *syntax.NFAInst, ch rune) bool {
+	if inst.Fold {
+		ch = unicode.ToLower(ch)
+	}
+	switch inst.Op {
+	case syntax.NFAChar:
+		return ch == inst.Ch
+	case syntax.NFANotChar:
+		return ch != inst.Ch
+	case syntax.NFASet:
+		return inst.Set.CharIn(ch)
+	}
+	return false
+}
+
+// scanNFA is scan for patterns with an NFA
+func (r *runner) scanNFA(quick bool) (*Match, error) {
+	found, known, err := r.dfaSearch(r.runtextstart)
+	if err != nil {
+		return nil, err
+	}
+	if known && !found {
+		return nil, nil
+	}
+	if known && quick {
+		// the caller only wants to know there's a match
+		r.initMatch()
+		return r.tidyMatch(true), nil
+	}
+
+	return r.pikeSearch(r.runtextstart, false, quick)
+}
+
+// nextStart finds the first place from pos that a match could start, using the same
+// prefix and anchor checks as scan.  It returns false if there's nowhere.
+func (r *runner) nextStart(pos int) (int, bool) {
+	for {
+		r.runtextpos = pos
+		if r.findFirstChar() {
+			return r.runtextpos, true
+		}
+		if r.runtextpos >= r.runtextend {
+			return 0, false
+		}
+		pos = r.runtextpos + 1
+	}
+}
+
+// dfaSearch returns whether there's a match starting at or after pos.  If the DFA grows
+// too big to say, known is false.
+func (r *runner) dfaSearch(pos int) (found, known bool, err error) {
+	if r.dfa == nil {
+		r.dfa = newLazyDFA(r.code.NFA)
+	}
+	d := r.dfa
+
+	var s *dfaState
+	for {
+		if s == nil || s.initial {
+			// skip ahead to where a match could start
+			next, ok := r.nextStart(pos)
+			if !ok {
+				return false, true, nil
+			}
+			if s == nil || next != pos {
+				pos = next
+				s = d.start(r.dfaContext(pos))
+			}
+		}
+		if d.full {
+			return false, false, nil
+		}
+		if pos >= r.runtextend {
+			return d.matchesAtEnd(s), true, nil
+		}
+		r.runtextpos = pos
+		if err := r.checkTimeout(); err != nil {
+			return false, false, err
+		}
+
+		ch := r.charAt(pos)
+		if s = d.step(s, ch, ch == '\n' && pos == r.runtextend-1); s == dfaMatched {
+			return true, true, nil
+		}
+		pos++
+	}
+}
+
+// dfaContext is the context before pos, for starting the DFA there
+func (r *runner) dfaContext(pos int) dfaContext {
+	var ctx dfaContext
+	if !r.code.NFA.Asserts {
+		return ctx
+	}
+	if pos == 0 {
+		ctx |= ctxBeginning
+	} else {
+		ctx |= afterContext(r.charAt(pos - 1))
+	}
+	if pos == r.runtextstart {
+		ctx |= ctxStart
+	}
+	return ctx
+}
+
+// pikeSearch runs the NFA from pos, returning the first match, or if anchored is true
+// only a match starting at pos.
+func (r *runner) pikeSearch(pos int, anchored, quick bool) (*Match, error) {
+	nfa := r.code.NFA
+	if r.pike == nil {
+		r.pike = &pikeVM{
+			clist: pikeList{sparse: make([]int, len(nfa.Insts))},
+			nlist: pikeList{sparse: make([]int, len(nfa.Insts))},
+		}
+	}
+	clist, nlist := &r.pike.clist, &r.pike.nlist
+	clist.threads = clist.threads[:0]
+	r.pike.captures = !quick
+
+	var (
+		found    bool
+		bestCaps *capHistory
+		bestEnd  int
+	)
+	start := pos
+	starts := make([]int, r.re.capsize)
+
+	for {
+		if !found && (!anchored || pos == start) {
+			if len(clist.threads) == 0 && !anchored {
+				var ok bool
+				if pos, ok = r.nextStart(pos); !ok {
+					break
+				}
+			}
+			r.addThread(clist, nfa.Start, pos, nil, starts)
+		}
+		if len(clist.threads) == 0 {
+			break
+		}
+		r.runtextpos = pos
+		if err := r.checkTimeout(); err != nil {
+			return nil, err
+		}
+
+		var ch rune
+		if pos < r.runtextend {
+			ch = r.charAt(pos)
+		}
+
+		nlist.threads = nlist.threads[:0]
+	step:
+		for i := range clist.threads {
+			t := &clist.threads[i]
+			inst := &nfa.Insts[t.pc]
+			switch inst.Op {
+			case syntax.NFAMatch:
+				// the threads after this one have lower priority
+				found, bestCaps, bestEnd = true, t.caps, pos
+				if quick {
+					// any match will do
+					nlist.threads = nlist.threads[:0]
+				}
+				break step
+			case syntax.NFAChar, syntax.NFANotChar, syntax.NFASet:
+				if pos < r.runtextend && nfaCharMatches(inst, ch) {
+					r.addThread(nlist, inst.Out, pos+1, t.caps, t.starts)
+				}
+			}
+		}
+
+		clist, nlist = nlist, clist
+		if pos >= r.runtextend {
+			break
+		}
+		pos++
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	r.runtextpos = bestEnd
+	r.initMatch()
+
+	var history []*capHistory
+	for h := bestCaps; h != nil; h = h.prev {
+		history = append(history, h)
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		h := history[i]
+		r.runmatch.addMatch(h.cap, h.start, h.end-h.start)
+	}
+	return r.tidyMatch(quick), nil
+}
+
+// addThread adds a thread at pc to the list, following the instructions that don't
+// consume anything, in priority order
+func (r *runner) addThread(l *pikeList, pc, pos int, caps *capHistory, starts []int) {
+	if l.contains(pc) {
+		return
+	}
+	l.sparse[pc] = len(l.threads)
+	l.threads = append(l.threads, pikeThread{pc: pc, caps: caps, starts: starts})
+
+	inst := &r.code.NFA.Insts[pc]
+	switch inst.Op {
+	case syntax.NFASplit:
+		r.addThread(l, inst.Out, pos, caps, starts)
+		r.addThread(l, inst.Out1, pos, caps, starts)
+	case syntax.NFACaptureStart, syntax.NFACaptureEnd:
+		if !r.pike.captures {
+			r.addThread(l, inst.Out, pos, caps, starts)
+			break
+		}
+		if inst.Op == syntax.NFACaptureEnd {
+			r.addThread(l, inst.Out, pos, &capHistory{inst.Cap, starts[inst.Cap], pos, caps}, starts)
+			break
+		}
+		s := make([]int, len(starts))
+		copy(s, starts)
+		s[inst.Cap] = pos
+		r.addThread(l, inst.Out, pos, caps, s)
+	case syntax.NFAAssert:
+		if r.nfaAssert(inst.Assertion, pos) {
+			r.addThread(l, inst.Out, pos, caps, starts)
+		}
+	}
+}
+
+// nfaAssert checks a zero width assertion at pos, in the same way as execute
+func (r *runner) nfaAssert(a syntax.NFAAssertion, pos int) bool {
+	switch a {
+	case syntax.AssertBol:
+		return pos == 0 || r.charAt(pos-1) == '\n'
+	case syntax.AssertEol:
+		return pos == r.runtextend || r.charAt(pos) == '\n'
+	case syntax.AssertBoundary:
+		return r.isBoundary(pos, 0, r.runtextend)
+	case syntax.AssertNonBoundary:
+		return !r.isBoundary(pos, 0, r.runtextend)
+	case syntax.AssertECMABoundary:
+		return r.isECMABoundary(pos, 0, r.runtextend)
+	case syntax.AssertNonECMABoundary:
+		return !r.isECMABoundary(pos, 0, r.runtextend)
+	case syntax.AssertBeginning:
+		return pos == 0
+	case syntax.AssertStart:
+		return pos == r.runtextstart
+	case syntax.AssertEndZ:
+		return pos == r.runtextend || (pos == r.runtextend-1 && r.charAt(pos) == '\n')
+	case syntax.AssertEnd:
+		return pos == r.runtextend
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package binexp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNFAChosen(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		linear  bool
+	}{
+		{`(a|b)*c`, true},
+		{`^\w+\b(?i:x)$`, true},
+		{`a{2,5}?b`, true},
+		{`(?<x>a)+`, true},
+		{`(a)\1`, false},
+		{`a(?=b)`, false},
+		{`(?<!a)b`, false},
+		{`(?>a+)b`, false},
+		{`(?(a)b|c)`, false},
+		{`(a?)*`, false},
+		{`(?<o-x>a)(?<x>b)`, false},
+		{`(?:abc){5000}`, false},
+	} {
+		re := MustCompile(test.pattern, 0)
+		if linear := re.code.NFA != nil; linear != test.linear {
+			t.Errorf("%q: expected linear %v, got %v", test.pattern, test.linear, linear)
+		}
+	}
+
+	if re := MustCompile(`a+b`, RightToLeft); re.code.NFA != nil {
+		t.Errorf("RightToLeft patterns shouldn't have an NFA")
+	}
+}
+
+// allCaptures describes every match of re in the input and every capture of every group
+func allCaptures(t *testing.T, re *Regexp, input string) string {
+	var sb strings.Builder
+	m, err := re.FindStringMatch(input)
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		for _, g := range m.Groups() {
+			fmt.Fprintf(&sb, "%v:", g.Name)
+			for _, c := range g.Captures {
+				fmt.Fprintf(&sb, "(%v,%v)", c.Index, c.Length)
+			}
+		}
+		sb.WriteString(" ")
+	}
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	return sb.String()
+}
+
+func TestNFAMatchesBacktracking(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		opt     RegexOptions
+		input   string
+	}{
+		{`(a|ab)(c|bcd)(d*)`, None, "abcd abcdd acd"},
+		{`((a)|b)+`, None, "abab bba"},
+		{`(?<word>\w+?)(?<num>\d{1,3})`, None, "abc12345 x9 99"},
+		{`^(\w+)\s*=\s*(.*?)$`, Multiline, "a = 1\nbb=two \n=3"},
+		{`\b(?i:the)\b`, None, "The theme of THE day"},
+		{`(a+)+?b|c`, None, "aab c b"},
+		{`x*`, None, "axxb"},
+		{`\Gab`, None, "ababxab"},
+		{`a\Z|b\z`, None, "a\nb"},
+		{`[^a]{2}(?:a|$)`, None, "bba bb"},
+		{`.{0,3}?x`, Singleline, "ab\nx"},
+	} {
+		re := MustCompile(test.pattern, test.opt)
+		if re.code.NFA == nil {
+			t.Fatalf("%q: expected an NFA", test.pattern)
+		}
+		backtracking := MustCompile(test.pattern, test.opt)
+		backtracking.code.NFA = nil
+
+		if got, want := allCaptures(t, re, test.input), allCaptures(t, backtracking, test.input); got != want {
+			t.Errorf("%q on %q:\nwanted %v\n   got %v", test.pattern, test.input, want, got)
+		}
+	}
+}
+
+func TestNFAQuickMatch(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		opt     RegexOptions
+		input   string
+		match   bool
+	}{
+		{`(a|b)*c`, None, "ababab", false},
+		{`(a|b)*c`, None, "xababcx", true},
+		{`\xe8.{4}\xc3`, ByteRunes, "\x90\xe8\x01\x02\x03\x04\xc3", true},
+		{`\xe8.{4}\xc3`, ByteRunes, "\x90\xe8\x01\x02\x03\xc3", false},
+		{`日本+`, None, "日本本", true},
+		{`(?i)straße`, None, "STRAßE", true},
+		{`^.bc(d|e)*$`, None, "abcddeedx", false},
+		{`^.bc(d|e)*$`, None, "abcddeed\n", true},
+		{`^b`, Multiline, "a\nb", true},
+		{`\bcat\b`, None, "concat cats", false},
+		{`\bcat\b`, None, "a cat.", true},
+	} {
+		re := MustCompile(test.pattern, test.opt)
+		var match bool
+		var err error
+		if test.opt&ByteRunes != 0 {
+			var m *Match
+			m, err = re.FindBytesMatchStartingAt([]byte(test.input), 0)
+			match = m != nil
+		} else {
+			match, err = re.MatchString(test.input)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if match != test.match {
+			t.Errorf("%q on %q: expected %v, got %v", test.pattern, test.input, test.match, match)
+		}
+	}
+}
+
+func TestNFACatastrophicPattern(t *testing.T) {
+	// these take exponential time to fail with backtracking
+	for _, pattern := range []string{`(.+)*\?`, `(a|aa)*b`, `(a+)+$x`} {
+		re := MustCompile(pattern, 0)
+		re.MatchTimeout = time.Second
+
+		input := strings.Repeat("a", 5000)
+		m, err := re.FindStringMatch(input)
+		if err != nil {
+			t.Fatalf("%q: unexpected err: %v", pattern, err)
+		}
+		if m != nil {
+			t.Fatalf("%q: expected no match", pattern)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package binexp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRequiredLiteral(t *testing.T) {
+	for _, test := range []struct {
+		pattern  string
+		opt      RegexOptions
+		literal  string
+		min, max int
+	}{
+		{`.{4}\xE8....\xC3`, ByteRunes, "\u00e8", 4, 4},
+		{`\d{2,3}-abc`, None, "-abc", 2, 3},
+		{`[a-z]+abc`, None, "", 0, 0},
+		{`(?:x|yy)(?:foo)+bar`, None, "foo", 1, 2},
+		{`\w\w?(?i:KEY)`, None, "key", 1, 2},
+		{`(\d)\1abc`, None, "", 0, 0},
+		{`\s*abc`, None, "", 0, 0},
+		{`\s{0,2}(?=a)a{3}`, None, "aaa", 0, 2},
+		{`abc\d+def`, None, "", 0, 0},
+	} {
+		re := MustCompile(test.pattern, test.opt)
+		lit := re.code.Literal
+		if test.literal == "" {
+			if lit != nil {
+				t.Errorf("%q: expected no literal, got %q at %v-%v", test.pattern, lit.BmPrefix.String(), lit.MinOffset, lit.MaxOffset)
+			}
+			continue
+		}
+		if lit == nil {
+			t.Errorf("%q: expected literal %q, got none", test.pattern, test.literal)
+			continue
+		}
+		if lit.BmPrefix.String() != test.literal || lit.MinOffset != test.min || lit.MaxOffset != test.max {
+			t.Errorf("%q: expected %q at %v-%v, got %q at %v-%v", test.pattern, test.literal, test.min, test.max,
+				lit.BmPrefix.String(), lit.MinOffset, lit.MaxOffset)
+		}
+	}
+}
+
+func TestRequiredLiteralMatches(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		opt     RegexOptions
+		input   string
+	}{
+		{`.{4}\xE8....\xC3`, ByteRunes | Singleline, "\x01\x02\x03\x04\x05\xe8\x01\x02\x03\x04\xc3\xe8\xe8\x00\x00\x00\x00\x00\xc3"},
+		{`\d{2,3}-abc`, None, "1-abc 12-abc 1234-abc -abc 99-ab"},
+		{`(?:x|yy)(?:foo)+bar`, None, "xfoobar yyfoofoobar xyfoobar foobar"},
+		{`\w\w?(?i:KEY)`, None, "aKEY abKey key k"},
+		{`\s{0,2}(?=a)a{3}`, None, "  aaa aaaa"},
+	} {
+		re := MustCompile(test.pattern, test.opt)
+		plain := MustCompile(test.pattern, test.opt)
+		plain.code.Literal = nil
+
+		find := func(re *Regexp) []*Match {
+			var ms []*Match
+			var err error
+			if test.opt&ByteRunes != 0 {
+				ms, err = re.FindAllBytesMatch([]byte(test.input), -1, true)
+			} else {
+				ms, err = re.FindAllStringMatch(test.input, -1, true)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected err: %v", err)
+			}
+			return ms
+		}
+
+		want, got := find(plain), find(re)
+		if len(want) == 0 {
+			t.Fatalf("%q on %q: no matches to compare", test.pattern, test.input)
+		}
+		if !reflect.DeepEqual(matchStrings(got), matchStrings(want)) {
+			t.Errorf("%q on %q: wanted %q, got %q", test.pattern, test.input, matchStrings(want), matchStrings(got))
+		}
+
+		if test.opt&ByteRunes == 0 {
+			continue
+		}
+		m, err := re.FindReaderAtMatchStartingAt(bytes.NewReader([]byte(test.input)), int64(len(test.input)), -1)
+		for i := 0; m != nil; i++ {
+			if i >= len(want) || m.Index != want[i].Index {
+				t.Fatalf("%q on a reader: unexpected match at %v", test.pattern, m.Index)
+			}
+			m, err = re.FindNextMatch(m)
+		}
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+	}
+}
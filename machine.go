@@ -0,0 +1,269 @@
+package binexp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Machine is the state of a search as the programs binexp-gen generates see it.  Its methods
+// are the primitive operations the instructions of a backtracking program are defined in terms
+// of, and most are small enough to be inlined, so a generated program works on the state of the
+// search directly rather than calling into the interpreter for every instruction.  A generated
+// program is only run for the program it was generated from, as identified by ProgramKey, which
+// includes the version of Machine, so when what its methods do changes, programs generated
+// earlier are interpreted instead.
+//
+// It isn't meant to be used by hand.
+type Machine runner
+
+// machineVersion is the version of Machine generated programs are written against, which
+// changes whenever what its methods do does
+const machineVersion = 2
+
+// Step counts a step against the MatchStepLimit and checks the other limits, returning the
+// error that ends the search once one is reached.  Generated programs take a step whenever
+// they backtrack or jump back, which is where the interpreter would take many.
+func (m *Machine) Step() error {
+	return (*runner)(m).checkTimeout()
+}
+
+// UnknownState is returned by a program that backtracks to an instruction it doesn't have
+func (m *Machine) UnknownState() error {
+	return errors.New("unknown state in regex runner")
+}
+
+// Pos returns the position in the text
+func (m *Machine) Pos() int {
+	return m.runtextpos
+}
+
+// SetPos moves to pos in the text
+func (m *Machine) SetPos(pos int) {
+	m.runtextpos = pos
+}
+
+// Start returns where the search started, which is where \G matches
+func (m *Machine) Start() int {
+	return m.runtextstart
+}
+
+// End returns the end of the text
+func (m *Machine) End() int {
+	return m.runtextend
+}
+
+// CharAt returns the character at i
+func (m *Machine) CharAt(i int) rune {
+	if m.bytemode {
+		return rune(m.runbytes[i])
+	}
+	if m.runpaged != nil {
+		return rune(m.runpaged.at(i))
+	}
+	return m.runtext[i]
+}
+
+// Next returns the character at the current position and moves past it
+func (m *Machine) Next() rune {
+	ch := m.CharAt(m.runtextpos)
+	m.runtextpos++
+	return ch
+}
+
+// Prev moves back a character and returns it, for right-to-left instructions
+func (m *Machine) Prev() rune {
+	m.runtextpos--
+	return m.CharAt(m.runtextpos)
+}
+
+// InSet returns true if ch is in the set with index set in the program
+func (m *Machine) InSet(set int, ch rune) bool {
+	return m.code.Sets[set].CharIn(ch)
+}
+
+// MatchString matches the string with index str in the program at the current position,
+// moving past it if it matches
+func (m *Machine) MatchString(str int, rtl, ci bool) bool {
+	m.rightToLeft, m.caseInsensitive = rtl, ci
+	return (*runner)(m).runematch(m.code.Strings[str])
+}
+
+// MatchRef matches the last capture of group capnum at the current position, moving past
+// it if it matches.  The group must have been matched.
+func (m *Machine) MatchRef(capnum int, rtl, ci bool) bool {
+	m.rightToLeft, m.caseInsensitive = rtl, ci
+	return (*runner)(m).refmatch(m.runmatch.matchIndex(capnum), m.runmatch.matchLength(capnum))
+}
+
+// IsBoundary returns true if pos is at a word boundary, as ECMAScript defines words if
+// ecma is true
+func (m *Machine) IsBoundary(pos int, ecma bool) bool {
+	if ecma {
+		return (*runner)(m).isECMABoundary(pos, 0, m.runtextend)
+	}
+	return (*runner)(m).isBoundary(pos, 0, m.runtextend)
+}
+
+// TrackPush pushes the code position codepos on the backtracking stack, negated for the
+// second way of backtracking into an instruction.  TrackPush1, TrackPush2 and TrackPush3
+// push one, two or three values first.
+func (m *Machine) TrackPush(codepos int) {
+	m.runtrackpos--
+	m.runtrack[m.runtrackpos] = codepos
+}
+
+func (m *Machine) TrackPush1(codepos, i1 int) {
+	m.runtrackpos -= 2
+	m.runtrack[m.runtrackpos+1] = i1
+	m.runtrack[m.runtrackpos] = codepos
+}
+
+func (m *Machine) TrackPush2(codepos, i1, i2 int) {
+	m.runtrackpos -= 3
+	m.runtrack[m.runtrackpos+2] = i1
+	m.runtrack[m.runtrackpos+1] = i2
+	m.runtrack[m.runtrackpos] = codepos
+}
+
+func (m *Machine) TrackPush3(codepos, i1, i2, i3 int) {
+	m.runtrackpos -= 4
+	m.runtrack[m.runtrackpos+3] = i1
+	m.runtrack[m.runtrackpos+2] = i2
+	m.runtrack[m.runtrackpos+1] = i3
+	m.runtrack[m.runtrackpos] = codepos
+}
+
+// TrackPop pops n values from the backtracking stack, leaving them to be read with TrackPeek
+func (m *Machine) TrackPop(n int) {
+	m.runtrackpos += n
+}
+
+// TrackPeek returns the ith value down the backtracking stack from its top, or since
+// TrackPop, the ith value popped
+func (m *Machine) TrackPeek(i int) int {
+	return m.runtrack[m.runtrackpos-i-1]
+}
+
+// Backtrack pops the code position to backtrack to
+func (m *Machine) Backtrack() int {
+	m.runtrackpos++
+	return m.runtrack[m.runtrackpos-1]
+}
+
+// Trackpos returns the height of the backtracking stack
+func (m *Machine) Trackpos() int {
+	return len(m.runtrack) - m.runtrackpos
+}
+
+// Trackto cuts the backtracking stack down to height pos
+func (m *Machine) Trackto(pos int) {
+	m.runtrackpos = len(m.runtrack) - pos
+}
+
+// EnsureStorage makes room on the stacks, as happens whenever the program jumps backwards
+func (m *Machine) EnsureStorage() {
+	(*runner)(m).ensureStorage()
+}
+
+// StackPush and StackPush2 push one or two values on the grouping stack
+func (m *Machine) StackPush(i1 int) {
+	m.runstackpos--
+	m.runstack[m.runstackpos] = i1
+}
+
+func (m *Machine) StackPush2(i1, i2 int) {
+	m.runstackpos -= 2
+	m.runstack[m.runstackpos+1] = i1
+	m.runstack[m.runstackpos] = i2
+}
+
+// StackPop pops n values from the grouping stack, leaving them to be read with StackPeek
+func (m *Machine) StackPop(n int) {
+	m.runstackpos += n
+}
+
+// StackPeek returns the ith value down the grouping stack from its top, or since StackPop,
+// the ith value popped
+func (m *Machine) StackPeek(i int) int {
+	return m.runstack[m.runstackpos-i-1]
+}
+
+// Crawlpos returns the number of captures made so far
+func (m *Machine) Crawlpos() int {
+	return len(m.runcrawl) - m.runcrawlpos
+}
+
+// Capture captures the text between start and end for group capnum, and for a balancing
+// group removes the last capture of uncapnum, which is otherwise -1
+func (m *Machine) Capture(capnum, uncapnum, start, end int) {
+	if uncapnum != -1 {
+		(*runner)(m).transferCapture(capnum, uncapnum, start, end)
+	} else {
+		(*runner)(m).capture(capnum, start, end)
+	}
+}
+
+// Uncapture reverts the last capture
+func (m *Machine) Uncapture() {
+	(*runner)(m).uncapture()
+}
+
+// IsMatched returns true if group capnum has been matched
+func (m *Machine) IsMatched(capnum int) bool {
+	return m.runmatch.isMatched(capnum)
+}
+
+var (
+	programsMu sync.RWMutex
+	programs   = map[string]func(*Machine) error{}
+)
+
+// RegisterProgram makes a generated program available to the patterns compiled with the
+// Compiled option whose backtracking program it implements, which are those with the same
+// ProgramKey.  It's called by the init functions of the code binexp-gen generates.
+func RegisterProgram(key string, program func(*Machine) error) {
+	programsMu.Lock()
+	programs[key] = program
+	programsMu.Unlock()
+}
+
+// ProgramKey identifies the backtracking program of re, along with the options that change
+// how it runs and the version of Machine it runs against.  It changes whenever the program
+// does, so a generated program is only used for the pattern it was generated from, and only
+// until a change to the package compiles the pattern differently or changes Machine, after
+// which the program is interpreted until it's generated again.
+func (re *Regexp) ProgramKey() (string, error) {
+	code, err := re.code.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%d:", machineVersion, re.options&^(Compiled|Debug))
+	h.Write(code)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// setCompiled looks up the key of re's program for its runners to find a generated program
+// with, if re was compiled with the Compiled option
+func (re *Regexp) setCompiled() error {
+	if re.options&Compiled == 0 {
+		re.programKey = ""
+		return nil
+	}
+	key, err := re.ProgramKey()
+	re.programKey = key
+	return err
+}
+
+// generatedProgram returns the generated program registered for re, or nil
+func (re *Regexp) generatedProgram() func(*Machine) error {
+	if re.programKey == "" {
+		return nil
+	}
+	programsMu.RLock()
+	defer programsMu.RUnlock()
+	return programs[re.programKey]
+}
@@ -0,0 +1,154 @@
+/*
+Package macho searches the sections of Mach-O executables, including every architecture
+of a universal (fat) binary, with a binexp Regexp.
+
+Matches come back annotated with the CPU of the slice they were found in, their segment and
+section, and their file offset and virtual address:
+
+	f, _ := os.Open("/usr/bin/true")
+	re := binexp.MustCompileHex("E8 ?? ?? ?? ??", 0)
+	macho.Scan(f, re, macho.ExecutableSections, func(m *macho.Match) bool {
+		fmt.Printf("%v %v,%v %#x: call\n", m.Cpu, m.Segment, m.Section, m.Addr)
+		return true
+	})
+*/
+package macho
+
+import (
+	gomacho "debug/macho"
+	"io"
+
+	"github.com/polyverse/binexp"
+)
+
+// section types (the low byte of the flags) that have no data in the file
+const (
+	sectionZerofill            = 0x1
+	sectionGBZerofill          = 0xc
+	sectionThreadLocalZerofill = 0x12
+)
+
+// section attributes marking code
+const (
+	sectionAttrPureInstructions = 0x80000000
+	sectionAttrSomeInstructions = 0x400
+)
+
+// Match is a match found in a Mach-O file.  The embedded Match's Index is relative to
+// the start of the section that was searched.
+type Match struct {
+	*binexp.Match
+
+	// Cpu is the architecture of the file, or of the slice of a fat file, the match was found in
+	Cpu gomacho.Cpu
+	// Segment and Section name the section the match was found in, e.g. __TEXT and __text
+	Segment string
+	Section string
+	// Offset is the position of the match in the file, counting from the start of a fat file
+	Offset int64
+	// Addr is the virtual address of the match
+	Addr uint64
+}
+
+// SectionFilter selects the sections to search
+type SectionFilter func(*gomacho.Section) bool
+
+// ExecutableSections selects the sections holding code
+func ExecutableSections(s *gomacho.Section) bool {
+	return s.Flags&(sectionAttrPureInstructions|sectionAttrSomeInstructions) != 0
+}
+
+// TextAndDataSections selects the sections of the __TEXT and __DATA segments
+func TextAndDataSections(s *gomacho.Section) bool {
+	return s.Seg == "__TEXT" || s.Seg == "__DATA"
+}
+
+// Scan searches r, which can be either a single architecture Mach-O file or a fat file.
+// Each architecture of a fat file is searched in turn, as with ScanFat.
+func Scan(r io.ReaderAt, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) error {
+	ff, err := gomacho.NewFatFile(r)
+	if err == gomacho.ErrNotFat {
+		f, err := gomacho.NewFile(r)
+		if err != nil {
+			return err
+		}
+		_, err = scanFile(f, 0, re, filter, fn)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return ScanFat(ff, re, filter, fn)
+}
+
+// ScanFat searches the selected sections of every architecture in a fat file, in the order
+// they're listed, calling fn with each match until it returns false.
+func ScanFat(ff *gomacho.FatFile, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) error {
+	for _, arch := range ff.Arches {
+		more, err := scanFile(arch.File, int64(arch.Offset), re, filter, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanFile searches every section of f selected by filter (all of them if filter is nil),
+// in the order they appear in the load commands, calling fn with each match until it returns
+// false.  Zero filled sections, such as __bss, are skipped.
+//
+// Sections are read from f a page at a time as they're searched, and the text of a match is
+// read back from f when it's asked for, so f must stay open while the matches are in use.
+func ScanFile(f *gomacho.File, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) error {
+	_, err := scanFile(f, 0, re, filter, fn)
+	return err
+}
+
+// FindAll returns all the matches Scan would find
+func FindAll(r io.ReaderAt, re *binexp.Regexp, filter SectionFilter) ([]*Match, error) {
+	var matches []*Match
+	err := Scan(r, re, filter, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return matches, err
+}
+
+// scanFile searches f, which starts at base in the file, returning false if fn asked to stop
+func scanFile(f *gomacho.File, base int64, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) (bool, error) {
+	for _, s := range f.Sections {
+		switch s.Flags & 0xff {
+		case sectionZerofill, sectionGBZerofill, sectionThreadLocalZerofill:
+			continue
+		}
+		if s.Size == 0 || (filter != nil && !filter(s)) {
+			continue
+		}
+
+		more, err := scan(re, s.ReaderAt, int64(s.Size), func(m *binexp.Match) bool {
+			return fn(&Match{
+				Match:   m,
+				Cpu:     f.Cpu,
+				Segment: s.Seg,
+				Section: s.Name,
+				Offset:  base + int64(s.Offset) + int64(m.Index),
+				Addr:    s.Addr + uint64(m.Index),
+			})
+		})
+		if err != nil || !more {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+// scan calls fn with each match in the first size bytes of r, returning false if fn asked to stop
+func scan(re *binexp.Regexp, r io.ReaderAt, size int64, fn func(*binexp.Match) bool) (bool, error) {
+	m, err := re.FindReaderAtMatchStartingAt(r, size, -1)
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		if !fn(m) {
+			return false, nil
+		}
+	}
+	return true, err
+}
@@ -0,0 +1,101 @@
+package macho
+
+import (
+	gomacho "debug/macho"
+	"os"
+	"testing"
+
+	"github.com/polyverse/binexp"
+)
+
+// the files in testdata are written by testdata/gen.go
+
+func openFile(t *testing.T, name string) *os.File {
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestScan_Thin(t *testing.T) {
+	re := binexp.MustCompileHex("EF BE AD DE", 0)
+
+	ms, err := FindAll(openFile(t, "hello"), re, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("Expected 2 matches, got %v", len(ms))
+	}
+	if m := ms[0]; m.Cpu != gomacho.CpuAmd64 || m.Segment != "__TEXT" || m.Section != "__text" || m.Index != 5 || m.Offset != 0x205 || m.Addr != 0x100000205 {
+		t.Fatalf("Unexpected __text match %+v", *m)
+	}
+	if m := ms[1]; m.Segment != "__DATA" || m.Section != "__data" || m.Index != 0 || m.Offset != 0x210 || m.Addr != 0x100001000 {
+		t.Fatalf("Unexpected __data match %+v", *m)
+	}
+}
+
+func TestScan_Fat(t *testing.T) {
+	re := binexp.MustCompileHex("EF BE AD DE", 0)
+
+	ms, err := FindAll(openFile(t, "hello_fat"), re, ExecutableSections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("Expected 2 matches, got %v", len(ms))
+	}
+	if m := ms[0]; m.Cpu != gomacho.CpuAmd64 || m.Section != "__text" || m.Offset != 0x200+0x205 || m.Addr != 0x100000205 {
+		t.Fatalf("Unexpected amd64 match %+v", *m)
+	}
+	if m := ms[1]; m.Cpu != gomacho.CpuArm64 || m.Section != "__text" || m.Offset != 0x600+0x204 || m.Addr != 0x100000204 {
+		t.Fatalf("Unexpected arm64 match %+v", *m)
+	}
+	if s := ms[1].String(); s != "\xef\xbe\xad\xde" {
+		t.Fatalf("Unexpected match text %q", s)
+	}
+}
+
+func TestScan_SkipsZerofill(t *testing.T) {
+	// __bss is 256 zero bytes in memory but isn't in the file at all
+	re := binexp.MustCompileHex("00 00 00 00", 0)
+
+	ms, err := FindAll(openFile(t, "hello_fat"), re, TextAndDataSections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ms {
+		if m.Section == "__bss" {
+			t.Fatalf("Unexpected match in __bss: %+v", *m)
+		}
+	}
+}
+
+func TestScanFile_Stop(t *testing.T) {
+	f, err := gomacho.NewFile(openFile(t, "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := binexp.MustCompile(`[\x00-\xff]`, binexp.ByteRunes)
+
+	count := 0
+	err = ScanFile(f, re, nil, func(m *Match) bool {
+		count++
+		return count < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("Expected the scan to stop after 3 matches, got %v", count)
+	}
+}
+
+func TestScan_NotMachO(t *testing.T) {
+	re := binexp.MustCompileHex("00", 0)
+	if _, err := FindAll(openFile(t, "gen.go"), re, nil); err == nil {
+		t.Fatalf("Expected an error scanning a file that isn't Mach-O")
+	}
+}
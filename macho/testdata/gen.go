@@ -0,0 +1,76 @@
+//go:build ignore
+
+// gen writes the Mach-O test fixtures: hello, a thin x86-64 file, and hello_fat, a
+// universal binary holding x86-64 and arm64 slices.  Each slice has a __TEXT,__text
+// section of code, a __DATA,__data section and a zero filled __DATA,__bss.
+//
+//	go run gen.go
+package main
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"os"
+)
+
+const (
+	textOff  = 0x200
+	dataOff  = 0x210
+	sliceLen = 0x220
+	textAddr = 0x100000000 + textOff
+	dataAddr = 0x100001000
+)
+
+func name(s string) (b [16]byte) {
+	copy(b[:], s)
+	return
+}
+
+func slice(cpu macho.Cpu, code []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := func(v interface{}) { binary.Write(buf, binary.LittleEndian, v) }
+
+	w(macho.FileHeader{Magic: macho.Magic64, Cpu: cpu, Type: macho.TypeExec, Ncmd: 2, Cmdsz: 72 + 80 + 72 + 2*80})
+	w(uint32(0)) // reserved
+
+	w(macho.Segment64{Cmd: macho.LoadCmdSegment64, Len: 72 + 80, Name: name("__TEXT"), Addr: 0x100000000, Memsz: 0x1000,
+		Offset: 0, Filesz: dataOff, Maxprot: 5, Prot: 5, Nsect: 1})
+	w(macho.Section64{Name: name("__text"), Seg: name("__TEXT"), Addr: textAddr, Size: uint64(len(code)), Offset: textOff,
+		Flags: 0x80000400})
+
+	w(macho.Segment64{Cmd: macho.LoadCmdSegment64, Len: 72 + 2*80, Name: name("__DATA"), Addr: dataAddr, Memsz: 0x1000,
+		Offset: dataOff, Filesz: sliceLen - dataOff, Maxprot: 3, Prot: 3, Nsect: 2})
+	w(macho.Section64{Name: name("__data"), Seg: name("__DATA"), Addr: dataAddr, Size: 10, Offset: dataOff})
+	w(macho.Section64{Name: name("__bss"), Seg: name("__DATA"), Addr: dataAddr + 0x10, Size: 0x100, Flags: 0x1})
+
+	buf.Write(make([]byte, textOff-buf.Len()))
+	buf.Write(code)
+	buf.Write(make([]byte, dataOff-buf.Len()))
+	buf.Write([]byte("\xef\xbe\xad\xdebinexp"))
+	buf.Write(make([]byte, sliceLen-buf.Len()))
+	return buf.Bytes()
+}
+
+func main() {
+	// push rbp; mov rbp, rsp; mov eax, 0xdeadbeef; pop rbp; ret
+	amd64 := slice(macho.CpuAmd64, []byte{0x55, 0x48, 0x89, 0xe5, 0xb8, 0xef, 0xbe, 0xad, 0xde, 0x5d, 0xc3})
+	// movz x0, #0; .word 0xdeadbeef; ret
+	arm64 := slice(macho.CpuArm64, []byte{0x00, 0x00, 0x80, 0xd2, 0xef, 0xbe, 0xad, 0xde, 0xc0, 0x03, 0x5f, 0xd6})
+
+	if err := os.WriteFile("hello", amd64, 0644); err != nil {
+		panic(err)
+	}
+
+	fat := &bytes.Buffer{}
+	binary.Write(fat, binary.BigEndian, []uint32{macho.MagicFat, 2})
+	binary.Write(fat, binary.BigEndian, macho.FatArchHeader{Cpu: macho.CpuAmd64, SubCpu: 3, Offset: 0x200, Size: sliceLen, Align: 9})
+	binary.Write(fat, binary.BigEndian, macho.FatArchHeader{Cpu: macho.CpuArm64, Offset: 0x600, Size: sliceLen, Align: 9})
+	fat.Write(make([]byte, 0x200-fat.Len()))
+	fat.Write(amd64)
+	fat.Write(make([]byte, 0x600-fat.Len()))
+	fat.Write(arm64)
+	if err := os.WriteFile("hello_fat", fat.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+}
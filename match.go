@@ -29,6 +29,20 @@ type Match struct {
 	// whether we've done any balancing with this match.  If we
 	// have done balancing, we'll need to do extra work in Tidy().
 	balancing bool
+
+	stats MatchStats
+}
+
+// MatchStats describes the work done by the search that found a Match
+type MatchStats struct {
+	// the most memory the stacks of the backtracking program held during the search, in
+	// bytes, as of the last time round a loop.  It's 0 for patterns matched in linear time.
+	PeakStackBytes int
+}
+
+// Stats returns statistics about the search that found the match
+func (m *Match) Stats() MatchStats {
+	return m.stats
 }
 
 // Group is an explicit or implit (group 0) matched group within the pattern
@@ -43,6 +57,16 @@ type Group struct {
 type Capture struct {
 	// the original string
 	text []rune
+	// the original byte slice, when matching bytes directly
+	bytes []byte
+	// the original input, when it's read through a page cache
+	paged *pagedText
+	// the byte slice text was converted from, one rune per byte, when bytes
+	// are searched without ByteRunes
+	src []byte
+	// the absolute position of the first character of text (or bytes),
+	// non-zero when only a window of a larger input was searched
+	offset int
 	// the position in the original string where the first character of
 	// captured substring was found.
 	Index int
@@ -50,17 +74,94 @@ type Capture struct {
 	Length int
 }
 
-// String returns the captured text as a String
+// String returns the captured text as a String, in the form the input was given in: for a
+// match against a string (or runes) it's that text, and for a match against bytes searched
+// in place with ByteRunes, or against an io.ReaderAt, it's the bytes unchanged.  Bytes
+// searched without ByteRunes are converted to one rune per byte first, so the bytes past 0x7F
+// come back UTF-8 encoded; Bytes returns them as they were.  For a match against an
+// io.ReaderAt, the text is read back from it, and if that fails only what was read is
+// returned; ReadBytes returns the error.
 func (c *Capture) String() string {
-	return string(c.text[c.Index : c.Index+c.Length])
+	start := c.Index - c.offset
+	if c.bytes != nil {
+		return string(c.bytes[start : start+c.Length])
+	}
+	if c.paged != nil {
+		b, _ := c.paged.readAt(start, start+c.Length)
+		return string(b)
+	}
+	return string(c.text[start : start+c.Length])
 }
 
-// Runes returns the captured text as a rune slice
+// Runes returns the captured text as a rune slice, which like String is cut short if it
+// can't all be read back from an io.ReaderAt
 func (c *Capture) Runes() []rune {
-	return c.text[c.Index : c.Index+c.Length]
+	start := c.Index - c.offset
+	if c.bytes != nil {
+		runes := make([]rune, c.Length)
+		for i, b := range c.bytes[start : start+c.Length] {
+			runes[i] = rune(b)
+		}
+		return runes
+	}
+	if c.paged != nil {
+		b, _ := c.paged.readAt(start, start+c.Length)
+		runes := make([]rune, len(b))
+		for i := range b {
+			runes[i] = rune(b[i])
+		}
+		return runes
+	}
+	return c.text[start : start+c.Length]
+}
+
+// Bytes returns the captured text as a byte slice.  For a match against a byte slice, whether
+// or not it was searched with ByteRunes, it's the captured bytes, sharing memory with the
+// original input.  For a match against an io.ReaderAt the bytes are read back from it (only
+// those before an error, which ReadBytes returns).  For a match against a string (or runes)
+// it's the UTF-8 encoding of the captured text, as with String; ReadBytes gives one byte for
+// each rune instead.
+func (c *Capture) Bytes() []byte {
+	start := c.Index - c.offset
+	if c.bytes != nil {
+		return c.bytes[start : start+c.Length]
+	}
+	if c.src != nil {
+		return c.src[start : start+c.Length]
+	}
+	if c.paged != nil {
+		b, _ := c.paged.readAt(start, start+c.Length)
+		return b
+	}
+	return []byte(string(c.text[start : start+c.Length]))
 }
 
-func newMatch(regex *Regexp, capcount int, text []rune, startpos int) *Match {
+// runeBytes converts runes to bytes one for one, cutting those past 0xFF to their low byte,
+// and returns the index of the first of them, or -1 if there are none
+func runeBytes(runes []rune) ([]byte, int) {
+	b := make([]byte, len(runes))
+	wide := -1
+	for i, r := range runes {
+		if r > 0xff && wide < 0 {
+			wide = i
+		}
+		b[i] = byte(r)
+	}
+	return b, wide
+}
+
+// textLen returns the length of the original input in runes (or bytes)
+func (c *Capture) textLen() int {
+	if c.bytes != nil {
+		return len(c.bytes)
+	}
+	if c.paged != nil {
+		return c.paged.size
+	}
+	return len(c.text)
+}
+
+func newMatch(regex *Regexp, capcount int, text []rune, bytes []byte, startpos int) *Match {
 	m := Match{
 		regex:      regex,
 		matchcount: make([]int, capcount),
@@ -70,18 +171,20 @@ func newMatch(regex *Regexp, capcount int, text []rune, startpos int) *Match {
 	}
 	m.Name = "0"
 	m.text = text
+	m.bytes = bytes
 	m.matches[0] = make([]int, 2)
 	return &m
 }
 
-func newMatchSparse(regex *Regexp, caps map[int]int, capcount int, text []rune, startpos int) *Match {
-	m := newMatch(regex, capcount, text, startpos)
+func newMatchSparse(regex *Regexp, caps map[int]int, capcount int, text []rune, bytes []byte, startpos int) *Match {
+	m := newMatch(regex, capcount, text, bytes, startpos)
 	m.sparseCaps = caps
 	return m
 }
 
-func (m *Match) reset(text []rune, textstart int) {
+func (m *Match) reset(text []rune, bytes []byte, textstart int) {
 	m.text = text
+	m.bytes = bytes
 	m.textstart = textstart
 	for i := 0; i < len(m.matchcount); i++ {
 		m.matchcount[i] = 0
@@ -89,6 +192,14 @@ func (m *Match) reset(text []rune, textstart int) {
 	m.balancing = false
 }
 
+// rebase makes the indexes of this match absolute, given that
+// the searched text started at offset in some larger input
+func (m *Match) rebase(offset int) {
+	m.offset = offset
+	m.Index += offset
+	m.Group.Captures[0] = m.Group.Capture
+}
+
 func (m *Match) tidy(textpos int) {
 
 	interval := m.matches[0]
@@ -269,7 +380,7 @@ func (m *Match) populateOtherGroups() {
 	if m.otherGroups == nil {
 		m.otherGroups = make([]Group, len(m.matchcount)-1)
 		for i := 0; i < len(m.otherGroups); i++ {
-			m.otherGroups[i] = newGroup(m.regex.GroupNameFromNumber(i+1), m.text, m.matches[i+1], m.matchcount[i+1])
+			m.otherGroups[i] = newGroup(m.regex.GroupNameFromNumber(i+1), m.text, m.bytes, m.src, m.paged, m.offset, m.matches[i+1], m.matchcount[i+1])
 		}
 	}
 }
@@ -285,16 +396,31 @@ func (m *Match) groupValueAppendToBuf(groupnum int, buf *bytes.Buffer) {
 	index := matches[(c-1)*2]
 	last := index + matches[(c*2)-1]
 
+	if m.bytes != nil {
+		buf.Write(m.bytes[index:last])
+		return
+	}
+	if m.paged != nil {
+		// only strings have replacements made in them, so there's no error to report
+		b, _ := m.paged.readAt(index, last)
+		buf.Write(b)
+		return
+	}
+
 	for ; index < last; index++ {
 		buf.WriteRune(m.text[index])
 	}
 }
 
-func newGroup(name string, text []rune, caps []int, capcount int) Group {
+func newGroup(name string, text []rune, bytes, src []byte, paged *pagedText, offset int, caps []int, capcount int) Group {
 	g := Group{}
 	g.text = text
+	g.bytes = bytes
+	g.src = src
+	g.paged = paged
+	g.offset = offset
 	if capcount > 0 {
-		g.Index = caps[(capcount-1)*2]
+		g.Index = caps[(capcount-1)*2] + offset
 		g.Length = caps[(capcount*2)-1]
 	}
 	g.Name = name
@@ -302,7 +428,11 @@ func newGroup(name string, text []rune, caps []int, capcount int) Group {
 	for i := 0; i < capcount; i++ {
 		g.Captures[i] = Capture{
 			text:   text,
-			Index:  caps[i*2],
+			bytes:  bytes,
+			src:    src,
+			paged:  paged,
+			offset: offset,
+			Index:  caps[i*2] + offset,
 			Length: caps[i*2+1],
 		}
 	}
@@ -0,0 +1,216 @@
+package binexp
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+// the number of bytes read at a time from an io.ReaderAt, and the number of pages kept in
+// memory at once, unless the Regexp says otherwise
+const (
+	defaultPageSize  = 64 * 1024
+	defaultPageCount = 64
+)
+
+// pagedText gives random access to the contents of an io.ReaderAt.  It reads
+// a page at a time and keeps the most recently used pages in memory, so the
+// runner can backtrack or look behind without the whole input being loaded.
+type pagedText struct {
+	r        io.ReaderAt
+	size     int
+	pageSize int
+
+	pages []textPage
+	tick  int
+
+	// the page used last, to make sequential access cheap
+	last      []byte
+	lastStart int
+
+	// first error returned by r, reported once the scan finishes
+	err error
+}
+
+type textPage struct {
+	start int
+	data  []byte
+	used  int
+}
+
+func newPagedText(r io.ReaderAt, size, pageSize, pageCount int) *pagedText {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageCount <= 0 {
+		pageCount = defaultPageCount
+	}
+	return &pagedText{
+		r:         r,
+		size:      size,
+		pageSize:  pageSize,
+		pages:     make([]textPage, 0, pageCount),
+		lastStart: -1,
+	}
+}
+
+// at returns the byte at position i, which must be within the size of the text
+func (p *pagedText) at(i int) byte {
+	if i >= p.lastStart && i-p.lastStart < len(p.last) {
+		return p.last[i-p.lastStart]
+	}
+	p.load(i)
+	if i-p.lastStart >= len(p.last) {
+		// a read error left us short, we return zeros and report it later
+		return 0
+	}
+	return p.last[i-p.lastStart]
+}
+
+// load makes the page holding position i the current page, reading it if needed
+func (p *pagedText) load(i int) {
+	start := i - i%p.pageSize
+	p.tick++
+
+	for j := range p.pages {
+		if p.pages[j].start == start {
+			p.pages[j].used = p.tick
+			p.last, p.lastStart = p.pages[j].data, start
+			return
+		}
+	}
+
+	// not cached, evict the least recently used page if we're full
+	var pg *textPage
+	if len(p.pages) < cap(p.pages) {
+		p.pages = append(p.pages, textPage{})
+		pg = &p.pages[len(p.pages)-1]
+	} else {
+		pg = &p.pages[0]
+		for j := range p.pages {
+			if p.pages[j].used < pg.used {
+				pg = &p.pages[j]
+			}
+		}
+	}
+
+	l := p.pageSize
+	if start+l > p.size {
+		l = p.size - start
+	}
+	if cap(pg.data) < l {
+		pg.data = make([]byte, l)
+	}
+	pg.data = pg.data[:l]
+
+	n, err := p.r.ReadAt(pg.data, int64(start))
+	if n < l {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if p.err == nil {
+			p.err = err
+		}
+		pg.data = pg.data[:n]
+	}
+
+	pg.start = start
+	pg.used = p.tick
+	p.last, p.lastStart = pg.data, start
+}
+
+// window returns the text from pos up to (but not including) end.  The result
+// refers into the page cache when it falls within a single page, and is
+// only valid until the next call.
+func (p *pagedText) window(pos, end int) []byte {
+	if pos >= end {
+		return nil
+	}
+	if pos < p.lastStart || pos-p.lastStart >= len(p.last) {
+		p.load(pos)
+	}
+	if end-p.lastStart <= len(p.last) {
+		return p.last[pos-p.lastStart : end-p.lastStart]
+	}
+
+	buf := make([]byte, end-pos)
+	for i := range buf {
+		buf[i] = p.at(pos + i)
+	}
+	return buf
+}
+
+// readAt copies the text between start and end out of the underlying reader.  If it can't
+// all be read, it returns what was read before the error.
+func (p *pagedText) readAt(start, end int) ([]byte, error) {
+	buf := make([]byte, end-start)
+	n, err := p.r.ReadAt(buf, int64(start))
+	if n < len(buf) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf[:n], err
+	}
+	return buf, nil
+}
+
+// scanPrefix runs the Boyer-Moore prefix scan over the paged text a page at a time,
+// overlapping pages by the length of the prefix so no occurrence is missed.
+func (p *pagedText) scanPrefix(bm *syntax.BmPrefix, index, beglimit, endlimit int, rightToLeft bool) int {
+	overlap := bm.Len() - 1
+
+	if !rightToLeft {
+		for pos := index; endlimit-pos > overlap; {
+			end := pos + p.pageSize + overlap
+			if end > endlimit {
+				end = endlimit
+			}
+			w := p.window(pos, end)
+			if i := bm.ScanBytes(w, 0, 0, len(w)); i >= 0 {
+				return pos + i
+			}
+			pos = end - overlap
+		}
+		return -1
+	}
+
+	for pos := index; pos-beglimit > overlap; {
+		start := pos - p.pageSize - overlap
+		if start < beglimit {
+			start = beglimit
+		}
+		w := p.window(start, pos)
+		if i := bm.ScanBytes(w, len(w), 0, len(w)); i >= 0 {
+			return start + i
+		}
+		pos = start + overlap
+	}
+	return -1
+}
+
+// FindReaderAtMatchStartingAt searches the first size bytes of r for a Regexp match starting at
+// the startAt index (use -1 to start at the beginning, or the end for RightToLeft).  Each byte
+// is matched as a single rune.
+//
+// The input is read on demand a page (ReaderAtPageSize bytes) at a time and at most ReaderAtPageCount
+// pages are kept in memory, so very large files can be searched without loading them.  The cache
+// is kept with the returned Match, and calling FindNextMatch with it carries on through the same input.
+// Capture text (String, Bytes, etc.) is read back from r when asked for, and ReadBytes reports
+// any error doing so.
+//
+// A file that is already memory mapped is a []byte and is best searched with FindBytesMatchStartingAt.
+func (re *Regexp) FindReaderAtMatchStartingAt(r io.ReaderAt, size int64, startAt int) (*Match, error) {
+	return re.findReaderAtMatch(context.Background(), r, size, startAt)
+}
+
+func (re *Regexp) findReaderAtMatch(ctx context.Context, r io.ReaderAt, size int64, startAt int) (*Match, error) {
+	if size < 0 || int64(int(size)) != size {
+		return nil, errors.New("size of the input is out of range")
+	}
+	if startAt > int(size) {
+		return nil, errors.New("startAt must be less than the size of the input")
+	}
+	return re.runPaged(ctx, false, startAt, newPagedText(r, int(size), re.ReaderAtPageSize, re.ReaderAtPageCount))
+}
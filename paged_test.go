@@ -0,0 +1,142 @@
+package binexp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func pagedMatches(t *testing.T, re *Regexp, data []byte, pageSize, pageCount int) []*Match {
+	var ms []*Match
+	m, err := re.runPaged(context.Background(), false, -1, newPagedText(bytes.NewReader(data), len(data), pageSize, pageCount))
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		ms = append(ms, m)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	return ms
+}
+
+func TestFindReaderAtMatch_SameAsBytes(t *testing.T) {
+	data := bytes.Repeat([]byte{0x90, 0xe8, 0x01, 0x02, 0x03, 0x04, 0xc3, 0xca, 0x10, 0x00}, 30)
+
+	for _, opt := range []RegexOptions{ByteRunes, ByteRunes | RightToLeft} {
+		for _, pattern := range []string{
+			"\xe8(?<rel>[\x00-\xff]{4})\xc3",
+			"\xc3\xca",
+			"(?<=\x04)\xc3",
+			"\xca[\x00-\xff]\\x00|\x90\xe8",
+			"\\A\x90\xe8",
+		} {
+			re := MustCompile(pattern, opt)
+
+			var want []*Match
+			for m, _ := re.FindBytesMatchStartingAt(data, -1); m != nil; m, _ = re.FindNextMatch(m) {
+				want = append(want, m)
+			}
+
+			// small pages and a tiny cache so matches cross page boundaries and pages get evicted
+			for _, pageSize := range []int{1, 3, 8, 1024} {
+				got := pagedMatches(t, re, data, pageSize, 2)
+				if len(got) != len(want) {
+					t.Fatalf("%q opt %v page %v: wanted %v matches, got %v", pattern, opt, pageSize, len(want), len(got))
+				}
+				for i := range want {
+					if want[i].Index != got[i].Index || !bytes.Equal(want[i].Bytes(), got[i].Bytes()) {
+						t.Fatalf("%q opt %v page %v: match %v wanted %x at %v, got %x at %v", pattern, opt, pageSize, i,
+							want[i].Bytes(), want[i].Index, got[i].Bytes(), got[i].Index)
+					}
+					if g := got[i].GroupByName("rel"); g != nil && !bytes.Equal(g.Bytes(), []byte{1, 2, 3, 4}) {
+						t.Fatalf("%q page %v: wanted capture 01020304, got %x", pattern, pageSize, g.Bytes())
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFindReaderAtMatchStartingAt(t *testing.T) {
+	re := MustCompile("ab(c+)", ByteRunes)
+	data := []byte("xxabcxxabcccx")
+
+	m, err := re.FindReaderAtMatchStartingAt(bytes.NewReader(data), int64(len(data)), 3)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if m == nil || m.Index != 7 || m.String() != "abccc" || m.GroupByNumber(1).String() != "ccc" {
+		t.Fatalf("Expected abccc at 7, got %v", m)
+	}
+	if _, err := re.FindReaderAtMatchStartingAt(bytes.NewReader(data), int64(len(data)), len(data)+1); err == nil {
+		t.Fatalf("Expected an error for startAt past the end")
+	}
+
+	// the pages can be as small as a byte
+	re.ReaderAtPageSize, re.ReaderAtPageCount = 1, 1
+	m, err = re.FindReaderAtMatchStartingAt(bytes.NewReader(data), int64(len(data)), 3)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if m == nil || m.Index != 7 || m.String() != "abccc" {
+		t.Fatalf("Expected abccc at 7 with one-byte pages, got %v", m)
+	}
+}
+
+type errReaderAt struct{}
+
+func (errReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, errors.New("bad read")
+}
+
+func TestFindReaderAtMatch_ReadError(t *testing.T) {
+	re := MustCompile("a", ByteRunes)
+	if _, err := re.FindReaderAtMatchStartingAt(errReaderAt{}, 10, -1); err == nil || err.Error() != "bad read" {
+		t.Fatalf("Expected the read error, got %v", err)
+	}
+
+	// a reader shorter than its stated size is an error too
+	if _, err := re.FindReaderAtMatchStartingAt(bytes.NewReader([]byte("xx")), 10, -1); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+// shrinkingReaderAt is a bytes.Reader that can be cut short after it's been searched
+type shrinkingReaderAt struct {
+	*bytes.Reader
+	size int64
+}
+
+func (r *shrinkingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > r.size {
+		n, _ := r.Reader.ReadAt(p[:max(r.size-off, 0)], off)
+		return n, io.EOF
+	}
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestFindReaderAtMatch_CaptureReadError(t *testing.T) {
+	re := MustCompile("abc(c)", ByteRunes)
+	data := []byte("xxabccx")
+	r := &shrinkingReaderAt{bytes.NewReader(data), int64(len(data))}
+
+	m, err := re.FindReaderAtMatchStartingAt(r, int64(len(data)), -1)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a match, got %v, %v", m, err)
+	}
+	if b, err := m.ReadBytes(); err != nil || string(b) != "abcc" {
+		t.Fatalf("Expected abcc, got %q, %v", b, err)
+	}
+
+	r.size = 5
+	if b, err := m.ReadBytes(); err != io.ErrUnexpectedEOF || string(b) != "abc" {
+		t.Fatalf("Expected abc and io.ErrUnexpectedEOF, got %q, %v", b, err)
+	}
+	if _, err := m.GroupByNumber(1).Uint8(); err == nil {
+		t.Fatalf("Expected decoding a capture that can't be read to fail")
+	}
+	if s := m.String(); s != "abc" {
+		t.Fatalf("Expected String to return what could be read, got %q", s)
+	}
+}
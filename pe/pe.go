@@ -0,0 +1,116 @@
+/*
+Package pe searches the sections of Windows PE executables with a binexp Regexp.
+
+Matches come back annotated with the section they were found in and its file offset,
+relative virtual address (RVA) and virtual address (VA):
+
+	f, _ := gope.Open("app.exe")
+	re := binexp.MustCompileHex("E8 ?? ?? ?? ??", 0)
+	pe.ScanSections(f, re, pe.ExecutableSections, func(m *pe.Match) bool {
+		fmt.Printf("%v %#x: call\n", m.Section, m.VA)
+		return true
+	})
+*/
+package pe
+
+import (
+	gope "debug/pe"
+	"io"
+
+	"github.com/polyverse/binexp"
+)
+
+// Match is a match found in a PE file.  The embedded Match's Index is relative to
+// the start of the section that was searched.
+type Match struct {
+	*binexp.Match
+
+	// Section is the name of the section the match was found in
+	Section string
+	// Offset is the position of the match in the file
+	Offset int64
+	// RVA is the address of the match relative to the image base
+	RVA uint32
+	// VA is the virtual address of the match once the image is loaded at its preferred base
+	VA uint64
+}
+
+// SectionFilter selects the sections to search
+type SectionFilter func(*gope.Section) bool
+
+// ExecutableSections selects the sections that are mapped executable
+func ExecutableSections(s *gope.Section) bool {
+	return s.Characteristics&gope.IMAGE_SCN_MEM_EXECUTE != 0
+}
+
+// ImageBase returns the preferred load address of the image, or 0 if f has no optional header
+func ImageBase(f *gope.File) uint64 {
+	switch oh := f.OptionalHeader.(type) {
+	case *gope.OptionalHeader32:
+		return uint64(oh.ImageBase)
+	case *gope.OptionalHeader64:
+		return oh.ImageBase
+	}
+	return 0
+}
+
+// ScanSections searches every section of f selected by filter (all of them if filter is nil),
+// in the order they appear in the section table, calling fn with each match until it returns false.
+// Only the part of each section stored in the file, and no more than its virtual size, is searched.
+//
+// Sections are read from f a page at a time as they're searched, and the text of a match is
+// read back from f when it's asked for, so f must stay open while the matches are in use.
+func ScanSections(f *gope.File, re *binexp.Regexp, filter SectionFilter, fn func(*Match) bool) error {
+	base := ImageBase(f)
+
+	for _, s := range f.Sections {
+		if filter != nil && !filter(s) {
+			continue
+		}
+
+		// the raw data is padded out to the file alignment, past the end of the section
+		size := s.Size
+		if s.VirtualSize != 0 && s.VirtualSize < size {
+			size = s.VirtualSize
+		}
+		if size == 0 {
+			continue
+		}
+
+		more, err := scan(re, s.ReaderAt, int64(size), func(m *binexp.Match) bool {
+			rva := s.VirtualAddress + uint32(m.Index)
+			return fn(&Match{
+				Match:   m,
+				Section: s.Name,
+				Offset:  int64(s.Offset) + int64(m.Index),
+				RVA:     rva,
+				VA:      base + uint64(rva),
+			})
+		})
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindAllSections returns all the matches ScanSections would find
+func FindAllSections(f *gope.File, re *binexp.Regexp, filter SectionFilter) ([]*Match, error) {
+	var matches []*Match
+	err := ScanSections(f, re, filter, func(m *Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return matches, err
+}
+
+// scan calls fn with each match in the first size bytes of r, returning false if fn asked to stop
+func scan(re *binexp.Regexp, r io.ReaderAt, size int64, fn func(*binexp.Match) bool) (bool, error) {
+	m, err := re.FindReaderAtMatchStartingAt(r, size, -1)
+	for ; m != nil; m, err = re.FindNextMatch(m) {
+		if !fn(m) {
+			return false, nil
+		}
+	}
+	return true, err
+}
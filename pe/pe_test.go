@@ -0,0 +1,98 @@
+package pe
+
+import (
+	gope "debug/pe"
+	"testing"
+
+	"github.com/polyverse/binexp"
+)
+
+// testdata/hello64.exe and hello32.exe are built from the .s files next to them with
+//
+//	as --64 -o hello64.o hello64.s
+//	ld -m i386pep -s --file-alignment 0x200 --section-alignment 0x1000 --image-base 0x140000000 -e _start -o hello64.exe hello64.o
+//	as --32 -o hello32.o hello32.s
+//	ld -m i386pe -s --file-alignment 0x200 --section-alignment 0x1000 --image-base 0x400000 -e _start -o hello32.exe hello32.o
+func open(t *testing.T, name string) *gope.File {
+	f, err := gope.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestScanSections(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		base       uint64
+		textOffset int64
+	}{
+		{"hello64.exe", 0x140000000, 0x405},
+		{"hello32.exe", 0x400000, 0x404},
+	} {
+		f := open(t, test.name)
+		if base := ImageBase(f); base != test.base {
+			t.Fatalf("%v: expected image base %#x, got %#x", test.name, test.base, base)
+		}
+
+		re := binexp.MustCompileHex("EF BE AD DE", 0)
+		ms, err := FindAllSections(f, re, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ms) != 2 {
+			t.Fatalf("%v: expected 2 matches, got %v", test.name, len(ms))
+		}
+
+		text := ms[0]
+		if text.Section != ".text" || text.Offset != test.textOffset || text.RVA != 0x1000+uint32(text.Index) || text.VA != test.base+uint64(text.RVA) {
+			t.Fatalf("%v: unexpected .text match %+v", test.name, *text)
+		}
+		data := ms[1]
+		if data.Section != ".data" || data.Offset != 0x600 || data.Index != 0 || data.RVA != 0x2000 || data.VA != test.base+0x2000 {
+			t.Fatalf("%v: unexpected .data match %+v", test.name, *data)
+		}
+
+		ms, err = FindAllSections(f, re, ExecutableSections)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ms) != 1 || ms[0].Section != ".text" {
+			t.Fatalf("%v: expected only the .text match, got %v", test.name, ms)
+		}
+	}
+}
+
+func TestScanSections_Padding(t *testing.T) {
+	f := open(t, "hello64.exe")
+
+	// the sections are padded with zeros in the file, which isn't part of them
+	re := binexp.MustCompileHex("00 00 00 00", 0)
+	ms, err := FindAllSections(f, re, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ms {
+		if m.Section == ".data" {
+			t.Fatalf("Unexpected match in the padding of .data at %v", m.Index)
+		}
+	}
+}
+
+func TestScanSections_Stop(t *testing.T) {
+	f := open(t, "hello32.exe")
+	re := binexp.MustCompile(`[\x00-\xff]`, binexp.ByteRunes)
+
+	count := 0
+	err := ScanSections(f, re, nil, func(m *Match) bool {
+		count++
+		return count < 5
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Fatalf("Expected the scan to stop after 5 matches, got %v", count)
+	}
+}
@@ -0,0 +1,12 @@
+	.text
+	.globl _start
+_start:
+	push %ebp
+	mov %esp, %ebp
+	mov $0xdeadbeef, %eax
+	pop %ebp
+	ret
+	.data
+magic:
+	.long 0xdeadbeef
+	.ascii "binexp"
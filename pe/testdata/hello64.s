@@ -0,0 +1,12 @@
+	.text
+	.globl _start
+_start:
+	push %rbp
+	mov %rsp, %rbp
+	mov $0xdeadbeef, %eax
+	pop %rbp
+	ret
+	.data
+magic:
+	.long 0xdeadbeef
+	.ascii "binexp"
@@ -0,0 +1,2129 @@
+// Code generated by binexp-gen. DO NOT EDIT.
+
+package binexp_test
+
+import (
+	"unicode"
+
+	"github.com/polyverse/binexp"
+)
+
+func init() {
+	binexp.RegisterProgram("8984f845ff0ce4622e36ce9162156955cbacc2377e519018109bd3763e586e8e", program0)
+	binexp.RegisterProgram("e35575a6ee42dfdb299f8e8b20a31decdf50cef71042c247c31c660c2a154df8", program1)
+	binexp.RegisterProgram("4c58d849980de3163d67a9fe0ae8a77d9fb8f3974beccde33eea19ef0ed620fd", program2)
+}
+
+// program0 is the program for `(?i:(?<q>['"])[a-z]+?\k<q>|hello world|x[^y]y)|(?:ab){0,4}?c|(?:a[bc]){2,}d|(a*?)+?b|(a?)*b|(?>a+|ab)c|(?<!foo)bar\b|^(?:(?<open>\()|[^()]|(?<close-open>\)))+(?(open)(?!))$|(?m:^#\w+$)|\Gab|cd\z|"[^"]*"|[^x]{3}z|[^a]*?d|b\B.|<.*?>`.
+func program0(m *binexp.Machine) error {
+	var back, c, codepos, count, i, mark, pos int
+
+	// 000000 *Lazybranch(Addr = 256)
+	m.TrackPush1(0, m.Pos())
+	// 000002 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(2)
+	// 000003 *Lazybranch(Addr = 21)
+	m.TrackPush1(3, m.Pos())
+	// 000005 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(5)
+	// 000006  Set-Ci(Set = ["'])
+	if m.End()-m.Pos() < 1 || !m.InSet(0, unicode.ToLower(m.Next())) {
+		codepos = 6
+		goto backtrack
+	}
+	// 000008 *Capturemark(Index = 3)
+	m.StackPop(1)
+	m.Capture(3, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(8, m.StackPeek(0))
+	// 000011  Setrep-Ci(Set = [a-z], Rep = 1)
+	if m.End()-m.Pos() < 1 {
+		codepos = 11
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if !m.InSet(1, unicode.ToLower(m.Next())) {
+			codepos = 11
+			goto backtrack
+		}
+	}
+	// 000014 *Setlazy-Ci(Set = [a-z], Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	if c > 0 {
+		m.TrackPush2(14, c-1, m.Pos())
+	}
+L17:
+	// 000017  Ref-Ci(Index = 3)
+	if !m.IsMatched(3) || !m.MatchRef(3, false, true) {
+		codepos = 17
+		goto backtrack
+	}
+	// 000019 *Goto(Addr = 253)
+	goto L253
+L21:
+	// 000021 *Lazybranch(Addr = 27)
+	m.TrackPush1(21, m.Pos())
+	// 000023  Multi-Ci(String = hello world)
+	if !m.MatchString(0, false, true) {
+		codepos = 23
+		goto backtrack
+	}
+	// 000025 *Goto(Addr = 253)
+	goto L253
+L27:
+	// 000027 *Lazybranch(Addr = 37)
+	m.TrackPush1(27, m.Pos())
+	// 000029  One-Ci(Ch = x)
+	if m.End()-m.Pos() < 1 || unicode.ToLower(m.Next()) != 'x' {
+		codepos = 29
+		goto backtrack
+	}
+	// 000031  Notone-Ci(Ch = y)
+	if m.End()-m.Pos() < 1 || unicode.ToLower(m.Next()) == 'y' {
+		codepos = 31
+		goto backtrack
+	}
+	// 000033  One-Ci(Ch = y)
+	if m.End()-m.Pos() < 1 || unicode.ToLower(m.Next()) != 'y' {
+		codepos = 33
+		goto backtrack
+	}
+	// 000035 *Goto(Addr = 253)
+	goto L253
+L37:
+	// 000037 *Lazybranch(Addr = 52)
+	m.TrackPush1(37, m.Pos())
+	// 000039 *Nullcount(Value = 0)
+	m.StackPush2(-1, 0)
+	m.TrackPush(39)
+	// 000041 *Goto(Addr = 45)
+	goto L45
+L43:
+	// 000043  Multi(String = ab)
+	if !m.MatchString(1, false, false) {
+		codepos = 43
+		goto backtrack
+	}
+L45:
+	// 000045 *Lazybranchcount(Addr = 43, Limit = 4)
+	m.StackPop(2)
+	mark = m.StackPeek(0)
+	count = m.StackPeek(1)
+	if count < 0 {
+		m.TrackPush1(-45, mark)
+		m.StackPush2(m.Pos(), count+1)
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L43
+	}
+	m.TrackPush3(45, mark, count, m.Pos())
+	// 000048  One(Ch = c)
+	if m.End()-m.Pos() < 1 || m.Next() != 'c' {
+		codepos = 48
+		goto backtrack
+	}
+	// 000050 *Goto(Addr = 253)
+	goto L253
+L52:
+	// 000052 *Lazybranch(Addr = 67)
+	m.TrackPush1(52, m.Pos())
+	// 000054 *Setcount(Value = -1)
+	m.StackPush2(m.Pos(), -1)
+	m.TrackPush(54)
+L56:
+	// 000056  One(Ch = a)
+	if m.End()-m.Pos() < 1 || m.Next() != 'a' {
+		codepos = 56
+		goto backtrack
+	}
+	// 000058  Set(Set = [bc])
+	if m.End()-m.Pos() < 1 || !m.InSet(2, m.Next()) {
+		codepos = 58
+		goto backtrack
+	}
+	// 000060 *Branchcount(Addr = 56, Limit = inf)
+	m.StackPop(2)
+	mark = m.StackPeek(0)
+	count = m.StackPeek(1)
+	if count >= 2147483647 || m.Pos() == mark && count >= 0 {
+		m.TrackPush2(-60, mark, count)
+	} else {
+		m.TrackPush1(60, mark)
+		m.StackPush2(m.Pos(), count+1)
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L56
+	}
+L63:
+	// 000063  One(Ch = d)
+	if m.End()-m.Pos() < 1 || m.Next() != 'd' {
+		codepos = 63
+		goto backtrack
+	}
+	// 000065 *Goto(Addr = 253)
+	goto L253
+L67:
+	// 000067 *Lazybranch(Addr = 83)
+	m.TrackPush1(67, m.Pos())
+	// 000069 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(69)
+L70:
+	// 000070 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(70)
+	// 000071 *Onelazy(Ch = a, Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	if c > 0 {
+		m.TrackPush2(71, c-1, m.Pos())
+	}
+L74:
+	// 000074 *Capturemark(Index = 1)
+	m.StackPop(1)
+	m.Capture(1, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(74, m.StackPeek(0))
+	// 000077 *Lazybranchmark(Addr = 70)
+	m.StackPop(1)
+	mark = m.StackPeek(0)
+	if m.Pos() != mark {
+		if mark != -1 {
+			m.TrackPush2(77, mark, m.Pos())
+		} else {
+			m.TrackPush2(77, m.Pos(), m.Pos())
+		}
+	} else {
+		m.StackPush(mark)
+		m.TrackPush1(-77, mark)
+	}
+	// 000079  One(Ch = b)
+	if m.End()-m.Pos() < 1 || m.Next() != 'b' {
+		codepos = 79
+		goto backtrack
+	}
+	// 000081 *Goto(Addr = 253)
+	goto L253
+L83:
+	// 000083 *Lazybranch(Addr = 101)
+	m.TrackPush1(83, m.Pos())
+	// 000085  Nullmark()
+	m.StackPush(-1)
+	m.TrackPush(85)
+	// 000086 *Goto(Addr = 95)
+	goto L95
+L88:
+	// 000088 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(88)
+	// 000089 *Oneloop(Ch = a, Rep = 1)
+	c = 1
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Next() != 'a' {
+			m.SetPos(m.Pos() - 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(89, c-i-1, m.Pos()-1)
+	}
+L92:
+	// 000092 *Capturemark(Index = 2)
+	m.StackPop(1)
+	m.Capture(2, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(92, m.StackPeek(0))
+L95:
+	// 000095 *Branchmark(Addr = 88)
+	m.StackPop(1)
+	if m.Pos() != m.StackPeek(0) {
+		m.TrackPush2(95, m.StackPeek(0), m.Pos())
+		m.StackPush(m.Pos())
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L88
+	}
+	m.TrackPush1(-95, m.StackPeek(0))
+L97:
+	// 000097  One(Ch = b)
+	if m.End()-m.Pos() < 1 || m.Next() != 'b' {
+		codepos = 97
+		goto backtrack
+	}
+	// 000099 *Goto(Addr = 253)
+	goto L253
+L101:
+	// 000101 *Lazybranch(Addr = 121)
+	m.TrackPush1(101, m.Pos())
+	// 000103 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(103)
+	// 000104 *Lazybranch(Addr = 114)
+	m.TrackPush1(104, m.Pos())
+	// 000106  Onerep(Ch = a, Rep = 1)
+	if m.End()-m.Pos() < 1 {
+		codepos = 106
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if m.Next() != 'a' {
+			codepos = 106
+			goto backtrack
+		}
+	}
+	// 000109 *Oneloop(Ch = a, Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Next() != 'a' {
+			m.SetPos(m.Pos() - 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(109, c-i-1, m.Pos()-1)
+	}
+L112:
+	// 000112 *Goto(Addr = 116)
+	goto L116
+L114:
+	// 000114  Multi(String = ab)
+	if !m.MatchString(1, false, false) {
+		codepos = 114
+		goto backtrack
+	}
+L116:
+	// 000116 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(116, m.StackPeek(1))
+	// 000117  One(Ch = c)
+	if m.End()-m.Pos() < 1 || m.Next() != 'c' {
+		codepos = 117
+		goto backtrack
+	}
+	// 000119 *Goto(Addr = 253)
+	goto L253
+L121:
+	// 000121 *Lazybranch(Addr = 135)
+	m.TrackPush1(121, m.Pos())
+	// 000123 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(123)
+	// 000124 *Lazybranch(Addr = 129)
+	m.TrackPush1(124, m.Pos())
+	// 000126  Multi-Rtl(String = foo)
+	if !m.MatchString(2, true, false) {
+		codepos = 126
+		goto backtrack
+	}
+	// 000128 *Backjump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	for m.Crawlpos() != m.StackPeek(1) {
+		m.Uncapture()
+	}
+	codepos = 128
+	goto backtrack
+L129:
+	// 000129 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(129, m.StackPeek(1))
+	// 000130  Multi(String = bar)
+	if !m.MatchString(3, false, false) {
+		codepos = 130
+		goto backtrack
+	}
+	// 000132  Boundary()
+	if !m.IsBoundary(m.Pos(), false) {
+		codepos = 132
+		goto backtrack
+	}
+	// 000133 *Goto(Addr = 253)
+	goto L253
+L135:
+	// 000135 *Lazybranch(Addr = 180)
+	m.TrackPush1(135, m.Pos())
+	// 000137  Beginning()
+	if m.Pos() > 0 {
+		codepos = 137
+		goto backtrack
+	}
+	// 000138 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(138)
+L139:
+	// 000139 *Lazybranch(Addr = 149)
+	m.TrackPush1(139, m.Pos())
+	// 000141 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(141)
+	// 000142  One(Ch = \()
+	if m.End()-m.Pos() < 1 || m.Next() != '(' {
+		codepos = 142
+		goto backtrack
+	}
+	// 000144 *Capturemark(Index = 4)
+	m.StackPop(1)
+	m.Capture(4, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(144, m.StackPeek(0))
+	// 000147 *Goto(Addr = 161)
+	goto L161
+L149:
+	// 000149 *Lazybranch(Addr = 155)
+	m.TrackPush1(149, m.Pos())
+	// 000151  Set(Set = [^\(\)])
+	if m.End()-m.Pos() < 1 || !m.InSet(3, m.Next()) {
+		codepos = 151
+		goto backtrack
+	}
+	// 000153 *Goto(Addr = 161)
+	goto L161
+L155:
+	// 000155 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(155)
+	// 000156  One(Ch = \))
+	if m.End()-m.Pos() < 1 || m.Next() != ')' {
+		codepos = 156
+		goto backtrack
+	}
+	// 000158 *Capturemark(Index = 5, Unindex = 4)
+	if !m.IsMatched(4) {
+		codepos = 158
+		goto backtrack
+	}
+	m.StackPop(1)
+	m.Capture(5, 4, m.StackPeek(0), m.Pos())
+	m.TrackPush1(158, m.StackPeek(0))
+L161:
+	// 000161 *Branchmark(Addr = 139)
+	m.StackPop(1)
+	if m.Pos() != m.StackPeek(0) {
+		m.TrackPush2(161, m.StackPeek(0), m.Pos())
+		m.StackPush(m.Pos())
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L139
+	}
+	m.TrackPush1(-161, m.StackPeek(0))
+L163:
+	// 000163 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(163)
+	// 000164 *Lazybranch(Addr = 176)
+	m.TrackPush1(164, m.Pos())
+	// 000166  Testref(Index = 4)
+	if !m.IsMatched(4) {
+		codepos = 166
+		goto backtrack
+	}
+	// 000168 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(168, m.StackPeek(1))
+	// 000169 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(169)
+	// 000170 *Lazybranch(Addr = 173)
+	m.TrackPush1(170, m.Pos())
+	// 000172 *Backjump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	for m.Crawlpos() != m.StackPeek(1) {
+		m.Uncapture()
+	}
+	codepos = 172
+	goto backtrack
+L173:
+	// 000173 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(173, m.StackPeek(1))
+	// 000174 *Goto(Addr = 177)
+	goto L177
+L176:
+	// 000176 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(176, m.StackPeek(1))
+L177:
+	// 000177  EndZ()
+	if m.End()-m.Pos() > 1 || m.End()-m.Pos() == 1 && m.CharAt(m.Pos()) != '\n' {
+		codepos = 177
+		goto backtrack
+	}
+	// 000178 *Goto(Addr = 253)
+	goto L253
+L180:
+	// 000180 *Lazybranch(Addr = 194)
+	m.TrackPush1(180, m.Pos())
+	// 000182  Bol()
+	if m.Pos() > 0 && m.CharAt(m.Pos()-1) != '\n' {
+		codepos = 182
+		goto backtrack
+	}
+	// 000183  One(Ch = \#)
+	if m.End()-m.Pos() < 1 || m.Next() != '#' {
+		codepos = 183
+		goto backtrack
+	}
+	// 000185  Setrep(Set = [\w], Rep = 1)
+	if m.End()-m.Pos() < 1 {
+		codepos = 185
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if !m.InSet(4, m.Next()) {
+			codepos = 185
+			goto backtrack
+		}
+	}
+	// 000188 *Setloop(Set = [\w], Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if !m.InSet(4, m.Next()) {
+			m.SetPos(m.Pos() - 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(188, c-i-1, m.Pos()-1)
+	}
+L191:
+	// 000191  Eol()
+	if m.Pos() < m.End() && m.CharAt(m.Pos()) != '\n' {
+		codepos = 191
+		goto backtrack
+	}
+	// 000192 *Goto(Addr = 253)
+	goto L253
+L194:
+	// 000194 *Lazybranch(Addr = 201)
+	m.TrackPush1(194, m.Pos())
+	// 000196  Start()
+	if m.Pos() != m.Start() {
+		codepos = 196
+		goto backtrack
+	}
+	// 000197  Multi(String = ab)
+	if !m.MatchString(1, false, false) {
+		codepos = 197
+		goto backtrack
+	}
+	// 000199 *Goto(Addr = 253)
+	goto L253
+L201:
+	// 000201 *Lazybranch(Addr = 208)
+	m.TrackPush1(201, m.Pos())
+	// 000203  Multi(String = cd)
+	if !m.MatchString(4, false, false) {
+		codepos = 203
+		goto backtrack
+	}
+	// 000205  End()
+	if m.Pos() < m.End() {
+		codepos = 205
+		goto backtrack
+	}
+	// 000206 *Goto(Addr = 253)
+	goto L253
+L208:
+	// 000208 *Lazybranch(Addr = 219)
+	m.TrackPush1(208, m.Pos())
+	// 000210  One(Ch = ")
+	if m.End()-m.Pos() < 1 || m.Next() != '"' {
+		codepos = 210
+		goto backtrack
+	}
+	// 000212 *Notoneloop(Ch = ", Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Next() == '"' {
+			m.SetPos(m.Pos() - 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(212, c-i-1, m.Pos()-1)
+	}
+L215:
+	// 000215  One(Ch = ")
+	if m.End()-m.Pos() < 1 || m.Next() != '"' {
+		codepos = 215
+		goto backtrack
+	}
+	// 000217 *Goto(Addr = 253)
+	goto L253
+L219:
+	// 000219 *Lazybranch(Addr = 228)
+	m.TrackPush1(219, m.Pos())
+	// 000221  Notonerep(Ch = x, Rep = 3)
+	if m.End()-m.Pos() < 3 {
+		codepos = 221
+		goto backtrack
+	}
+	for i = 3; i > 0; i-- {
+		if m.Next() == 'x' {
+			codepos = 221
+			goto backtrack
+		}
+	}
+	// 000224  One(Ch = z)
+	if m.End()-m.Pos() < 1 || m.Next() != 'z' {
+		codepos = 224
+		goto backtrack
+	}
+	// 000226 *Goto(Addr = 253)
+	goto L253
+L228:
+	// 000228 *Lazybranch(Addr = 237)
+	m.TrackPush1(228, m.Pos())
+	// 000230 *Setlazy(Set = [^a], Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	if c > 0 {
+		m.TrackPush2(230, c-1, m.Pos())
+	}
+L233:
+	// 000233  One(Ch = d)
+	if m.End()-m.Pos() < 1 || m.Next() != 'd' {
+		codepos = 233
+		goto backtrack
+	}
+	// 000235 *Goto(Addr = 253)
+	goto L253
+L237:
+	// 000237 *Lazybranch(Addr = 246)
+	m.TrackPush1(237, m.Pos())
+	// 000239  One(Ch = b)
+	if m.End()-m.Pos() < 1 || m.Next() != 'b' {
+		codepos = 239
+		goto backtrack
+	}
+	// 000241  Nonboundary()
+	if m.IsBoundary(m.Pos(), false) {
+		codepos = 241
+		goto backtrack
+	}
+	// 000242  Notone(Ch = \n)
+	if m.End()-m.Pos() < 1 || m.Next() == 0xa {
+		codepos = 242
+		goto backtrack
+	}
+	// 000244 *Goto(Addr = 253)
+	goto L253
+L246:
+	// 000246  One(Ch = <)
+	if m.End()-m.Pos() < 1 || m.Next() != '<' {
+		codepos = 246
+		goto backtrack
+	}
+	// 000248 *Notonelazy(Ch = \n, Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	if c > 0 {
+		m.TrackPush2(248, c-1, m.Pos())
+	}
+L251:
+	// 000251  One(Ch = >)
+	if m.End()-m.Pos() < 1 || m.Next() != '>' {
+		codepos = 251
+		goto backtrack
+	}
+L253:
+	// 000253 *Capturemark(Index = 0)
+	m.StackPop(1)
+	m.Capture(0, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(253, m.StackPeek(0))
+L256:
+	// 000256  Stop()
+	return nil
+
+backtrack:
+	back = m.Backtrack()
+	if back < codepos && -back < codepos {
+		m.EnsureStorage()
+	}
+	if err := m.Step(); err != nil {
+		return err
+	}
+	switch back {
+	case 0:
+		goto L0Back
+	case 2:
+		goto L2Back
+	case 3:
+		goto L3Back
+	case 5:
+		goto L5Back
+	case 8:
+		goto L8Back
+	case 14:
+		goto L14Back
+	case 21:
+		goto L21Back
+	case 27:
+		goto L27Back
+	case 37:
+		goto L37Back
+	case 39:
+		goto L39Back
+	case 45:
+		goto L45Back
+	case -45:
+		goto L45Back2
+	case 52:
+		goto L52Back
+	case 54:
+		goto L54Back
+	case 60:
+		goto L60Back
+	case -60:
+		goto L60Back2
+	case 67:
+		goto L67Back
+	case 69:
+		goto L69Back
+	case 70:
+		goto L70Back
+	case 71:
+		goto L71Back
+	case 74:
+		goto L74Back
+	case 77:
+		goto L77Back
+	case -77:
+		goto L77Back2
+	case 83:
+		goto L83Back
+	case 85:
+		goto L85Back
+	case 88:
+		goto L88Back
+	case 89:
+		goto L89Back
+	case 92:
+		goto L92Back
+	case 95:
+		goto L95Back
+	case -95:
+		goto L95Back2
+	case 101:
+		goto L101Back
+	case 103:
+		goto L103Back
+	case 104:
+		goto L104Back
+	case 109:
+		goto L109Back
+	case 116:
+		goto L116Back
+	case 121:
+		goto L121Back
+	case 123:
+		goto L123Back
+	case 124:
+		goto L124Back
+	case 129:
+		goto L129Back
+	case 135:
+		goto L135Back
+	case 138:
+		goto L138Back
+	case 139:
+		goto L139Back
+	case 141:
+		goto L141Back
+	case 144:
+		goto L144Back
+	case 149:
+		goto L149Back
+	case 155:
+		goto L155Back
+	case 158:
+		goto L158Back
+	case 161:
+		goto L161Back
+	case -161:
+		goto L161Back2
+	case 163:
+		goto L163Back
+	case 164:
+		goto L164Back
+	case 168:
+		goto L168Back
+	case 169:
+		goto L169Back
+	case 170:
+		goto L170Back
+	case 173:
+		goto L173Back
+	case 176:
+		goto L176Back
+	case 180:
+		goto L180Back
+	case 188:
+		goto L188Back
+	case 194:
+		goto L194Back
+	case 201:
+		goto L201Back
+	case 208:
+		goto L208Back
+	case 212:
+		goto L212Back
+	case 219:
+		goto L219Back
+	case 228:
+		goto L228Back
+	case 230:
+		goto L230Back
+	case 237:
+		goto L237Back
+	case 248:
+		goto L248Back
+	case 253:
+		goto L253Back
+	}
+	return m.UnknownState()
+
+L0Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L256
+
+L2Back:
+	m.StackPop(1)
+	codepos = 2
+	goto backtrack
+
+L3Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L21
+
+L5Back:
+	m.StackPop(1)
+	codepos = 5
+	goto backtrack
+
+L8Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 8
+	goto backtrack
+
+L14Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if !m.InSet(1, unicode.ToLower(m.Next())) {
+		codepos = 14
+		goto backtrack
+	}
+	i = m.TrackPeek(0)
+	if i > 0 {
+		m.TrackPush2(14, i-1, pos+1)
+	}
+	goto L17
+
+L21Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L27
+
+L27Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L37
+
+L37Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L52
+
+L39Back:
+	m.StackPop(2)
+	codepos = 39
+	goto backtrack
+
+L45Back:
+	m.TrackPop(3)
+	mark = m.TrackPeek(0)
+	pos = m.TrackPeek(2)
+	if m.TrackPeek(1) < 4 && pos != mark {
+		m.SetPos(pos)
+		m.StackPush2(pos, m.TrackPeek(1)+1)
+		m.TrackPush1(-45, mark)
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L43
+	}
+	m.StackPush2(m.TrackPeek(0), m.TrackPeek(1))
+	codepos = 45
+	goto backtrack
+
+L45Back2:
+	m.TrackPop(1)
+	m.StackPop(2)
+	m.StackPush2(m.TrackPeek(0), m.StackPeek(1)-1)
+	codepos = 45
+	goto backtrack
+
+L52Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L67
+
+L54Back:
+	m.StackPop(2)
+	codepos = 54
+	goto backtrack
+
+L60Back:
+	m.TrackPop(1)
+	m.StackPop(2)
+	if m.StackPeek(1) > 0 {
+		m.SetPos(m.StackPeek(0))
+		m.TrackPush2(-60, m.TrackPeek(0), m.StackPeek(1)-1)
+		goto L63
+	}
+	m.StackPush2(m.TrackPeek(0), m.StackPeek(1)-1)
+	codepos = 60
+	goto backtrack
+
+L60Back2:
+	m.TrackPop(2)
+	m.StackPush2(m.TrackPeek(0), m.TrackPeek(1))
+	codepos = 60
+	goto backtrack
+
+L67Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L83
+
+L69Back:
+	m.StackPop(1)
+	codepos = 69
+	goto backtrack
+
+L70Back:
+	m.StackPop(1)
+	codepos = 70
+	goto backtrack
+
+L71Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if m.Next() != 'a' {
+		codepos = 71
+		goto backtrack
+	}
+	i = m.TrackPeek(0)
+	if i > 0 {
+		m.TrackPush2(71, i-1, pos+1)
+	}
+	goto L74
+
+L74Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 74
+	goto backtrack
+
+L77Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.TrackPush1(-77, m.TrackPeek(0))
+	m.StackPush(pos)
+	m.SetPos(pos)
+	m.EnsureStorage()
+	if err := m.Step(); err != nil {
+		return err
+	}
+	goto L70
+
+L77Back2:
+	m.StackPop(1)
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 77
+	goto backtrack
+
+L83Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L101
+
+L85Back:
+	m.StackPop(1)
+	codepos = 85
+	goto backtrack
+
+L88Back:
+	m.StackPop(1)
+	codepos = 88
+	goto backtrack
+
+L89Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(89, i-1, pos-1)
+	}
+	goto L92
+
+L92Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 92
+	goto backtrack
+
+L95Back:
+	m.TrackPop(2)
+	m.StackPop(1)
+	m.SetPos(m.TrackPeek(1))
+	m.TrackPush1(-95, m.TrackPeek(0))
+	goto L97
+
+L95Back2:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 95
+	goto backtrack
+
+L101Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L121
+
+L103Back:
+	m.StackPop(2)
+	codepos = 103
+	goto backtrack
+
+L104Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L114
+
+L109Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(109, i-1, pos-1)
+	}
+	goto L112
+
+L116Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 116
+	goto backtrack
+
+L121Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L135
+
+L123Back:
+	m.StackPop(2)
+	codepos = 123
+	goto backtrack
+
+L124Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L129
+
+L129Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 129
+	goto backtrack
+
+L135Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L180
+
+L138Back:
+	m.StackPop(1)
+	codepos = 138
+	goto backtrack
+
+L139Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L149
+
+L141Back:
+	m.StackPop(1)
+	codepos = 141
+	goto backtrack
+
+L144Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 144
+	goto backtrack
+
+L149Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L155
+
+L155Back:
+	m.StackPop(1)
+	codepos = 155
+	goto backtrack
+
+L158Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	m.Uncapture()
+	codepos = 158
+	goto backtrack
+
+L161Back:
+	m.TrackPop(2)
+	m.StackPop(1)
+	m.SetPos(m.TrackPeek(1))
+	m.TrackPush1(-161, m.TrackPeek(0))
+	goto L163
+
+L161Back2:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 161
+	goto backtrack
+
+L163Back:
+	m.StackPop(2)
+	codepos = 163
+	goto backtrack
+
+L164Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L176
+
+L168Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 168
+	goto backtrack
+
+L169Back:
+	m.StackPop(2)
+	codepos = 169
+	goto backtrack
+
+L170Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L173
+
+L173Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 173
+	goto backtrack
+
+L176Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 176
+	goto backtrack
+
+L180Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L194
+
+L188Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(188, i-1, pos-1)
+	}
+	goto L191
+
+L194Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L201
+
+L201Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L208
+
+L208Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L219
+
+L212Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(212, i-1, pos-1)
+	}
+	goto L215
+
+L219Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L228
+
+L228Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L237
+
+L230Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if !m.InSet(5, m.Next()) {
+		codepos = 230
+		goto backtrack
+	}
+	i = m.TrackPeek(0)
+	if i > 0 {
+		m.TrackPush2(230, i-1, pos+1)
+	}
+	goto L233
+
+L237Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L246
+
+L248Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if m.Next() == 0xa {
+		codepos = 248
+		goto backtrack
+	}
+	i = m.TrackPeek(0)
+	if i > 0 {
+		m.TrackPush2(248, i-1, pos+1)
+	}
+	goto L251
+
+L253Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 253
+	goto backtrack
+}
+
+// program1 is the program for `\d+-\w{2,3}?(?:ab){2,}?|a+|(?<=(a+))b|c(?=(d+))|(?i)abc[x-z]*|"[^"]*"|q{2}|(?<r>a)\k<r>` with options 0x40.
+func program1(m *binexp.Machine) error {
+	var back, c, codepos, count, i, mark, pos int
+
+	// 000000 *Lazybranch(Addr = 116)
+	m.TrackPush1(0, m.Pos())
+	// 000002 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(2)
+	// 000003 *Lazybranch(Addr = 28)
+	m.TrackPush1(3, m.Pos())
+	// 000005 *Setcount(Value = -1)
+	m.StackPush2(m.Pos(), -1)
+	m.TrackPush(5)
+L7:
+	// 000007  Multi-Rtl(String = ab)
+	if !m.MatchString(0, true, false) {
+		codepos = 7
+		goto backtrack
+	}
+	// 000009 *Lazybranchcount(Addr = 7, Limit = inf)
+	m.StackPop(2)
+	mark = m.StackPeek(0)
+	count = m.StackPeek(1)
+	if count < 0 {
+		m.TrackPush1(-9, mark)
+		m.StackPush2(m.Pos(), count+1)
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L7
+	}
+	m.TrackPush3(9, mark, count, m.Pos())
+	// 000012  Setrep-Rtl(Set = [\w], Rep = 2)
+	if m.Pos() < 2 {
+		codepos = 12
+		goto backtrack
+	}
+	for i = 2; i > 0; i-- {
+		if !m.InSet(0, m.Prev()) {
+			codepos = 12
+			goto backtrack
+		}
+	}
+	// 000015 *Setlazy-Rtl(Set = [\w], Rep = 1)
+	c = 1
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	if c > 0 {
+		m.TrackPush2(15, c-1, m.Pos())
+	}
+L18:
+	// 000018  One-Rtl(Ch = -)
+	if m.Pos() < 1 || m.Prev() != '-' {
+		codepos = 18
+		goto backtrack
+	}
+	// 000020  Setrep-Rtl(Set = [\p{Nd}], Rep = 1)
+	if m.Pos() < 1 {
+		codepos = 20
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if !m.InSet(1, m.Prev()) {
+			codepos = 20
+			goto backtrack
+		}
+	}
+	// 000023 *Setloop-Rtl(Set = [\p{Nd}], Rep = inf)
+	c = 2147483647
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if !m.InSet(1, m.Prev()) {
+			m.SetPos(m.Pos() + 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(23, c-i-1, m.Pos()+1)
+	}
+L26:
+	// 000026 *Goto(Addr = 113)
+	goto L113
+L28:
+	// 000028 *Lazybranch(Addr = 38)
+	m.TrackPush1(28, m.Pos())
+	// 000030  Onerep-Rtl(Ch = a, Rep = 1)
+	if m.Pos() < 1 {
+		codepos = 30
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if m.Prev() != 'a' {
+			codepos = 30
+			goto backtrack
+		}
+	}
+	// 000033 *Oneloop-Rtl(Ch = a, Rep = inf)
+	c = 2147483647
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Prev() != 'a' {
+			m.SetPos(m.Pos() + 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(33, c-i-1, m.Pos()+1)
+	}
+L36:
+	// 000036 *Goto(Addr = 113)
+	goto L113
+L38:
+	// 000038 *Lazybranch(Addr = 58)
+	m.TrackPush1(38, m.Pos())
+	// 000040  One-Rtl(Ch = b)
+	if m.Pos() < 1 || m.Prev() != 'b' {
+		codepos = 40
+		goto backtrack
+	}
+	// 000042 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(42)
+	// 000043 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(43)
+	// 000044 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(44)
+	// 000045  Onerep-Rtl(Ch = a, Rep = 1)
+	if m.Pos() < 1 {
+		codepos = 45
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if m.Prev() != 'a' {
+			codepos = 45
+			goto backtrack
+		}
+	}
+	// 000048 *Oneloop-Rtl(Ch = a, Rep = inf)
+	c = 2147483647
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Prev() != 'a' {
+			m.SetPos(m.Pos() + 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(48, c-i-1, m.Pos()+1)
+	}
+L51:
+	// 000051 *Capturemark(Index = 1)
+	m.StackPop(1)
+	m.Capture(1, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(51, m.StackPeek(0))
+	// 000054 *Getmark()
+	m.StackPop(1)
+	m.TrackPush1(54, m.StackPeek(0))
+	m.SetPos(m.StackPeek(0))
+	// 000055 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(55, m.StackPeek(1))
+	// 000056 *Goto(Addr = 113)
+	goto L113
+L58:
+	// 000058 *Lazybranch(Addr = 78)
+	m.TrackPush1(58, m.Pos())
+	// 000060 *Setjump()
+	m.StackPush2(m.Trackpos(), m.Crawlpos())
+	m.TrackPush(60)
+	// 000061 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(61)
+	// 000062 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(62)
+	// 000063  Onerep(Ch = d, Rep = 1)
+	if m.End()-m.Pos() < 1 {
+		codepos = 63
+		goto backtrack
+	}
+	for i = 1; i > 0; i-- {
+		if m.Next() != 'd' {
+			codepos = 63
+			goto backtrack
+		}
+	}
+	// 000066 *Oneloop(Ch = d, Rep = inf)
+	c = 2147483647
+	if c > m.End()-m.Pos() {
+		c = m.End() - m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if m.Next() != 'd' {
+			m.SetPos(m.Pos() - 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(66, c-i-1, m.Pos()-1)
+	}
+L69:
+	// 000069 *Capturemark(Index = 2)
+	m.StackPop(1)
+	m.Capture(2, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(69, m.StackPeek(0))
+	// 000072 *Getmark()
+	m.StackPop(1)
+	m.TrackPush1(72, m.StackPeek(0))
+	m.SetPos(m.StackPeek(0))
+	// 000073 *Forejump()
+	m.StackPop(2)
+	m.Trackto(m.StackPeek(0))
+	m.TrackPush1(73, m.StackPeek(1))
+	// 000074  One-Rtl(Ch = c)
+	if m.Pos() < 1 || m.Prev() != 'c' {
+		codepos = 74
+		goto backtrack
+	}
+	// 000076 *Goto(Addr = 113)
+	goto L113
+L78:
+	// 000078 *Lazybranch(Addr = 87)
+	m.TrackPush1(78, m.Pos())
+	// 000080 *Setloop-Ci-Rtl(Set = [x-z], Rep = inf)
+	c = 2147483647
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if !m.InSet(2, unicode.ToLower(m.Prev())) {
+			m.SetPos(m.Pos() + 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(80, c-i-1, m.Pos()+1)
+	}
+L83:
+	// 000083  Multi-Ci-Rtl(String = abc)
+	if !m.MatchString(1, true, true) {
+		codepos = 83
+		goto backtrack
+	}
+	// 000085 *Goto(Addr = 113)
+	goto L113
+L87:
+	// 000087 *Lazybranch(Addr = 98)
+	m.TrackPush1(87, m.Pos())
+	// 000089  One-Ci-Rtl(Ch = ")
+	if m.Pos() < 1 || unicode.ToLower(m.Prev()) != '"' {
+		codepos = 89
+		goto backtrack
+	}
+	// 000091 *Notoneloop-Ci-Rtl(Ch = ", Rep = inf)
+	c = 2147483647
+	if c > m.Pos() {
+		c = m.Pos()
+	}
+	for i = c; i > 0; i-- {
+		if unicode.ToLower(m.Prev()) == '"' {
+			m.SetPos(m.Pos() + 1)
+			break
+		}
+	}
+	if c > i {
+		m.TrackPush2(91, c-i-1, m.Pos()+1)
+	}
+L94:
+	// 000094  One-Ci-Rtl(Ch = ")
+	if m.Pos() < 1 || unicode.ToLower(m.Prev()) != '"' {
+		codepos = 94
+		goto backtrack
+	}
+	// 000096 *Goto(Addr = 113)
+	goto L113
+L98:
+	// 000098 *Lazybranch(Addr = 105)
+	m.TrackPush1(98, m.Pos())
+	// 000100  Onerep-Ci-Rtl(Ch = q, Rep = 2)
+	if m.Pos() < 2 {
+		codepos = 100
+		goto backtrack
+	}
+	for i = 2; i > 0; i-- {
+		if unicode.ToLower(m.Prev()) != 'q' {
+			codepos = 100
+			goto backtrack
+		}
+	}
+	// 000103 *Goto(Addr = 113)
+	goto L113
+L105:
+	// 000105  Ref-Ci-Rtl(Index = 3)
+	if !m.IsMatched(3) || !m.MatchRef(3, true, true) {
+		codepos = 105
+		goto backtrack
+	}
+	// 000107 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(107)
+	// 000108  One-Ci-Rtl(Ch = a)
+	if m.Pos() < 1 || unicode.ToLower(m.Prev()) != 'a' {
+		codepos = 108
+		goto backtrack
+	}
+	// 000110 *Capturemark(Index = 3)
+	m.StackPop(1)
+	m.Capture(3, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(110, m.StackPeek(0))
+L113:
+	// 000113 *Capturemark(Index = 0)
+	m.StackPop(1)
+	m.Capture(0, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(113, m.StackPeek(0))
+L116:
+	// 000116  Stop()
+	return nil
+
+backtrack:
+	back = m.Backtrack()
+	if back < codepos && -back < codepos {
+		m.EnsureStorage()
+	}
+	if err := m.Step(); err != nil {
+		return err
+	}
+	switch back {
+	case 0:
+		goto L0Back
+	case 2:
+		goto L2Back
+	case 3:
+		goto L3Back
+	case 5:
+		goto L5Back
+	case 9:
+		goto L9Back
+	case -9:
+		goto L9Back2
+	case 15:
+		goto L15Back
+	case 23:
+		goto L23Back
+	case 28:
+		goto L28Back
+	case 33:
+		goto L33Back
+	case 38:
+		goto L38Back
+	case 42:
+		goto L42Back
+	case 43:
+		goto L43Back
+	case 44:
+		goto L44Back
+	case 48:
+		goto L48Back
+	case 51:
+		goto L51Back
+	case 54:
+		goto L54Back
+	case 55:
+		goto L55Back
+	case 58:
+		goto L58Back
+	case 60:
+		goto L60Back
+	case 61:
+		goto L61Back
+	case 62:
+		goto L62Back
+	case 66:
+		goto L66Back
+	case 69:
+		goto L69Back
+	case 72:
+		goto L72Back
+	case 73:
+		goto L73Back
+	case 78:
+		goto L78Back
+	case 80:
+		goto L80Back
+	case 87:
+		goto L87Back
+	case 91:
+		goto L91Back
+	case 98:
+		goto L98Back
+	case 107:
+		goto L107Back
+	case 110:
+		goto L110Back
+	case 113:
+		goto L113Back
+	}
+	return m.UnknownState()
+
+L0Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L116
+
+L2Back:
+	m.StackPop(1)
+	codepos = 2
+	goto backtrack
+
+L3Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L28
+
+L5Back:
+	m.StackPop(2)
+	codepos = 5
+	goto backtrack
+
+L9Back:
+	m.TrackPop(3)
+	mark = m.TrackPeek(0)
+	pos = m.TrackPeek(2)
+	if m.TrackPeek(1) < 2147483647 && pos != mark {
+		m.SetPos(pos)
+		m.StackPush2(pos, m.TrackPeek(1)+1)
+		m.TrackPush1(-9, mark)
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L7
+	}
+	m.StackPush2(m.TrackPeek(0), m.TrackPeek(1))
+	codepos = 9
+	goto backtrack
+
+L9Back2:
+	m.TrackPop(1)
+	m.StackPop(2)
+	m.StackPush2(m.TrackPeek(0), m.StackPeek(1)-1)
+	codepos = 9
+	goto backtrack
+
+L15Back:
+	m.TrackPop(2)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if !m.InSet(0, m.Prev()) {
+		codepos = 15
+		goto backtrack
+	}
+	i = m.TrackPeek(0)
+	if i > 0 {
+		m.TrackPush2(15, i-1, pos-1)
+	}
+	goto L18
+
+L23Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(23, i-1, pos+1)
+	}
+	goto L26
+
+L28Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L38
+
+L33Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(33, i-1, pos+1)
+	}
+	goto L36
+
+L38Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L58
+
+L42Back:
+	m.StackPop(2)
+	codepos = 42
+	goto backtrack
+
+L43Back:
+	m.StackPop(1)
+	codepos = 43
+	goto backtrack
+
+L44Back:
+	m.StackPop(1)
+	codepos = 44
+	goto backtrack
+
+L48Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(48, i-1, pos+1)
+	}
+	goto L51
+
+L51Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 51
+	goto backtrack
+
+L54Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 54
+	goto backtrack
+
+L55Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 55
+	goto backtrack
+
+L58Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L78
+
+L60Back:
+	m.StackPop(2)
+	codepos = 60
+	goto backtrack
+
+L61Back:
+	m.StackPop(1)
+	codepos = 61
+	goto backtrack
+
+L62Back:
+	m.StackPop(1)
+	codepos = 62
+	goto backtrack
+
+L66Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(66, i-1, pos-1)
+	}
+	goto L69
+
+L69Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 69
+	goto backtrack
+
+L72Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 72
+	goto backtrack
+
+L73Back:
+	m.TrackPop(1)
+	for m.Crawlpos() != m.TrackPeek(0) {
+		m.Uncapture()
+	}
+	codepos = 73
+	goto backtrack
+
+L78Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L87
+
+L80Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(80, i-1, pos+1)
+	}
+	goto L83
+
+L87Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L98
+
+L91Back:
+	m.TrackPop(2)
+	i = m.TrackPeek(0)
+	pos = m.TrackPeek(1)
+	m.SetPos(pos)
+	if i > 0 {
+		m.TrackPush2(91, i-1, pos+1)
+	}
+	goto L94
+
+L98Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L105
+
+L107Back:
+	m.StackPop(1)
+	codepos = 107
+	goto backtrack
+
+L110Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 110
+	goto backtrack
+
+L113Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 113
+	goto backtrack
+}
+
+// program2 is the program for `(a)|\1b|\bfoo\B|\x55\x48\x89\xe5(?:\x41[\x54-\x57])+` with options 0x300.
+func program2(m *binexp.Machine) error {
+	var back, codepos int
+
+	// 000000 *Lazybranch(Addr = 41)
+	m.TrackPush1(0, m.Pos())
+	// 000002 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(2)
+	// 000003 *Lazybranch(Addr = 13)
+	m.TrackPush1(3, m.Pos())
+	// 000005 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(5)
+	// 000006  One(Ch = a)
+	if m.End()-m.Pos() < 1 || m.Next() != 'a' {
+		codepos = 6
+		goto backtrack
+	}
+	// 000008 *Capturemark(Index = 1)
+	m.StackPop(1)
+	m.Capture(1, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(8, m.StackPeek(0))
+	// 000011 *Goto(Addr = 38)
+	goto L38
+L13:
+	// 000013 *Lazybranch(Addr = 21)
+	m.TrackPush1(13, m.Pos())
+	// 000015  Ref(Index = 1)
+	if m.IsMatched(1) && !m.MatchRef(1, false, false) {
+		codepos = 15
+		goto backtrack
+	}
+	// 000017  One(Ch = b)
+	if m.End()-m.Pos() < 1 || m.Next() != 'b' {
+		codepos = 17
+		goto backtrack
+	}
+	// 000019 *Goto(Addr = 38)
+	goto L38
+L21:
+	// 000021 *Lazybranch(Addr = 29)
+	m.TrackPush1(21, m.Pos())
+	// 000023  ECMABoundary()
+	if !m.IsBoundary(m.Pos(), true) {
+		codepos = 23
+		goto backtrack
+	}
+	// 000024  Multi(String = foo)
+	if !m.MatchString(0, false, false) {
+		codepos = 24
+		goto backtrack
+	}
+	// 000026  NonECMABoundary()
+	if m.IsBoundary(m.Pos(), true) {
+		codepos = 26
+		goto backtrack
+	}
+	// 000027 *Goto(Addr = 38)
+	goto L38
+L29:
+	// 000029  Multi(String = UH\u0089\u00e5)
+	if !m.MatchString(1, false, false) {
+		codepos = 29
+		goto backtrack
+	}
+	// 000031 *Setmark()
+	m.StackPush(m.Pos())
+	m.TrackPush(31)
+L32:
+	// 000032  One(Ch = A)
+	if m.End()-m.Pos() < 1 || m.Next() != 'A' {
+		codepos = 32
+		goto backtrack
+	}
+	// 000034  Set(Set = [T-W])
+	if m.End()-m.Pos() < 1 || !m.InSet(0, m.Next()) {
+		codepos = 34
+		goto backtrack
+	}
+	// 000036 *Branchmark(Addr = 32)
+	m.StackPop(1)
+	if m.Pos() != m.StackPeek(0) {
+		m.TrackPush2(36, m.StackPeek(0), m.Pos())
+		m.StackPush(m.Pos())
+		m.EnsureStorage()
+		if err := m.Step(); err != nil {
+			return err
+		}
+		goto L32
+	}
+	m.TrackPush1(-36, m.StackPeek(0))
+L38:
+	// 000038 *Capturemark(Index = 0)
+	m.StackPop(1)
+	m.Capture(0, -1, m.StackPeek(0), m.Pos())
+	m.TrackPush1(38, m.StackPeek(0))
+L41:
+	// 000041  Stop()
+	return nil
+
+backtrack:
+	back = m.Backtrack()
+	if back < codepos && -back < codepos {
+		m.EnsureStorage()
+	}
+	if err := m.Step(); err != nil {
+		return err
+	}
+	switch back {
+	case 0:
+		goto L0Back
+	case 2:
+		goto L2Back
+	case 3:
+		goto L3Back
+	case 5:
+		goto L5Back
+	case 8:
+		goto L8Back
+	case 13:
+		goto L13Back
+	case 21:
+		goto L21Back
+	case 31:
+		goto L31Back
+	case 36:
+		goto L36Back
+	case -36:
+		goto L36Back2
+	case 38:
+		goto L38Back
+	}
+	return m.UnknownState()
+
+L0Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L41
+
+L2Back:
+	m.StackPop(1)
+	codepos = 2
+	goto backtrack
+
+L3Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L13
+
+L5Back:
+	m.StackPop(1)
+	codepos = 5
+	goto backtrack
+
+L8Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 8
+	goto backtrack
+
+L13Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L21
+
+L21Back:
+	m.TrackPop(1)
+	m.SetPos(m.TrackPeek(0))
+	goto L29
+
+L31Back:
+	m.StackPop(1)
+	codepos = 31
+	goto backtrack
+
+L36Back:
+	m.TrackPop(2)
+	m.StackPop(1)
+	m.SetPos(m.TrackPeek(1))
+	m.TrackPush1(-36, m.TrackPeek(0))
+	goto L38
+
+L36Back2:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	codepos = 36
+	goto backtrack
+
+L38Back:
+	m.TrackPop(1)
+	m.StackPush(m.TrackPeek(0))
+	m.Uncapture()
+	codepos = 38
+	goto backtrack
+}
@@ -9,6 +9,7 @@ need to write very complex patterns or require compatibility with .NET.
 package binexp
 
 import (
+	"context"
 	"errors"
 	"math"
 	"strconv"
@@ -27,6 +28,26 @@ type Regexp struct {
 	//timeout when trying to find matches
 	MatchTimeout time.Duration
 
+	// the most steps a search can take before it fails with a *MatchStepLimitError, or 0
+	// for no limit.  A step is an instruction of the backtracking program, or a character
+	// for patterns matched in linear time, so unlike the timeout the limit cuts a search
+	// off at the same point on every machine.  A program generated by binexp-gen, which
+	// runs with the Compiled option, takes a step each time it backtracks or jumps back
+	// instead, so it gets further before it's cut off.
+	MatchStepLimit int
+
+	// the most memory, in bytes, the stacks of the backtracking program can take before a
+	// search fails with a *MatchMemoryLimitError, or 0 for no limit.  The stacks grow when
+	// a pattern has to remember many places to backtrack to, such as a loop over a long
+	// input, and are kept for the next search.
+	MatchMemoryLimit int
+
+	// the number of bytes read at a time when searching an io.ReaderAt, or 0 for 64KiB
+	ReaderAtPageSize int
+
+	// the most pages of an io.ReaderAt kept in memory at once by a search, or 0 for 64
+	ReaderAtPageCount int
+
 	// read-only after Compile
 	pattern string       // as passed to Compile
 	options RegexOptions // options
@@ -36,7 +57,8 @@ type Regexp struct {
 	capslist []string       //sorted list of capture group names
 	capsize  int            // size of the capture array
 
-	code *syntax.Code // compiled program
+	code       *syntax.Code // compiled program
+	programKey string       // the ProgramKey of a Regexp compiled with the Compiled option
 
 	// cache of machines for running regexp
 	muRun  sync.Mutex
@@ -52,14 +74,39 @@ func Compile(expr string, opt RegexOptions) (*Regexp, error) {
 		return nil, err
 	}
 
+	return compileTree(expr, opt, tree)
+}
+
+// CompileHex parses a YARA-style hex string, such as "CA ?? ?? [2-4] (E8 | E9) ?? ?? ?? ??",
+// and returns, if successful, a Regexp object that can be used to match against bytes.
+// See syntax.ParseHex for the full syntax.  The ByteRunes option is always set.
+func CompileHex(hex string, opt RegexOptions) (*Regexp, error) {
+	opt = (opt | ByteRunes) &^ (IgnoreCase | IgnorePatternWhitespace)
+	tree, err := syntax.ParseHex(hex, syntax.RegexOptions(opt))
+	if err != nil {
+		return nil, err
+	}
+
+	return compileTree(hex, opt, tree)
+}
+
+// MustCompileHex is like CompileHex but panics if the hex string cannot be parsed.
+func MustCompileHex(hex string, opt RegexOptions) *Regexp {
+	regexp, error := CompileHex(hex, opt)
+	if error != nil {
+		panic(`regexp2: CompileHex(` + quote(hex) + `): ` + error.Error())
+	}
+	return regexp
+}
+
+func compileTree(expr string, opt RegexOptions, tree *syntax.RegexTree) (*Regexp, error) {
 	// translate it to code
 	code, err := syntax.Write(tree)
 	if err != nil {
 		return nil, err
 	}
 
-	// return it
-	return &Regexp{
+	re := &Regexp{
 		pattern:      expr,
 		options:      opt,
 		caps:         code.Caps,
@@ -68,7 +115,13 @@ func Compile(expr string, opt RegexOptions) (*Regexp, error) {
 		capsize:      code.Capsize,
 		code:         code,
 		MatchTimeout: DefaultMatchTimeout,
-	}, nil
+	}
+	if err := re.setCompiled(); err != nil {
+		return nil, err
+	}
+
+	// return it
+	return re, nil
 }
 
 // MustCompile is like Compile but panics if the expression cannot be parsed.
@@ -136,13 +189,17 @@ func (re *Regexp) Debug() bool {
 // us to skip past possible matches at the start of the input (left or right depending on RightToLeft option).
 // Set startAt and count to -1 to go through the whole string
 func (re *Regexp) Replace(input, replacement string, startAt, count int) (string, error) {
-	data, err := syntax.NewReplacerData(replacement, re.caps, re.capsize, re.capnames, syntax.RegexOptions(re.options))
+	data, err := re.replacerData(replacement)
 	if err != nil {
 		return "", err
 	}
-	//TODO: cache ReplacerData
 
-	return replace(re, data, nil, input, startAt, count)
+	return replace(context.Background(), re, data, nil, input, startAt, count)
+}
+
+func (re *Regexp) replacerData(replacement string) (*syntax.ReplacerData, error) {
+	//TODO: cache ReplacerData
+	return syntax.NewReplacerData(replacement, re.caps, re.capsize, re.capnames, syntax.RegexOptions(re.options))
 }
 
 // ReplaceFunc searches the input string and replaces each match found using the string from the evaluator
@@ -150,22 +207,89 @@ func (re *Regexp) Replace(input, replacement string, startAt, count int) (string
 // us to skip past possible matches at the start of the input (left or right depending on RightToLeft option).
 // Set startAt and count to -1 to go through the whole string.
 func (re *Regexp) ReplaceFunc(input string, evaluator MatchEvaluator, startAt, count int) (string, error) {
-	return replace(re, nil, evaluator, input, startAt, count)
+	return replace(context.Background(), re, nil, evaluator, input, startAt, count)
+}
+
+// Split splits the input string into the substrings between matches of the regex.
+// Count will limit the number of substrings returned, the last of them holding the rest of
+// the input, and startAt will allow us to skip past possible matches at the start of the input
+// (left or right depending on RightToLeft option).  Set startAt and count to -1 to go through the
+// whole string.  The text of any capture groups is included in the result, as in .NET.
+func (re *Regexp) Split(input string, count, startAt int) ([]string, error) {
+	return re.splitString(context.Background(), input, count, startAt)
+}
+
+func (re *Regexp) splitString(ctx context.Context, input string, count, startAt int) ([]string, error) {
+	if count < -1 {
+		return nil, errors.New("Count too small")
+	}
+	if startAt > len(input) {
+		return nil, errors.New("startAt must be less than the length of the input string")
+	}
+	if count == 1 {
+		return []string{input}, nil
+	}
+
+	m, err := re.findStringMatch(ctx, input, startAt)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return []string{input}, nil
+	}
+
+	return split(ctx, re, m, count, len(m.text), func(start, end int) string {
+		return string(m.text[start:end])
+	})
+}
+
+// SplitBytes is like Split but splits a byte slice, each byte being matched as a single rune.
+// The returned slices share memory with the input.
+func (re *Regexp) SplitBytes(input []byte, count, startAt int) ([][]byte, error) {
+	return re.splitBytes(context.Background(), input, count, startAt)
+}
+
+func (re *Regexp) splitBytes(ctx context.Context, input []byte, count, startAt int) ([][]byte, error) {
+	if count < -1 {
+		return nil, errors.New("Count too small")
+	}
+	if startAt > len(input) {
+		return nil, errors.New("startAt must be less than the length of the input")
+	}
+	if count == 1 {
+		return [][]byte{input}, nil
+	}
+
+	m, err := re.findBytesMatch(ctx, input, startAt)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return [][]byte{input}, nil
+	}
+
+	return split(ctx, re, m, count, len(input), func(start, end int) []byte {
+		return input[start:end]
+	})
 }
 
 // FindStringMatch searches the input string for a Regexp match
 func (re *Regexp) FindStringMatch(s string) (*Match, error) {
 	// convert string to runes
-	return re.run(false, -1, getRunes(s))
+	return re.run(context.Background(), false, -1, getRunes(s), nil)
 }
 
 // FindRunesMatch searches the input rune slice for a Regexp match
 func (re *Regexp) FindRunesMatch(r []rune) (*Match, error) {
-	return re.run(false, -1, r)
+	return re.run(context.Background(), false, -1, r, nil)
 }
 
 // FindStringMatchStartingAt searches the input string for a Regexp match starting at the startAt index
 func (re *Regexp) FindStringMatchStartingAt(s string, startAt int) (*Match, error) {
+	return re.findStringMatch(context.Background(), s, startAt)
+}
+
+func (re *Regexp) findStringMatch(ctx context.Context, s string, startAt int) (*Match, error) {
 	if startAt > len(s) {
 		return nil, errors.New("startAt must be less than the length of the input string")
 	}
@@ -175,26 +299,35 @@ func (re *Regexp) FindStringMatchStartingAt(s string, startAt int) (*Match, erro
 		return nil, errors.New("startAt must align to the start of a valid rune in the input string")
 	}
 
-	return re.run(false, startAt, r)
+	return re.run(ctx, false, startAt, r, nil)
 }
 
-// FindRunesMatchStartingAt searches the input rune slice for a Regexp match starting at the startAt index
+// FindBytesMatchStartingAt searches the input byte slice for a Regexp match starting at the startAt index.
+// Each byte is matched as a single rune.  If the Regexp was compiled with ByteRunes the
+// bytes are searched in place and the returned Match refers back into b.
 func (re *Regexp) FindBytesMatchStartingAt(b []byte, startAt int) (*Match, error) {
-	runes := make([]rune, len(b))
-	for idx, bi := range b {
-		runes[idx] = rune(bi)
+	return re.findBytesMatch(context.Background(), b, startAt)
+}
+
+func (re *Regexp) findBytesMatch(ctx context.Context, b []byte, startAt int) (*Match, error) {
+	if re.options&ByteRunes != 0 {
+		return re.runBytes(ctx, false, startAt, b)
 	}
-	return re.run(false, startAt, runes)
+	return re.run(ctx, false, startAt, re.bytesToRunes(b), b)
 }
 
 // FindRunesMatchStartingAt searches the input rune slice for a Regexp match starting at the startAt index
 func (re *Regexp) FindRunesMatchStartingAt(r []rune, startAt int) (*Match, error) {
-	return re.run(false, startAt, r)
+	return re.run(context.Background(), false, startAt, r, nil)
 }
 
 // FindNextMatch returns the next match in the same input string as the match parameter.
 // Will return nil if there is no next match or if given a nil match.
 func (re *Regexp) FindNextMatch(m *Match) (*Match, error) {
+	return re.findNextMatch(context.Background(), m)
+}
+
+func (re *Regexp) findNextMatch(ctx context.Context, m *Match) (*Match, error) {
 	if m == nil {
 		return nil, nil
 	}
@@ -203,7 +336,7 @@ func (re *Regexp) FindNextMatch(m *Match) (*Match, error) {
 	// infinite loop
 	startAt := m.textpos
 	if m.Length == 0 {
-		if m.textpos == len(m.text) {
+		if m.textpos == m.textLen() {
 			return nil, nil
 		}
 
@@ -213,40 +346,56 @@ func (re *Regexp) FindNextMatch(m *Match) (*Match, error) {
 			startAt++
 		}
 	}
-	return re.run(false, startAt, m.text)
+	return re.runNext(ctx, startAt, m)
 }
 
-// FindNextMatch returns the next match in the same input string as the match parameter.
-// Will return nil if there is no next match or if given a nil match.
+// FindNextOverlappingMatch returns the next match in the same input string as the match
+// parameter, which may overlap it: the search starts one character past the start of m (or,
+// for RightToLeft, one character before its end), as FindAllStringMatch does for overlapping
+// matches.  Will return nil if there is no next match or if given a nil match.
 func (re *Regexp) FindNextOverlappingMatch(m *Match) (*Match, error) {
+	return re.findNextOverlappingMatch(context.Background(), m)
+}
+
+func (re *Regexp) findNextOverlappingMatch(ctx context.Context, m *Match) (*Match, error) {
 	if m == nil {
 		return nil, nil
 	}
 
-	startAt := m.Index + 1
-	if re.RightToLeft() {
-		startAt = m.Index - 1
+	// the same starting point FindAllStringMatch uses for overlapping matches, which is
+	// always at least one character on
+	startAt := re.nextOverlappingStart(m)
+	if startAt < 0 || startAt > m.textLen() {
+		return nil, nil
 	}
-	// If previous match was empty, advance by one before matching to prevent
-	// infinite loop
-	if m.Length == 0 {
-		if m.textpos == len(m.text) {
-			return nil, nil
-		}
+	return re.runNext(ctx, startAt, m)
+}
 
-		if re.RightToLeft() {
-			startAt--
-		} else {
-			startAt++
-		}
+// runNext searches the same input as the previous match m, starting at startAt
+func (re *Regexp) runNext(ctx context.Context, startAt int, m *Match) (*Match, error) {
+	var next *Match
+	var err error
+	if m.bytes != nil {
+		next, err = re.runBytes(ctx, false, startAt, m.bytes)
+	} else if m.paged != nil {
+		next, err = re.runPaged(ctx, false, startAt, m.paged)
+	} else {
+		next, err = re.run(ctx, false, startAt, m.text, m.src)
+	}
+	if next != nil && m.offset != 0 {
+		next.rebase(m.offset)
 	}
-	return re.run(false, startAt, m.text)
+	return next, rebaseErr(err, m.offset)
 }
 
 // MatchString return true if the string matches the regex
 // error will be set if a timeout occurs
 func (re *Regexp) MatchString(s string) (bool, error) {
-	m, err := re.run(true, -1, getRunes(s))
+	return re.matchString(context.Background(), s)
+}
+
+func (re *Regexp) matchString(ctx context.Context, s string) (bool, error) {
+	m, err := re.run(ctx, true, -1, getRunes(s), nil)
 	if err != nil {
 		return false, err
 	}
@@ -287,7 +436,7 @@ func getRunes(s string) []rune {
 // MatchRunes return true if the runes matches the regex
 // error will be set if a timeout occurs
 func (re *Regexp) MatchRunes(r []rune) (bool, error) {
-	m, err := re.run(true, -1, r)
+	m, err := re.run(context.Background(), true, -1, r, nil)
 	if err != nil {
 		return false, err
 	}
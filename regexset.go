@@ -0,0 +1,390 @@
+package binexp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/polyverse/binexp/syntax"
+)
+
+// RegexSet is a list of patterns that are searched for together, in a single pass over
+// the input, rather than running each one over the whole input in turn.
+//
+// A combined prefilter is used to skip the positions where none of the patterns can match,
+// and at every other position only the patterns that can start there are tried.  Patterns
+// that start with a literal string, or contain one a bounded distance in, are found with an
+// Aho-Corasick automaton over all of those strings, and the rest by the characters they can
+// start with.  Each pattern reports the same matches it would find with FindStringMatch and
+// FindNextMatch on its own.
+type RegexSet struct {
+	// timeout for the whole search when finding matches.  A pattern that runs past it returns
+	// a *MatchTimeoutError, and the scan between them a *SetTimeoutError.
+	MatchTimeout time.Duration
+
+	// the most steps (see Regexp.MatchStepLimit) each pattern can take over the whole
+	// search, or 0 for no limit
+	MatchStepLimit int
+
+	// the most memory (see Regexp.MatchMemoryLimit) each pattern can use, or 0 for no limit
+	MatchMemoryLimit int
+
+	// read-only after CompileSet
+	regexps []*Regexp
+
+	// the patterns without a literal prefix to try at a position holding each character up
+	// to 0xFF, in order.  This includes the patterns without a first character set.
+	byChar [256][]int
+	// the patterns without a first character set, which are tried at every position
+	always []int
+
+	// the literal prefixes and required literals, matched exactly and ignoring case, the
+	// patterns each word of those automata is found in, and how far past a position the
+	// automata have to have read to have found every word that places a pattern there
+	literals  [2]*syntax.AhoCorasick
+	literalOf [2][][]setLiteral
+	lookahead int
+	isLiteral []bool
+}
+
+// setLiteral is a pattern that a word of the automata is found in, and how far from the
+// start of a match the word can be
+type setLiteral struct {
+	pattern              int
+	minOffset, maxOffset int
+}
+
+// maxSetLiteral limits how much of each prefix goes into the automata, which keeps
+// their tables small.  The pattern itself is still matched in full.
+const maxSetLiteral = 8
+
+// CompileSet compiles each of the patterns with the given options and returns, if they all
+// parse, a RegexSet that searches for all of them at once.  RightToLeft isn't supported.
+func CompileSet(patterns []string, opt RegexOptions) (*RegexSet, error) {
+	if opt&RightToLeft != 0 {
+		return nil, errors.New("RightToLeft is not supported in a RegexSet")
+	}
+
+	set := &RegexSet{
+		MatchTimeout: DefaultMatchTimeout,
+		regexps:      make([]*Regexp, len(patterns)),
+	}
+
+	for i, pattern := range patterns {
+		re, err := Compile(pattern, opt)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %v: %w", i, err)
+		}
+		set.regexps[i] = re
+	}
+
+	set.buildPrefilter()
+	return set, nil
+}
+
+// MustCompileSet is like CompileSet but panics if any of the patterns cannot be parsed.
+func MustCompileSet(patterns []string, opt RegexOptions) *RegexSet {
+	set, error := CompileSet(patterns, opt)
+	if error != nil {
+		panic(`regexp2: CompileSet: ` + error.Error())
+	}
+	return set
+}
+
+// buildPrefilter fills in the automata of literals and the table of which of the other
+// patterns can start at each character
+func (s *RegexSet) buildPrefilter() {
+	var words [2][][]rune
+	seen := [2]map[string]int{{}, {}}
+	s.isLiteral = make([]bool, len(s.regexps))
+
+	for i, re := range s.regexps {
+		// a literal prefix is at the start of every match, and a required literal between
+		// its offsets
+		bm, lit := re.code.BmPrefix, setLiteral{pattern: i}
+		if bm == nil && re.code.Literal != nil {
+			bm, lit.minOffset, lit.maxOffset = re.code.Literal.BmPrefix, re.code.Literal.MinOffset, re.code.Literal.MaxOffset
+		}
+		if bm != nil {
+			k := 0
+			if bm.CaseInsensitive() {
+				k = 1
+			}
+			word := bm.Runes()
+			if len(word) > maxSetLiteral {
+				word = word[:maxSetLiteral]
+			}
+
+			// patterns sharing a prefix share the word
+			w, ok := seen[k][string(word)]
+			if !ok {
+				w = len(words[k])
+				seen[k][string(word)] = w
+				words[k] = append(words[k], word)
+				s.literalOf[k] = append(s.literalOf[k], nil)
+			}
+			s.literalOf[k][w] = append(s.literalOf[k][w], lit)
+			s.lookahead = max(s.lookahead, lit.maxOffset+len(word))
+			s.isLiteral[i] = true
+			continue
+		}
+
+		fc := re.code.FcPrefix
+		if fc == nil {
+			s.always = append(s.always, i)
+			for ch := range s.byChar {
+				s.byChar[ch] = append(s.byChar[ch], i)
+			}
+			continue
+		}
+		for ch := range s.byChar {
+			if canStartWith(fc, rune(ch)) {
+				s.byChar[ch] = append(s.byChar[ch], i)
+			}
+		}
+	}
+
+	for k := range words {
+		if len(words[k]) > 0 {
+			s.literals[k] = syntax.NewAhoCorasick(words[k], k == 1)
+		}
+	}
+}
+
+// canStartWith returns true if ch is one of the first characters in fc
+func canStartWith(fc *syntax.Prefix, ch rune) bool {
+	if fc.CaseInsensitive {
+		ch = unicode.ToLower(ch)
+	}
+	return fc.PrefixSet.CharIn(ch)
+}
+
+// Len returns the number of patterns in the set
+func (s *RegexSet) Len() int {
+	return len(s.regexps)
+}
+
+// Regexp returns the compiled pattern at index i of the set.  The Regexp of every
+// Match reported by the set is one of these.
+func (s *RegexSet) Regexp(i int) *Regexp {
+	return s.regexps[i]
+}
+
+// FindStringMatches searches the input string for every pattern in the set, calling fn
+// with the index of the pattern and the match for each match found until it returns false.
+// Matches are reported in order of where they start, and by pattern index for matches
+// starting at the same place.
+func (s *RegexSet) FindStringMatches(input string, fn func(pattern int, m *Match) bool) error {
+	return s.each(context.Background(), getRunes(input), nil, false, fn)
+}
+
+// FindBytesMatches is like FindStringMatches but searches a byte slice, each byte being
+// matched as a single rune.  If the set was compiled with ByteRunes the bytes are
+// searched in place.
+func (s *RegexSet) FindBytesMatches(b []byte, fn func(pattern int, m *Match) bool) error {
+	return s.findBytesMatches(context.Background(), b, fn)
+}
+
+func (s *RegexSet) findBytesMatches(ctx context.Context, b []byte, fn func(pattern int, m *Match) bool) error {
+	if len(s.regexps) == 0 {
+		return nil
+	}
+	re := s.regexps[0]
+	return s.each(ctx, re.bytesToRunes(b), b, false, fn)
+}
+
+// MatchString returns the indexes, in ascending order, of the patterns that match
+// somewhere in the input string.  Each pattern is only searched for until it's found.
+func (s *RegexSet) MatchString(input string) ([]int, error) {
+	return s.matched(context.Background(), getRunes(input), nil)
+}
+
+// MatchBytes is like MatchString but searches a byte slice, in the same way as FindBytesMatches
+func (s *RegexSet) MatchBytes(b []byte) ([]int, error) {
+	return s.matchBytes(context.Background(), b)
+}
+
+func (s *RegexSet) matchBytes(ctx context.Context, b []byte) ([]int, error) {
+	if len(s.regexps) == 0 {
+		return nil, nil
+	}
+	re := s.regexps[0]
+	return s.matched(ctx, re.bytesToRunes(b), b)
+}
+
+func (s *RegexSet) matched(ctx context.Context, text []rune, b []byte) ([]int, error) {
+	var matched []int
+	err := s.each(ctx, text, b, true, func(pattern int, m *Match) bool {
+		matched = append(matched, pattern)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(matched)
+	return matched, nil
+}
+
+// each makes a single pass over the input, which is text, or b when text is nil, calling
+// fn with each match until it returns false.  If first is true each pattern is dropped from
+// the search once it has matched, and the matches passed to fn are only valid during the call.
+func (s *RegexSet) each(ctx context.Context, text []rune, b []byte, first bool, fn func(pattern int, m *Match) bool) error {
+	if len(s.regexps) == 0 {
+		return nil
+	}
+
+	textend := len(text)
+	if text == nil {
+		textend = len(b)
+	}
+
+	// one runner per pattern for the whole search
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	runners := make([]*runner, len(s.regexps))
+	for i, re := range s.regexps {
+		r := re.getRunner()
+		defer re.putRunner(r)
+
+		r.setInput(text, b)
+		r.ctx = ctx
+		r.setLimits(s.MatchTimeout, s.MatchStepLimit, s.MatchMemoryLimit)
+		r.startTimeoutWatch()
+		runners[i] = r
+	}
+
+	// next[i] is where pattern i's search continues from: the end of its last match,
+	// or one past it if that was empty.  A pattern that can't match any more has it
+	// set past the end of the text.
+	next := make([]int, len(s.regexps))
+	live := len(s.regexps)
+	done := func(i int) {
+		next[i] = textend + 1
+		live--
+	}
+
+	// the patterns whose literal has been found, by where a match containing it could
+	// start, in a ring as long as the lookahead.  The automata are fed far enough ahead of
+	// pos to have found every literal that places a pattern at pos.
+	var found [][]int
+	if s.lookahead > 0 {
+		found = make([][]int, s.lookahead)
+	}
+	var states [2]int
+	scanned := 0
+	var merged []int
+
+	// the scan for places to try the patterns isn't a step of any of them, so it only checks
+	// the context and the timeout of the whole search, every so often
+	timed := s.MatchTimeout != time.Duration(math.MaxInt64)
+	var timeoutAt time.Time
+	if timed {
+		timeoutAt = time.Now().Add(s.MatchTimeout)
+	}
+
+	for pos := 0; pos <= textend && live > 0; pos++ {
+		if pos%timeoutCheckFrequency == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if timed && !time.Now().Before(timeoutAt) {
+				return &SetTimeoutError{Timeout: s.MatchTimeout, Pos: pos}
+			}
+		}
+
+		for ; found != nil && scanned < textend && scanned < pos+s.lookahead; scanned++ {
+			ch := runners[0].charAt(scanned)
+			for k, a := range s.literals {
+				if a == nil {
+					continue
+				}
+				states[k] = a.Next(states[k], ch)
+				for _, w := range a.Matches(states[k]) {
+					start := scanned + 1 - a.WordLen(w)
+					for _, l := range s.literalOf[k][w] {
+						for p := max(start-l.maxOffset, 0); p <= start-l.minOffset; p++ {
+							found[p%s.lookahead] = append(found[p%s.lookahead], l.pattern)
+						}
+					}
+				}
+			}
+		}
+
+		var candidates []int
+		if pos == textend {
+			candidates = s.always
+		} else if ch := runners[0].charAt(pos); ch <= 0xff {
+			candidates = s.byChar[ch]
+		} else {
+			candidates = s.wideCandidates(ch)
+		}
+
+		if found != nil && len(found[pos%s.lookahead]) > 0 {
+			// a pattern can be placed here by more than one of its literals
+			slot := pos % s.lookahead
+			merged = append(append(merged[:0], candidates...), found[slot]...)
+			sort.Ints(merged)
+			candidates = slices.Compact(merged)
+			found[slot] = found[slot][:0]
+		}
+
+		for _, i := range candidates {
+			if pos < next[i] {
+				continue
+			}
+
+			anchors := s.regexps[i].code.Anchors
+			if (anchors&syntax.AnchorBeginning != 0 && pos > 0) || (anchors&syntax.AnchorStart != 0 && pos > next[i]) {
+				// it can never match again
+				done(i)
+				continue
+			}
+			if (anchors&syntax.AnchorEndZ != 0 && pos < textend-1) || (anchors&syntax.AnchorEnd != 0 && pos < textend) {
+				continue
+			}
+
+			m, err := runners[i].matchAt(pos, next[i], first)
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				continue
+			}
+
+			if !fn(i, m) {
+				return nil
+			}
+
+			if first || (m.Length == 0 && m.textpos == textend) {
+				done(i)
+			} else if m.Length == 0 {
+				next[i] = m.textpos + 1
+			} else {
+				next[i] = m.textpos
+			}
+		}
+	}
+
+	return nil
+}
+
+// wideCandidates returns the patterns without a literal prefix that can start with ch,
+// which is past 0xFF
+func (s *RegexSet) wideCandidates(ch rune) []int {
+	var candidates []int
+	for i, re := range s.regexps {
+		if s.isLiteral[i] {
+			continue
+		}
+		if fc := re.code.FcPrefix; fc == nil || canStartWith(fc, ch) {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,228 @@
+package binexp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type setMatch struct {
+	pattern       int
+	index, length int
+	text          string
+}
+
+// separateMatches finds the matches of each pattern by running it on its own, in the order
+// the set reports them
+func separateMatches(t *testing.T, patterns []string, opt RegexOptions, input string) []setMatch {
+	var want []setMatch
+	for i, p := range patterns {
+		re := MustCompile(p, opt)
+		m, err := re.FindStringMatch(input)
+		for ; m != nil; m, err = re.FindNextMatch(m) {
+			want = append(want, setMatch{i, m.Index, m.Length, m.String()})
+		}
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+	}
+	sort.SliceStable(want, func(i, j int) bool {
+		if want[i].index != want[j].index {
+			return want[i].index < want[j].index
+		}
+		return want[i].pattern < want[j].pattern
+	})
+	return want
+}
+
+func TestRegexSetMatchesSeparateRuns(t *testing.T) {
+	for _, test := range []struct {
+		patterns []string
+		opt      RegexOptions
+		input    string
+	}{
+		{[]string{"a+", "b", "ab"}, None, "xaabxabbab"},
+		{[]string{"a*", "b"}, None, "bab"},
+		{[]string{`\Aa`, `b\z`, `\Gx`}, None, "axxab"},
+		{[]string{`\Ga`, `a`}, None, "aaxa"},
+		{[]string{"cat", "CAT", "dog"}, IgnoreCase, "Cat and DOG and cAt"},
+		{[]string{"(?<=a)b", `\bword\b`, `$`}, Multiline, "ab words word\nab"},
+		{[]string{"日本", "本語?", "."}, None, "日本語です"},
+		{[]string{`\xe8.{4}`, `\xc3`, `\x90+`}, ByteRunes, "\x90\x90\xe8\x01\x02\x03\x04\xc3"},
+		{[]string{"x"}, None, ""},
+		{[]string{"", "a?"}, None, "ab"},
+		{[]string{"he", "she", "his", "hers", "h.", "s"}, None, "ushers and his shears"},
+		{[]string{"(?i)she", "she", "(?i)HE", "shears?"}, None, "SHE sells sea shells to shears"},
+		{[]string{"日本語", "本", "語で?"}, None, "日本語で日本"},
+		{[]string{"abcdefghijklmnop", "abcdefghij", "fgh", `\w+`}, None, "xabcdefghijklmnopq abcdefghijk"},
+		{[]string{`\x55\x48\x89\xe5`, `\x48\x89`, `\x89\xe5\xe8`}, ByteRunes, "\x55\x48\x89\xe5\xe8\x55\x48\x89"},
+		{[]string{`\d{1,3}-abc`, `.b.`, `[a-z]+@example`, `\w{2}(?i:XY)`}, None, "12-abc 1234-abc -abc x@example bob abxy axy"},
+		{[]string{`.{4}\xe8..`, `[\x00-\xff]{2,6}\xe8\xff`, `\xe8`}, ByteRunes | Singleline, "\x90\n\x90\x90\xe8\xff\x01\xe8\xff\xe8"},
+	} {
+		set := MustCompileSet(test.patterns, test.opt)
+
+		var got []setMatch
+		err := set.FindStringMatches(test.input, func(pattern int, m *Match) bool {
+			if m.regex != set.Regexp(pattern) {
+				t.Errorf("%q: match from the wrong regexp", test.patterns)
+			}
+			got = append(got, setMatch{pattern, m.Index, m.Length, m.String()})
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+
+		if want := separateMatches(t, test.patterns, test.opt, test.input); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q on %q:\nwanted %v\n   got %v", test.patterns, test.input, want, got)
+		}
+	}
+}
+
+func TestRegexSetBytes(t *testing.T) {
+	set := MustCompileSet([]string{`\xe8.{4}`, `\xc3`, `\x55\x48\x89\xe5`}, ByteRunes)
+	code := []byte{0x55, 0x48, 0x89, 0xe5, 0xe8, 1, 2, 3, 4, 0xc3, 0xe8, 0xc3}
+
+	var got []string
+	err := set.FindBytesMatches(code, func(pattern int, m *Match) bool {
+		got = append(got, fmt.Sprintf("%v@%v+%v", pattern, m.Index, m.Length))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if want := []string{"2@0+4", "0@4+5", "1@9+1", "1@11+1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %v, got %v", want, got)
+	}
+
+	matched, err := set.MatchBytes(code[4:])
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(matched, want) {
+		t.Fatalf("wanted %v, got %v", want, matched)
+	}
+}
+
+func TestRegexSetMatchString(t *testing.T) {
+	set := MustCompileSet([]string{"foo", "bar", `^baz`, `\d+$`}, None)
+	for _, test := range []struct {
+		input string
+		want  []int
+	}{
+		{"bar foo 12", []int{0, 1, 3}},
+		{"baz", []int{2}},
+		{"a baz", nil},
+		{"", nil},
+	} {
+		matched, err := set.MatchString(test.input)
+		if err != nil {
+			t.Fatalf("Unexpected err: %v", err)
+		}
+		if !reflect.DeepEqual(matched, test.want) {
+			t.Errorf("%q: wanted %v, got %v", test.input, test.want, matched)
+		}
+	}
+}
+
+func TestRegexSetStop(t *testing.T) {
+	set := MustCompileSet([]string{"a", "b"}, None)
+	count := 0
+	err := set.FindStringMatches(strings.Repeat("ab", 10), func(pattern int, m *Match) bool {
+		count++
+		return count < 3
+	})
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("wanted 3 matches before stopping, got %v", count)
+	}
+}
+
+func TestCompileSetErrors(t *testing.T) {
+	if _, err := CompileSet([]string{"a", "(b"}, None); err == nil || !strings.HasPrefix(err.Error(), "pattern 1: ") {
+		t.Fatalf("wanted an error for pattern 1, got %v", err)
+	}
+	if _, err := CompileSet([]string{"a"}, RightToLeft); err == nil {
+		t.Fatal("wanted an error for RightToLeft")
+	}
+}
+
+func TestRegexSetRequiredLiterals(t *testing.T) {
+	// patterns whose literal is a bounded distance in go in the automaton too
+	set := MustCompileSet([]string{`\d{1,3}-abc`, `.b.`, `\w{2}(?i:XY)`, `[a-z]+@example`}, None)
+	if want := []bool{true, true, true, false}; !reflect.DeepEqual(set.isLiteral, want) {
+		t.Fatalf("wanted the patterns in the automaton to be %v, got %v", want, set.isLiteral)
+	}
+}
+
+func TestRegexSetManyLiterals(t *testing.T) {
+	// literals drawn from a small alphabet so they overlap and share suffixes
+	rnd := rand.New(rand.NewSource(1))
+	word := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abc"[rnd.Intn(3)]
+		}
+		return string(b)
+	}
+
+	// half of them with their literal a little way in
+	var patterns []string
+	for i := 0; i < 60; i++ {
+		p := word(1+rnd.Intn(10)) + `.?` + word(1)
+		if i%2 == 1 {
+			p = `[ab]{0,2}` + p
+		}
+		patterns = append(patterns, p)
+	}
+	input := word(2000)
+
+	set := MustCompileSet(patterns, None)
+	for i := range patterns {
+		if !set.isLiteral[i] {
+			t.Fatalf("%q wasn't put in the automaton", patterns[i])
+		}
+	}
+
+	var got []setMatch
+	err := set.FindStringMatches(input, func(pattern int, m *Match) bool {
+		got = append(got, setMatch{pattern, m.Index, m.Length, m.String()})
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if want := separateMatches(t, patterns, None, input); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wanted %v matches, got %v", len(want), len(got))
+	}
+}
+
+func TestRegexSetLimits(t *testing.T) {
+	// the scan between the patterns isn't counted against the step limit of any of them
+	input := strings.Repeat("b", 1000) + "a"
+	set := MustCompileSet([]string{"zzz", "a"}, 0)
+	set.MatchStepLimit = 100
+	if got, err := set.MatchString(input); err != nil || fmt.Sprint(got) != "[1]" {
+		t.Errorf("Expected pattern 1 to match, got %v, %v", got, err)
+	}
+	re := MustCompile("zzz", 0)
+	re.MatchStepLimit = 100
+	if _, err := re.MatchString(input); err != nil {
+		t.Errorf("Unexpected err: %v", err)
+	}
+
+	// but it's still cut off by the timeout
+	set.MatchTimeout = time.Nanosecond
+	_, err := set.MatchString(strings.Repeat("b", 10000))
+	var timeoutErr *SetTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("Expected a *SetTimeoutError, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package binexp
 
 import (
 	"bytes"
+	"context"
 	"errors"
 
 	"github.com/polyverse/binexp/syntax"
@@ -28,7 +29,7 @@ type MatchEvaluator func(Match) string
 // with no matches, the input string is returned unchanged.
 // The right-to-left case is split out because StringBuilder
 // doesn't handle right-to-left string building directly very well.
-func replace(regex *Regexp, data *syntax.ReplacerData, evaluator MatchEvaluator, input string, startAt, count int) (string, error) {
+func replace(ctx context.Context, regex *Regexp, data *syntax.ReplacerData, evaluator MatchEvaluator, input string, startAt, count int) (string, error) {
 	if count < -1 {
 		return "", errors.New("Count too small")
 	}
@@ -36,7 +37,7 @@ func replace(regex *Regexp, data *syntax.ReplacerData, evaluator MatchEvaluator,
 		return "", nil
 	}
 
-	m, err := regex.FindStringMatchStartingAt(input, startAt)
+	m, err := regex.findStringMatch(ctx, input, startAt)
 
 	if err != nil {
 		return "", err
@@ -65,9 +66,9 @@ func replace(regex *Regexp, data *syntax.ReplacerData, evaluator MatchEvaluator,
 			if count == 0 {
 				break
 			}
-			m, err = regex.FindNextMatch(m)
+			m, err = regex.findNextMatch(ctx, m)
 			if err != nil {
-				return "", nil
+				return "", err
 			}
 		}
 
@@ -93,9 +94,9 @@ func replace(regex *Regexp, data *syntax.ReplacerData, evaluator MatchEvaluator,
 			if count == 0 {
 				break
 			}
-			m, err = regex.FindNextMatch(m)
+			m, err = regex.findNextMatch(ctx, m)
 			if err != nil {
-				return "", nil
+				return "", err
 			}
 		}
 
@@ -175,3 +176,70 @@ func replacementImplRTL(data *syntax.ReplacerData, al *[]string, m *Match) {
 
 	*al = l
 }
+
+// split does the work of Split and SplitBytes once they've found the first match, m, in
+// input textLen characters long.  It finds the rest of the matches itself, up to count-1
+// of them in all (or every one when count is -1), and cuts the input around them with
+// piece, which returns the input between two indexes as a string or a byte slice, adding
+// the groups that took part in each match after the piece before it.  Right to left the
+// matches are found back to front, so the pieces are collected in that order and reversed.
+func split[T any](ctx context.Context, regex *Regexp, m *Match, count, textLen int, piece func(start, end int) T) ([]T, error) {
+	var al []T
+	var err error
+
+	count--
+
+	if !regex.RightToLeft() {
+		prevat := 0
+		for m != nil {
+			al = append(al, piece(prevat, m.Index))
+			prevat = m.Index + m.Length
+
+			// add all matched capture groups to the list
+			for _, g := range m.Groups()[1:] {
+				if len(g.Captures) > 0 {
+					al = append(al, piece(g.Index, g.Index+g.Length))
+				}
+			}
+
+			count--
+			if count == 0 {
+				break
+			}
+			if m, err = regex.findNextMatch(ctx, m); err != nil {
+				return nil, err
+			}
+		}
+
+		al = append(al, piece(prevat, textLen))
+	} else {
+		prevat := textLen
+		for m != nil {
+			al = append(al, piece(m.Index+m.Length, prevat))
+			prevat = m.Index
+
+			// add all matched capture groups to the list
+			for _, g := range m.Groups()[1:] {
+				if len(g.Captures) > 0 {
+					al = append(al, piece(g.Index, g.Index+g.Length))
+				}
+			}
+
+			count--
+			if count == 0 {
+				break
+			}
+			if m, err = regex.findNextMatch(ctx, m); err != nil {
+				return nil, err
+			}
+		}
+
+		al = append(al, piece(0, prevat))
+
+		for i, j := 0, len(al)-1; i < j; i, j = i+1, j-1 {
+			al[i], al[j] = al[j], al[i]
+		}
+	}
+
+	return al, nil
+}
@@ -1,6 +1,8 @@
 package binexp
 
 import (
+	"bytes"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -170,3 +172,98 @@ func TestReplace_NestedGroups(t *testing.T) {
 		t.Fatalf("Wrong result: %s", got)
 	}
 }
+
+func TestSplit_Basic(t *testing.T) {
+	re := MustCompile(`-`, None)
+	res, err := re.Split("a-b--c", -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "", "c"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+}
+
+func TestSplit_Groups(t *testing.T) {
+	// captured text is included, as in .NET
+	re := MustCompile(`(-)|(\+)`, None)
+	res, err := re.Split("a-b+c", -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "-", "b", "+", "c"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+}
+
+func TestSplit_CountAndStart(t *testing.T) {
+	re := MustCompile(`,`, None)
+	res, err := re.Split("a,b,c,d", 2, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b,c,d"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+
+	res, err = re.Split("a,b,c,d", -1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a,b", "c", "d"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+
+	res, err = re.Split("a,b", 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a,b"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+
+	if _, err := re.Split("a,b", -2, -1); err == nil {
+		t.Fatal("Expected an error for count -2")
+	}
+	for _, count := range []int{-1, 1} {
+		if _, err := re.Split("a,b", count, 4); err == nil {
+			t.Fatalf("Expected an error for startAt past the end with count %v", count)
+		}
+		if _, err := re.SplitBytes([]byte("a,b"), count, 4); err == nil {
+			t.Fatalf("Expected an error for startAt past the end of the bytes with count %v", count)
+		}
+	}
+}
+
+func TestSplit_RightToLeft(t *testing.T) {
+	re := MustCompile(`(,)`, RightToLeft)
+	res, err := re.Split("a,b,c,d", 3, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a,b", ",", "c", ",", "d"}; !reflect.DeepEqual(want, res) {
+		t.Fatalf("Wrong result: %q", res)
+	}
+}
+
+func TestSplitBytes(t *testing.T) {
+	data := []byte{0x01, 0xcc, 0xcc, 0x02, 0x03, 0xcc, 0xcc, 0xff}
+	for _, opt := range []RegexOptions{None, ByteRunes} {
+		res, err := MustCompile(`\xcc+`, opt).SplitBytes(data, -1, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := [][]byte{{0x01}, {0x02, 0x03}, {0xff}}; !reflect.DeepEqual(want, res) {
+			t.Fatalf("opt %v: wrong result: %x", opt, res)
+		}
+	}
+
+	// the groups come back as raw bytes too
+	res, err := MustCompile(`(\xcc)\xcc`, None).SplitBytes(data, -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 5 || !bytes.Equal(res[1], []byte{0xcc}) {
+		t.Fatalf("Wrong result: %x", res)
+	}
+}
@@ -2,6 +2,7 @@ package binexp
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -19,8 +20,12 @@ type runner struct {
 
 	runtextstart int // starting point for search
 
-	runtext    []rune // text to search
-	runtextpos int    // current position in text
+	runtext    []rune     // text to search
+	runbytes   []byte     // text to search when matching raw bytes
+	bytemode   bool       // true if runbytes holds the text instead of runtext
+	runpaged   *pagedText // text to search when reading from an io.ReaderAt
+	runsrc     []byte     // the byte slice runtext was converted from, if it was
+	runtextpos int        // current position in text
 	runtextend int
 
 	// The backtracking stack.  Opcodes use this to store data regarding
@@ -58,15 +63,29 @@ type runner struct {
 
 	runmatch *Match // result object
 
+	ctx                 context.Context // cancels the search, checked along with the timeout
 	ignoreTimeout       bool
 	timeout             time.Duration // timeout in milliseconds (needed for actual)
 	timeoutChecksToSkip int
 	timeoutAt           time.Time
+	stepLimit           int // the most steps the search can take, if > 0
+	steps               int
+	memoryLimit         int   // the most bytes the stacks can take, if > 0
+	stackErr            error // set when the stacks would have grown past memoryLimit
+	peakStack           int   // the most ints the stacks have held during the search
 
 	operator        syntax.InstOp
 	codepos         int
 	rightToLeft     bool
 	caseInsensitive bool
+
+	// for patterns matched with their NFA
+	pike *pikeVM
+	dfa  *lazyDFA
+
+	// the generated program that runs in place of execute, for patterns compiled with
+	// the Compiled option
+	program func(*Machine) error
 }
 
 // run searches for matches and can continue from the previous match
@@ -74,7 +93,54 @@ type runner struct {
 // quick is usually false, but can be true to not return matches, just put it in caches
 // textstart is -1 to start at the "beginning" (depending on Right-To-Left), otherwise an index in input
 // input is the string to search for our regex pattern
-func (re *Regexp) run(quick bool, textstart int, input []rune) (*Match, error) {
+// src is the byte slice input was converted from, one rune per byte, or nil
+func (re *Regexp) run(ctx context.Context, quick bool, textstart int, input []rune, src []byte) (*Match, error) {
+
+	// get a cached runner
+	runner := re.getRunner()
+	defer re.putRunner(runner)
+
+	if textstart < 0 {
+		if re.RightToLeft() {
+			textstart = len(input)
+		} else {
+			textstart = 0
+		}
+	}
+
+	runner.runtext = input
+	runner.runbytes = nil
+	runner.bytemode = false
+	runner.runpaged = nil
+	runner.runsrc = src
+	runner.runtextend = len(input)
+	runner.ctx = ctx
+
+	return runner.scan(textstart, quick, re.MatchTimeout)
+}
+
+// setInput points the runner at the input, which is text, or b when text is nil.  When both
+// are given, text was converted from b one rune per byte.
+func (r *runner) setInput(text []rune, b []byte) {
+	r.runtext = text
+	r.runpaged = nil
+	r.bytemode = text == nil
+	if r.bytemode {
+		if b == nil {
+			// the matches are still marked as being against bytes
+			b = []byte{}
+		}
+		r.runbytes, r.runsrc = b, nil
+		r.runtextend = len(b)
+	} else {
+		r.runbytes, r.runsrc = nil, b
+		r.runtextend = len(text)
+	}
+}
+
+// runBytes is like run but searches the byte slice directly, each byte
+// being treated as a single rune.  The input is never copied.
+func (re *Regexp) runBytes(ctx context.Context, quick bool, textstart int, input []byte) (*Match, error) {
 
 	// get a cached runner
 	runner := re.getRunner()
@@ -88,7 +154,45 @@ func (re *Regexp) run(quick bool, textstart int, input []rune) (*Match, error) {
 		}
 	}
 
-	return runner.scan(input, textstart, quick, re.MatchTimeout)
+	runner.runtext = nil
+	runner.runbytes = input
+	runner.bytemode = true
+	runner.runpaged = nil
+	runner.runsrc = nil
+	runner.runtextend = len(input)
+	runner.ctx = ctx
+
+	return runner.scan(textstart, quick, re.MatchTimeout)
+}
+
+// runPaged is like runBytes but reads the text from a paged io.ReaderAt as it's needed.
+func (re *Regexp) runPaged(ctx context.Context, quick bool, textstart int, input *pagedText) (*Match, error) {
+
+	// get a cached runner
+	runner := re.getRunner()
+	defer re.putRunner(runner)
+
+	if textstart < 0 {
+		if re.RightToLeft() {
+			textstart = input.size
+		} else {
+			textstart = 0
+		}
+	}
+
+	runner.runtext = nil
+	runner.runbytes = nil
+	runner.bytemode = false
+	runner.runpaged = input
+	runner.runsrc = nil
+	runner.runtextend = input.size
+	runner.ctx = ctx
+
+	m, err := runner.scan(textstart, quick, re.MatchTimeout)
+	if input.err != nil {
+		return nil, input.err
+	}
+	return m, err
 }
 
 // Scans the string to find the first match. Uses the Match object
@@ -101,12 +205,11 @@ func (re *Regexp) run(quick bool, textstart int, input []rune) (*Match, error) {
 // The optimizer can compute a set of candidate starting characters,
 // and we could use a separate method Skip() that will quickly scan past
 // any characters that we know can't match.
-func (r *runner) scan(rt []rune, textstart int, quick bool, timeout time.Duration) (*Match, error) {
-	r.timeout = timeout
-	r.ignoreTimeout = (time.Duration(math.MaxInt64) == timeout)
+//
+// The text to search (runtext or runbytes), runtextend and ctx must already be set.
+func (r *runner) scan(textstart int, quick bool, timeout time.Duration) (*Match, error) {
+	r.setLimits(timeout, r.re.MatchStepLimit, r.re.MatchMemoryLimit)
 	r.runtextstart = textstart
-	r.runtext = rt
-	r.runtextend = len(rt)
 
 	stoppos := r.runtextend
 	bump := 1
@@ -120,6 +223,13 @@ func (r *runner) scan(rt []rune, textstart int, quick bool, timeout time.Duratio
 	initted := false
 
 	r.startTimeoutWatch()
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if r.code.NFA != nil && !r.re.Debug() {
+		return r.scanNFA(quick)
+	}
+
 	for {
 		if r.re.Debug() {
 			//fmt.Printf("\nSearch content: %v\n", string(r.runtext))
@@ -172,7 +282,43 @@ func (r *runner) scan(rt []rune, textstart int, quick bool, timeout time.Duratio
 	// We never get here
 }
 
+// matchAt runs the program once with the match starting at pos, rather than looking for
+// the first place a match can start like scan does.  The text to search and runtextend must
+// already be set, along with the timeout and context, and textstart is where \G matches.  It returns nil
+// if there's no match starting at pos.
+func (r *runner) matchAt(pos, textstart int, quick bool) (*Match, error) {
+	r.runtextstart = textstart
+	r.runtextpos = pos
+	r.peakStack = 0
+
+	if err := r.checkTimeout(); err != nil {
+		return nil, err
+	}
+
+	if r.code.NFA != nil && !r.re.Debug() {
+		return r.pikeSearch(pos, true, quick)
+	}
+
+	r.initMatch()
+	if err := r.execute(); err != nil {
+		return nil, err
+	}
+
+	if r.runmatch.matchcount[0] > 0 {
+		return r.tidyMatch(quick), nil
+	}
+
+	// reset state for another go
+	r.runtrackpos = len(r.runtrack)
+	r.runstackpos = len(r.runstack)
+	r.runcrawlpos = len(r.runcrawl)
+	return nil, nil
+}
+
 func (r *runner) execute() error {
+	if r.program != nil && !r.re.Debug() {
+		return r.program((*Machine)(r))
+	}
 
 	r.goTo(0)
 
@@ -894,12 +1040,37 @@ func (r *runner) execute() error {
 
 // increase the size of stack and track storage
 func (r *runner) ensureStorage() {
+	if used := len(r.runtrack) - r.runtrackpos + len(r.runstack) - r.runstackpos + len(r.runcrawl) - r.runcrawlpos; used > r.peakStack {
+		r.peakStack = used
+	}
 	if r.runstackpos < r.runtrackcount*4 {
-		doubleIntSlice(&r.runstack, &r.runstackpos)
+		r.grow(&r.runstack, &r.runstackpos)
 	}
 	if r.runtrackpos < r.runtrackcount*4 {
-		doubleIntSlice(&r.runtrack, &r.runtrackpos)
+		r.grow(&r.runtrack, &r.runtrackpos)
+	}
+}
+
+// intSize is the size of an int on the stacks, in bytes
+const intSize = strconv.IntSize / 8
+
+// grow doubles the size of one of the stacks, unless that would take them past the memory
+// limit, in which case it leaves them as they are and notes the error for checkTimeout to
+// return.  It returns false if the stack didn't grow.
+func (r *runner) grow(s *[]int, pos *int) bool {
+	if r.memoryLimit > 0 && (len(r.runtrack)+len(r.runstack)+len(r.runcrawl)+len(*s))*intSize > r.memoryLimit {
+		if r.stackErr == nil {
+			r.stackErr = &MatchMemoryLimitError{
+				Pattern: r.re.pattern,
+				Limit:   r.memoryLimit,
+				Start:   r.runtextstart,
+				Pos:     r.runtextpos,
+			}
+		}
+		return false
 	}
+	doubleIntSlice(s, pos)
+	return true
 }
 
 func doubleIntSlice(s *[]int, pos *int) {
@@ -913,8 +1084,9 @@ func doubleIntSlice(s *[]int, pos *int) {
 
 // Save a number on the longjump unrolling stack
 func (r *runner) crawl(i int) {
-	if r.runcrawlpos == 0 {
-		doubleIntSlice(&r.runcrawl, &r.runcrawlpos)
+	if r.runcrawlpos == 0 && !r.grow(&r.runcrawl, &r.runcrawlpos) {
+		// the search stops at the next instruction, so there's no need to keep it
+		return
 	}
 	r.runcrawlpos--
 	r.runcrawl[r.runcrawlpos] = i
@@ -1136,9 +1308,9 @@ func (r *runner) forwardcharnext() rune {
 	var ch rune
 	if r.rightToLeft {
 		r.runtextpos--
-		ch = r.runtext[r.runtextpos]
+		ch = r.charAt(r.runtextpos)
 	} else {
-		ch = r.runtext[r.runtextpos]
+		ch = r.charAt(r.runtextpos)
 		r.runtextpos++
 	}
 
@@ -1170,7 +1342,7 @@ func (r *runner) runematch(str []rune) bool {
 		for c != 0 {
 			c--
 			pos--
-			if str[c] != r.runtext[pos] {
+			if str[c] != r.charAt(pos) {
 				return false
 			}
 		}
@@ -1178,7 +1350,7 @@ func (r *runner) runematch(str []rune) bool {
 		for c != 0 {
 			c--
 			pos--
-			if str[c] != unicode.ToLower(r.runtext[pos]) {
+			if str[c] != unicode.ToLower(r.charAt(pos)) {
 				return false
 			}
 		}
@@ -1218,7 +1390,7 @@ func (r *runner) refmatch(index, len int) bool {
 			c--
 			cmpos--
 			pos--
-			if r.runtext[cmpos] != r.runtext[pos] {
+			if r.charAt(cmpos) != r.charAt(pos) {
 				return false
 			}
 
@@ -1229,7 +1401,7 @@ func (r *runner) refmatch(index, len int) bool {
 			cmpos--
 			pos--
 
-			if unicode.ToLower(r.runtext[cmpos]) != unicode.ToLower(r.runtext[pos]) {
+			if unicode.ToLower(r.charAt(cmpos)) != unicode.ToLower(r.charAt(pos)) {
 				return false
 			}
 		}
@@ -1253,6 +1425,12 @@ func (r *runner) backwardnext() {
 }
 
 func (r *runner) charAt(j int) rune {
+	if r.bytemode {
+		return rune(r.runbytes[j])
+	}
+	if r.runpaged != nil {
+		return rune(r.runpaged.at(j))
+	}
 	return r.runtext[j]
 }
 
@@ -1284,12 +1462,22 @@ func (r *runner) findFirstChar() bool {
 		}
 
 		if r.code.BmPrefix != nil {
+			if r.bytemode {
+				return r.code.BmPrefix.IsMatchBytes(r.runbytes, r.runtextpos, 0, r.runtextend)
+			}
+			if r.runpaged != nil {
+				l := r.code.BmPrefix.Len()
+				if r.code.RightToLeft {
+					return r.runtextpos >= l && r.code.BmPrefix.IsMatchBytes(r.runpaged.window(r.runtextpos-l, r.runtextpos), l, 0, l)
+				}
+				return r.runtextend-r.runtextpos >= l && r.code.BmPrefix.IsMatchBytes(r.runpaged.window(r.runtextpos, r.runtextpos+l), 0, 0, l)
+			}
 			return r.code.BmPrefix.IsMatch(r.runtext, r.runtextpos, 0, r.runtextend)
 		}
 
 		return true // found a valid start or end anchor
 	} else if r.code.BmPrefix != nil {
-		r.runtextpos = r.code.BmPrefix.Scan(r.runtext, r.runtextpos, 0, r.runtextend)
+		r.runtextpos = r.scanPrefix(r.code.BmPrefix, r.runtextpos)
 
 		if r.runtextpos == -1 {
 			if r.code.RightToLeft {
@@ -1301,6 +1489,8 @@ func (r *runner) findFirstChar() bool {
 		}
 
 		return true
+	} else if r.code.Literal != nil {
+		return r.findRequiredLiteral()
 	} else if r.code.FcPrefix == nil {
 		return true
 	}
@@ -1331,18 +1521,52 @@ func (r *runner) findFirstChar() bool {
 	return false
 }
 
+// scanPrefix finds the next occurrence of the Boyer-Moore prefix in the text, starting at index
+func (r *runner) scanPrefix(bm *syntax.BmPrefix, index int) int {
+	if r.bytemode {
+		return bm.ScanBytes(r.runbytes, index, 0, r.runtextend)
+	}
+	if r.runpaged != nil {
+		return r.runpaged.scanPrefix(bm, index, 0, r.runtextend, r.code.RightToLeft)
+	}
+	return bm.Scan(r.runtext, index, 0, r.runtextend)
+}
+
+// findRequiredLiteral looks for the string every match contains, then backs off to the
+// first place a match containing it could start.  It returns false, with runtextpos at the
+// end, if the string isn't in the rest of the text.
+func (r *runner) findRequiredLiteral() bool {
+	lit := r.code.Literal
+
+	found := -1
+	if from := r.runtextpos + lit.MinOffset; from <= r.runtextend {
+		found = r.scanPrefix(lit.BmPrefix, from)
+	}
+	if found == -1 {
+		r.runtextpos = r.runtextend
+		return false
+	}
+
+	if start := found - lit.MaxOffset; start > r.runtextpos {
+		r.runtextpos = start
+	}
+	return true
+}
+
 func (r *runner) initMatch() {
 	// Use a hashtable'ed Match object if the capture numbers are sparse
 
 	if r.runmatch == nil {
 		if r.re.caps != nil {
-			r.runmatch = newMatchSparse(r.re, r.re.caps, r.re.capsize, r.runtext, r.runtextstart)
+			r.runmatch = newMatchSparse(r.re, r.re.caps, r.re.capsize, r.runtext, r.runbytes, r.runtextstart)
 		} else {
-			r.runmatch = newMatch(r.re, r.re.capsize, r.runtext, r.runtextstart)
+			r.runmatch = newMatch(r.re, r.re.capsize, r.runtext, r.runbytes, r.runtextstart)
 		}
 	} else {
-		r.runmatch.reset(r.runtext, r.runtextstart)
+		r.runmatch.reset(r.runtext, r.runbytes, r.runtextstart)
 	}
+	r.runmatch.paged = r.runpaged
+	r.runmatch.src = r.runsrc
 
 	// note we test runcrawl, because it is the last one to be allocated
 	// If there is an alloc failure in the middle of the three allocations,
@@ -1385,6 +1609,7 @@ func (r *runner) tidyMatch(quick bool) *Match {
 		r.runmatch = nil
 
 		match.tidy(r.runtextpos)
+		match.stats = MatchStats{PeakStackBytes: r.peakStack * intSize}
 		return match
 	} else {
 		// send back our match -- it's not leaving the package, so it's safe to not clean it up
@@ -1505,15 +1730,15 @@ func (r *runner) textposDescription() string {
 	}
 
 	if r.runtextpos > 0 {
-		buf.WriteString(syntax.CharDescription(r.runtext[r.runtextpos-1]))
+		buf.WriteString(syntax.CharDescription(r.charAt(r.runtextpos - 1)))
 	} else {
 		buf.WriteRune('^')
 	}
 
 	buf.WriteRune('>')
 
-	for i := r.runtextpos; i < r.runtextend; i++ {
-		buf.WriteString(syntax.CharDescription(r.runtext[i]))
+	for i := r.runtextpos; i < r.runtextend && buf.Len() < 64; i++ {
+		buf.WriteString(syntax.CharDescription(r.charAt(i)))
 	}
 	if buf.Len() >= 64 {
 		buf.Truncate(61)
@@ -1529,13 +1754,13 @@ func (r *runner) textposDescription() string {
 // at the specified index is a boundary or not. It's just not worth
 // emitting inline code for this logic.
 func (r *runner) isBoundary(index, startpos, endpos int) bool {
-	return (index > startpos && syntax.IsWordChar(r.runtext[index-1])) !=
-		(index < endpos && syntax.IsWordChar(r.runtext[index]))
+	return (index > startpos && syntax.IsWordChar(r.charAt(index-1))) !=
+		(index < endpos && syntax.IsWordChar(r.charAt(index)))
 }
 
 func (r *runner) isECMABoundary(index, startpos, endpos int) bool {
-	return (index > startpos && syntax.IsECMAWordChar(r.runtext[index-1])) !=
-		(index < endpos && syntax.IsECMAWordChar(r.runtext[index]))
+	return (index > startpos && syntax.IsECMAWordChar(r.charAt(index-1))) !=
+		(index < endpos && syntax.IsECMAWordChar(r.charAt(index)))
 }
 
 // this seems like a comment to justify randomly picking 1000 :-P
@@ -1545,6 +1770,17 @@ func (r *runner) isECMABoundary(index, startpos, endpos int) bool {
 // of TimeoutCheckFrequency tended to slow down the execution.
 const timeoutCheckFrequency int = 1000
 
+// setLimits sets the timeout and the step and memory limits for the search.  ctx must
+// already be set.
+func (r *runner) setLimits(timeout time.Duration, stepLimit, memoryLimit int) {
+	r.timeout = timeout
+	r.stepLimit = stepLimit
+	r.memoryLimit = memoryLimit
+	r.stackErr = nil
+	r.peakStack = 0
+	r.ignoreTimeout = time.Duration(math.MaxInt64) == timeout && r.ctx.Done() == nil && stepLimit <= 0 && memoryLimit <= 0
+}
+
 func (r *runner) startTimeoutWatch() {
 	if r.ignoreTimeout {
 		return
@@ -1552,12 +1788,29 @@ func (r *runner) startTimeoutWatch() {
 
 	r.timeoutChecksToSkip = timeoutCheckFrequency
 	r.timeoutAt = time.Now().Add(r.timeout)
+	r.steps = 0
 }
 
+// checkTimeout is called for every step of the search, and checks the step and memory
+// limits as well as the timeout and the context
 func (r *runner) checkTimeout() error {
 	if r.ignoreTimeout {
 		return nil
 	}
+	if r.stackErr != nil {
+		return r.stackErr
+	}
+	if r.stepLimit > 0 {
+		r.steps++
+		if r.steps > r.stepLimit {
+			return &MatchStepLimitError{
+				Pattern: r.re.pattern,
+				Limit:   r.stepLimit,
+				Start:   r.runtextstart,
+				Pos:     r.runtextpos,
+			}
+		}
+	}
 	r.timeoutChecksToSkip--
 	if r.timeoutChecksToSkip != 0 {
 		return nil
@@ -1568,6 +1821,14 @@ func (r *runner) checkTimeout() error {
 }
 
 func (r *runner) doCheckTimeout() error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+	if r.timeout == time.Duration(math.MaxInt64) {
+		// only the context can stop the search
+		return nil
+	}
+
 	current := time.Now()
 
 	if current.Before(r.timeoutAt) {
@@ -1584,7 +1845,12 @@ func (r *runner) doCheckTimeout() error {
 		//Debug.WriteLine("About to throw RegexMatchTimeoutException.")
 	}
 
-	return fmt.Errorf("match timeout after %v on input `%v`", r.timeout, string(r.runtext))
+	return &MatchTimeoutError{
+		Pattern: r.re.pattern,
+		Timeout: r.timeout,
+		Start:   r.runtextstart,
+		Pos:     r.runtextpos,
+	}
 }
 
 func (r *runner) initTrackCount() {
@@ -1604,8 +1870,9 @@ func (re *Regexp) getRunner() *runner {
 	}
 	re.muRun.Unlock()
 	z := &runner{
-		re:   re,
-		code: re.code,
+		re:      re,
+		code:    re.code,
+		program: re.generatedProgram(),
 	}
 	return z
 }
@@ -1615,6 +1882,7 @@ func (re *Regexp) getRunner() *runner {
 // grow to the maximum number of simultaneous matches
 // run using re.  (The cache empties when re gets garbage collected.)
 func (re *Regexp) putRunner(r *runner) {
+	r.ctx = nil
 	re.muRun.Lock()
 	re.runner = append(re.runner, r)
 	re.muRun.Unlock()
@@ -0,0 +1,127 @@
+package binexp
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// defaultReaderChunkSize is the number of bytes FindReaderMatches reads at a time
+// when the caller doesn't specify a chunk size
+const defaultReaderChunkSize = 64 * 1024
+
+// FindReaderMatches searches the bytes read from rd for non-overlapping matches, calling fn
+// with each one in order until fn returns false or rd is exhausted.  Each byte is matched as a
+// single rune, the same as FindBytesMatchStartingAt with the ByteRunes option.
+//
+// The input is read chunkSize bytes at a time (a default is used if chunkSize <= 0).  The last
+// window bytes of every chunk are searched again together with the next chunk, so a match that
+// straddles a chunk boundary is still found as long as it is no longer than window bytes.
+// Enough of the input before the search position is kept for the pattern's lookbehinds,
+// anchors and boundaries to see what they would in the whole stream, so \A and ^ only match
+// at its start, and a match isn't reported until enough of the input after it has been read
+// for its lookaheads, anchors and boundaries, so \z and $ only match at its end.  A pattern
+// with a lookaround that can match text of any length, such as (?<=a\w*)b or a(?!\w*b),
+// can't be searched in a stream, and returns an error.  \G matches where each chunk's search
+// resumes.
+//
+// Index in every Match (and in its Groups and Captures) is an absolute byte offset from the
+// start of the stream.  A Match only holds on to the chunk it was found in, so FindNextMatch
+// on it will not look past the end of that chunk.
+func (re *Regexp) FindReaderMatches(rd io.Reader, chunkSize, window int, fn func(*Match) bool) error {
+	return re.findReaderMatches(context.Background(), rd, chunkSize, window, fn)
+}
+
+func (re *Regexp) findReaderMatches(ctx context.Context, rd io.Reader, chunkSize, window int, fn func(*Match) bool) error {
+	if re.RightToLeft() {
+		return errors.New("RightToLeft is not supported when searching a reader")
+	}
+	if window < 0 {
+		return errors.New("window must not be negative")
+	}
+	if re.code.Lookbehind < 0 {
+		return errors.New("a lookbehind of unbounded length is not supported when searching a reader")
+	}
+	if re.code.Lookahead < 0 {
+		return errors.New("a lookahead of unbounded length is not supported when searching a reader")
+	}
+	// how much of the input before pos is kept, which also covers the window of the
+	// last chunk that's searched again
+	behind := max(window, re.code.Lookbehind)
+	ahead := re.code.Lookahead
+	if chunkSize <= 0 {
+		chunkSize = defaultReaderChunkSize
+	}
+
+	var (
+		buf  []byte // text being searched, buf[0] is at absolute offset base
+		base int
+		pos  int // next position in buf to search from
+		eof  bool
+	)
+
+	for !eof {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// carry the unsearched tail (plus context) over into a fresh buffer so
+		// matches we've already handed out keep their own text
+		keep := pos - behind
+		if keep < 0 {
+			keep = 0
+		}
+		next := make([]byte, len(buf)-keep, len(buf)-keep+chunkSize)
+		copy(next, buf[keep:])
+		buf = next
+		base += keep
+		pos -= keep
+
+		n, err := io.ReadFull(rd, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			eof = true
+		} else if err != nil {
+			return err
+		}
+
+		// matches starting at or past limit might still grow with more input, or be judged
+		// differently by what the pattern sees after them, so they wait for the next chunk
+		limit := max(len(buf)-window-ahead, 0)
+		if eof {
+			limit = len(buf)
+		}
+
+		// the next chunk's search starts at limit, unless a match before it has to wait
+		resume := limit
+		for pos < limit || (eof && pos <= limit) {
+			m, err := re.runBytes(ctx, false, pos, buf)
+			if err != nil {
+				return rebaseErr(err, base)
+			}
+			if m == nil || (!eof && m.Index >= limit) {
+				break
+			}
+			if !eof && m.Index+m.Length+ahead > len(buf) {
+				// a match longer than the window, which is found again once enough of
+				// what comes after it has been read
+				resume = m.Index
+				break
+			}
+
+			pos = m.Index + m.Length
+			if m.Length == 0 {
+				pos++
+			}
+
+			m.rebase(base)
+			if !fn(m) {
+				return nil
+			}
+		}
+
+		pos = max(pos, resume)
+	}
+
+	return nil
+}
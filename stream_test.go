@@ -0,0 +1,179 @@
+package binexp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"testing/iotest"
+)
+
+func TestFindReaderMatches_AcrossChunks(t *testing.T) {
+	re := MustCompile("\xca(?<imm>[\x00-\xff]{2})", ByteRunes)
+
+	data := bytes.Repeat([]byte{0x90, 0x90, 0x90, 0xca, 0x01, 0x02, 0x90}, 20)
+
+	// what a search of the whole slice finds
+	var want []int
+	for m, _ := re.FindBytesMatchStartingAt(data, 0); m != nil; m, _ = re.FindNextMatch(m) {
+		want = append(want, m.Index)
+	}
+
+	for _, chunkSize := range []int{1, 2, 5, 7, 64} {
+		var got []int
+		err := re.FindReaderMatches(iotest.OneByteReader(bytes.NewReader(data)), chunkSize, 3, func(m *Match) bool {
+			got = append(got, m.Index)
+			if want, got := data[m.Index:m.Index+3], m.Bytes(); !bytes.Equal(want, got) {
+				t.Errorf("chunk %v: match at %v wanted bytes %x, got %x", chunkSize, m.Index, want, got)
+			}
+			if imm := m.GroupByName("imm"); imm.Index != m.Index+1 || !bytes.Equal(imm.Bytes(), []byte{0x01, 0x02}) {
+				t.Errorf("chunk %v: capture wanted 0102 at %v, got %x at %v", chunkSize, m.Index+1, imm.Bytes(), imm.Index)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("chunk %v: unexpected err: %v", chunkSize, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("chunk %v: wanted %v matches, got %v (%v)", chunkSize, len(want), len(got), got)
+		}
+		for i := range want {
+			if want[i] != got[i] {
+				t.Fatalf("chunk %v: match %v wanted at %v, got %v", chunkSize, i, want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestFindReaderMatches_Stop(t *testing.T) {
+	re := MustCompile("a", ByteRunes)
+
+	count := 0
+	err := re.FindReaderMatches(bytes.NewReader([]byte("aaaaa")), 2, 0, func(m *Match) bool {
+		count++
+		return count < 3
+	})
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("Expected the search to stop after 3 matches, got %v", count)
+	}
+}
+
+func TestFindReaderMatches_NextMatchKeepsOffset(t *testing.T) {
+	re := MustCompile("ab", ByteRunes)
+
+	var first *Match
+	err := re.FindReaderMatches(bytes.NewReader([]byte("xxxxxxabxabx")), 6, 4, func(m *Match) bool {
+		first = m
+		return false
+	})
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if first == nil || first.Index != 6 {
+		t.Fatalf("Expected first match at 6, got %v", first)
+	}
+
+	m, err := re.FindNextMatch(first)
+	if err != nil {
+		t.Fatalf("Unexpected err: %v", err)
+	}
+	if m == nil || m.Index != 9 || m.String() != "ab" {
+		t.Fatalf("Expected next match at 9, got %v", m)
+	}
+}
+
+func TestFindReaderMatches_RightToLeft(t *testing.T) {
+	re := MustCompile("a", RightToLeft)
+	err := re.FindReaderMatches(bytes.NewReader([]byte("a")), 0, 0, func(*Match) bool { return true })
+	if err == nil {
+		t.Fatalf("Expected an error for RightToLeft")
+	}
+}
+
+func TestFindReaderMatches_LookBack(t *testing.T) {
+	// matches no longer than the window of 0, so any difference is down to what
+	// the pattern sees before them
+	data := []byte("abxab ab\nab_ab yxab xyab\nxab")
+
+	for _, pattern := range []string{`\Aa`, `^a`, `(?m)^a`, `\ba`, `\Ba`, `(?<!x)a`, `(?<=x.)a`, `(?<=(?<!y)x)a`} {
+		re := MustCompile(pattern, ByteRunes)
+
+		var want []int
+		for m, _ := re.FindBytesMatchStartingAt(data, 0); m != nil; m, _ = re.FindNextMatch(m) {
+			want = append(want, m.Index)
+		}
+
+		for _, chunkSize := range []int{1, 2, 3, 64} {
+			var got []int
+			err := re.FindReaderMatches(bytes.NewReader(data), chunkSize, 0, func(m *Match) bool {
+				got = append(got, m.Index)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("%v chunk %v: unexpected err: %v", pattern, chunkSize, err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%v chunk %v: wanted matches at %v, got %v", pattern, chunkSize, want, got)
+			}
+		}
+	}
+
+	re := MustCompile(`(?<=a\w*)b`, ByteRunes)
+	err := re.FindReaderMatches(bytes.NewReader(data), 0, 0, func(*Match) bool { return true })
+	if err == nil {
+		t.Fatalf("Expected an error for a lookbehind of unbounded length")
+	}
+}
+
+func TestFindReaderMatches_LookAhead(t *testing.T) {
+	// matches no longer than the window, so any difference is down to what the pattern
+	// sees after them
+	data := []byte("xabc ab\nabc a_ab a\nxa")
+
+	for _, pattern := range []string{`a\z`, `a\Z`, `a$`, `(?m)a$`, `a\b`, `a\B`, `a(?=b)`, `a(?!bc)`,
+		`a(?!b\z)`, `a(?=.(?!c))`, `a(?=(?<=a)b)`, `a(?=b?c)`} {
+		re := MustCompile(pattern, ByteRunes|Singleline)
+
+		var want []int
+		for m, _ := re.FindBytesMatchStartingAt(data, 0); m != nil; m, _ = re.FindNextMatch(m) {
+			want = append(want, m.Index)
+		}
+
+		for _, chunkSize := range []int{1, 2, 3, 64} {
+			for _, window := range []int{0, 1} {
+				var got []int
+				err := re.FindReaderMatches(bytes.NewReader(data), chunkSize, window, func(m *Match) bool {
+					got = append(got, m.Index)
+					return true
+				})
+				if err != nil {
+					t.Fatalf("%v chunk %v window %v: unexpected err: %v", pattern, chunkSize, window, err)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%v chunk %v window %v: wanted matches at %v, got %v", pattern, chunkSize, window, want, got)
+				}
+			}
+		}
+	}
+
+	// a match longer than the window isn't reported until what's after it has been read
+	re := MustCompile(`ab\z`, ByteRunes)
+	for _, chunkSize := range []int{1, 2, 3} {
+		var got []int
+		err := re.FindReaderMatches(bytes.NewReader([]byte("xabc ab")), chunkSize, 0, func(m *Match) bool {
+			got = append(got, m.Index)
+			return true
+		})
+		if err != nil || fmt.Sprint(got) != "[5]" {
+			t.Errorf("ab\\z chunk %v: wanted a match at [5], got %v, %v", chunkSize, got, err)
+		}
+	}
+
+	re = MustCompile(`a(?!\w*b)`, ByteRunes)
+	err := re.FindReaderMatches(bytes.NewReader(data), 0, 0, func(*Match) bool { return true })
+	if err == nil {
+		t.Fatalf("Expected an error for a lookahead of unbounded length")
+	}
+}
@@ -0,0 +1,144 @@
+package syntax
+
+import (
+	"unicode"
+)
+
+// AhoCorasick is an Aho-Corasick automaton, which finds every occurrence of a set of literal
+// strings (such as the prefixes of many patterns) in a single pass over the text.
+//
+// The text is fed in one character at a time with Next, starting from state 0, and Matches
+// reports the strings that end at the character just fed in.  Transitions on characters up
+// to 0xFF, which covers all the text when matching bytes, are looked up in a table; others
+// follow the failure links of the trie.
+type AhoCorasick struct {
+	words           [][]rune
+	caseInsensitive bool
+	maxLen          int
+
+	// per state
+	next  [][256]int32     // complete transitions on characters up to 0xFF
+	wide  []map[rune]int32 // trie transitions on characters past 0xFF
+	fail  []int32          // the state for the longest proper suffix that's in the trie
+	match [][]int          // the words ending at this state, longest first
+}
+
+// NewAhoCorasick builds an automaton that finds all of the words.  If caseInsensitive is
+// true both the words and the text are compared in lowercase.
+func NewAhoCorasick(words [][]rune, caseInsensitive bool) *AhoCorasick {
+	a := &AhoCorasick{
+		words:           words,
+		caseInsensitive: caseInsensitive,
+	}
+
+	// the trie
+	trie := []map[rune]int32{{}}
+	var ends [][]int
+	ends = append(ends, nil)
+	for i, word := range words {
+		state := int32(0)
+		for _, ch := range word {
+			if caseInsensitive {
+				ch = unicode.ToLower(ch)
+			}
+			next, ok := trie[state][ch]
+			if !ok {
+				next = int32(len(trie))
+				trie = append(trie, map[rune]int32{})
+				ends = append(ends, nil)
+				trie[state][ch] = next
+			}
+			state = next
+		}
+		ends[state] = append(ends[state], i)
+		if len(word) > a.maxLen {
+			a.maxLen = len(word)
+		}
+	}
+
+	a.next = make([][256]int32, len(trie))
+	a.wide = make([]map[rune]int32, len(trie))
+	a.fail = make([]int32, len(trie))
+	a.match = make([][]int, len(trie))
+
+	// the failure links, in breadth first order so that the failure state of every state,
+	// which is always closer to the root, is complete before it's used.  Missing byte
+	// transitions are taken from the failure state, which makes the byte table complete.
+	queue := []int32{0}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		f := a.fail[state]
+		a.match[state] = append(ends[state], a.match[f]...)
+
+		for ch := rune(0); ch <= 0xff; ch++ {
+			child, ok := trie[state][ch]
+			switch {
+			case ok:
+				a.next[state][ch] = child
+				if state != 0 {
+					a.fail[child] = a.next[f][ch]
+				}
+				queue = append(queue, child)
+			case state != 0:
+				a.next[state][ch] = a.next[f][ch]
+			}
+		}
+		for ch, child := range trie[state] {
+			if ch <= 0xff {
+				continue
+			}
+			if a.wide[state] == nil {
+				a.wide[state] = map[rune]int32{}
+			}
+			a.wide[state][ch] = child
+			if state != 0 {
+				a.fail[child] = a.step(f, ch)
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return a
+}
+
+// step follows the transition on ch, which must already be lowercase if needed
+func (a *AhoCorasick) step(state int32, ch rune) int32 {
+	if ch <= 0xff {
+		return a.next[state][ch]
+	}
+	for {
+		if s, ok := a.wide[state][ch]; ok {
+			return s
+		}
+		if state == 0 {
+			return 0
+		}
+		state = a.fail[state]
+	}
+}
+
+// Next returns the state after feeding ch to the automaton in the given state
+func (a *AhoCorasick) Next(state int, ch rune) int {
+	if a.caseInsensitive {
+		ch = unicode.ToLower(ch)
+	}
+	return int(a.step(int32(state), ch))
+}
+
+// Matches returns the indexes of the words that end at the last character fed to the
+// automaton to reach state
+func (a *AhoCorasick) Matches(state int) []int {
+	return a.match[state]
+}
+
+// WordLen returns the length of word i
+func (a *AhoCorasick) WordLen(i int) int {
+	return len(a.words[i])
+}
+
+// MaxLen returns the length of the longest word
+func (a *AhoCorasick) MaxLen() int {
+	return a.maxLen
+}
@@ -9,10 +9,11 @@ import (
 	"unicode/utf8"
 )
 
-// CharSet combines start-end rune ranges and unicode categories representing a set of characters
+// CharSet combines start-end rune ranges, unicode categories and byte masks representing a set of characters
 type CharSet struct {
 	ranges     []singleRange
 	categories []category
+	masks      []byteMask
 	sub        *CharSet //optional subtractor
 	negate     bool
 	anything   bool
@@ -28,6 +29,16 @@ type singleRange struct {
 	last  rune
 }
 
+// byteMask matches the bytes (runes up to 0xFF) b where b&mask == value
+type byteMask struct {
+	mask  byte
+	value byte
+}
+
+func (m byteMask) match(ch rune) bool {
+	return ch <= 0xff && byte(ch)&m.mask == m.value
+}
+
 const (
 	spaceCategoryText = " "
 	wordCategoryText  = "W"
@@ -149,6 +160,7 @@ func (c CharSet) Copy() CharSet {
 
 	ret.ranges = append(ret.ranges, c.ranges...)
 	ret.categories = append(ret.categories, c.categories...)
+	ret.masks = append(ret.masks, c.masks...)
 
 	if c.sub != nil {
 		sub := c.sub.Copy()
@@ -183,6 +195,10 @@ func (c CharSet) String() string {
 		buf.WriteString(c.String())
 	}
 
+	for _, m := range c.masks {
+		fmt.Fprintf(buf, "\\m{%02X:%02X}", m.mask, m.value)
+	}
+
 	if c.sub != nil {
 		buf.WriteRune('-')
 		buf.WriteString(c.sub.String())
@@ -215,6 +231,11 @@ func (c CharSet) mapHashFill(buf *bytes.Buffer) {
 			buf.WriteByte(0)
 		}
 	}
+	binary.Write(buf, binary.LittleEndian, int32(len(c.masks)))
+	for _, m := range c.masks {
+		buf.WriteByte(m.mask)
+		buf.WriteByte(m.value)
+	}
 
 	if c.sub != nil {
 		c.sub.mapHashFill(buf)
@@ -238,6 +259,16 @@ func (c CharSet) CharIn(ch rune) bool {
 		}
 	}
 
+	//check byte masks
+	if !val {
+		for _, m := range c.masks {
+			if m.match(ch) {
+				val = true
+				break
+			}
+		}
+	}
+
 	//check categories if we haven't already found a range
 	if !val && len(c.categories) > 0 {
 		for _, ct := range c.categories {
@@ -357,14 +388,14 @@ func (c CharSet) SingletonChar() rune {
 
 func (c CharSet) IsSingleton() bool {
 	return !c.negate && //negated is multiple chars
-		len(c.categories) == 0 && len(c.ranges) == 1 && // multiple ranges and unicode classes represent multiple chars
+		len(c.categories) == 0 && len(c.masks) == 0 && len(c.ranges) == 1 && // multiple ranges and unicode classes represent multiple chars
 		c.sub == nil && // subtraction means we've got multiple chars
 		c.ranges[0].first == c.ranges[0].last // first and last equal means we're just 1 char
 }
 
 func (c CharSet) IsSingletonInverse() bool {
 	return c.negate && //same as above, but requires negated
-		len(c.categories) == 0 && len(c.ranges) == 1 && // multiple ranges and unicode classes represent multiple chars
+		len(c.categories) == 0 && len(c.masks) == 0 && len(c.ranges) == 1 && // multiple ranges and unicode classes represent multiple chars
 		c.sub == nil && // subtraction means we've got multiple chars
 		c.ranges[0].first == c.ranges[0].last // first and last equal means we're just 1 char
 }
@@ -382,7 +413,7 @@ func (c CharSet) HasSubtraction() bool {
 }
 
 func (c CharSet) IsEmpty() bool {
-	return len(c.ranges) == 0 && len(c.categories) == 0 && c.sub == nil
+	return len(c.ranges) == 0 && len(c.categories) == 0 && len(c.masks) == 0 && c.sub == nil
 }
 
 func (c *CharSet) addDigit(ecma, negate bool, pattern string) {
@@ -437,15 +468,42 @@ func (c *CharSet) addSet(set CharSet) {
 	// just append here to prevent double-canon
 	c.ranges = append(c.ranges, set.ranges...)
 	c.addCategories(set.categories...)
+	for _, m := range set.masks {
+		c.addMask(m.mask, m.value)
+	}
 	c.canonicalize()
 }
 
 func (c *CharSet) makeAnything() {
 	c.anything = true
 	c.categories = []category{}
+	c.masks = nil
 	c.ranges = AnyClass().ranges
 }
 
+// addMask adds the bytes b where b&mask == value.  A full mask is just a single
+// char and a zero mask is every byte, so those are added as ranges instead.
+func (c *CharSet) addMask(mask, value byte) {
+	if c.anything {
+		return
+	}
+	value &= mask
+	switch mask {
+	case 0xff:
+		c.addRange(rune(value), rune(value))
+		return
+	case 0:
+		c.addRange(0, 0xff)
+		return
+	}
+	for _, m := range c.masks {
+		if m.mask == mask && m.value == value {
+			return
+		}
+	}
+	c.masks = append(c.masks, byteMask{mask: mask, value: value})
+}
+
 func (c *CharSet) addCategories(cats ...category) {
 	// don't add dupes and remove positive+negative
 	if c.anything {
@@ -589,6 +647,13 @@ func (c *CharSet) addLowercase() {
 	for _, r := range toAdd {
 		c.addLowercaseRange(r.first, r.last)
 	}
+	for _, m := range c.masks {
+		for b := rune(0); b <= 0xff; b++ {
+			if m.match(b) {
+				c.addLowercaseRange(b, b)
+			}
+		}
+	}
 	c.canonicalize()
 }
 
@@ -90,16 +90,20 @@ const (
 )
 
 type Code struct {
-	Codes       []int       // the code
-	Strings     [][]rune    // string table
-	Sets        []*CharSet  //character set table
-	TrackCount  int         // how many instructions use backtracking
-	Caps        map[int]int // mapping of user group numbers -> impl group slots
-	Capsize     int         // number of impl group slots
-	FcPrefix    *Prefix     // the set of candidate first characters (may be null)
-	BmPrefix    *BmPrefix   // the fixed prefix string as a Boyer-Moore machine (may be null)
-	Anchors     AnchorLoc   // the set of zero-length start anchors (RegexFCD.Bol, etc)
-	RightToLeft bool        // true if right to left
+	Codes       []int            // the code
+	Strings     [][]rune         // string table
+	Sets        []*CharSet       //character set table
+	TrackCount  int              // how many instructions use backtracking
+	Caps        map[int]int      // mapping of user group numbers -> impl group slots
+	Capsize     int              // number of impl group slots
+	FcPrefix    *Prefix          // the set of candidate first characters (may be null)
+	BmPrefix    *BmPrefix        // the fixed prefix string as a Boyer-Moore machine (may be null)
+	Literal     *RequiredLiteral // a string every match contains, when there's no prefix (may be null)
+	NFA         *NFA             // the pattern as an NFA, if it doesn't need backtracking (may be null)
+	Anchors     AnchorLoc        // the set of zero-length start anchors (RegexFCD.Bol, etc)
+	Lookbehind  int              // how far before the start of a match the program looks, or -1 if there's no bound
+	Lookahead   int              // how far past the end of a match the program looks, or -1 if there's no bound
+	RightToLeft bool             // true if right to left
 }
 
 func opcodeBacktracks(op InstOp) bool {
@@ -137,6 +141,12 @@ func opcodeSize(op InstOp) int {
 	}
 }
 
+// OpcodeSize returns the number of ints an instruction with opcode op takes up in Codes,
+// counting the opcode and its operands
+func OpcodeSize(op InstOp) int {
+	return opcodeSize(op)
+}
+
 var codeStr = []string{
 	"Onerep", "Notonerep", "Setrep",
 	"Oneloop", "Notoneloop", "Setloop",
@@ -259,7 +269,13 @@ func (c *Code) Dump() string {
 		fmt.Fprintf(buf, "Prefix:     %v\n", Escape(c.BmPrefix.String()))
 	}
 
+	if c.Literal != nil {
+		fmt.Fprintf(buf, "Literal:    %v at %v-%v\n", Escape(c.Literal.BmPrefix.String()), c.Literal.MinOffset, c.Literal.MaxOffset)
+	}
+
 	fmt.Fprintf(buf, "Anchors:    %v\n", c.Anchors)
+	fmt.Fprintf(buf, "Lookbehind: %v\n", c.Lookbehind)
+	fmt.Fprintf(buf, "Lookahead:  %v\n", c.Lookahead)
 	fmt.Fprintln(buf)
 
 	if c.BmPrefix != nil {
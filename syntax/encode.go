@@ -0,0 +1,513 @@
+package syntax
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+)
+
+// The binary encoding of a Code starts with codeMagic and the version of the encoding,
+// followed by each of its fields in turn.  Integers are varints and lists are preceded
+// by their length.  The version changes whenever the encoding (or the meaning of the
+// instructions) does, and data with a different version is rejected.  Like the other
+// encodings, it ends with a CRC-32 checksum, so damaged data is rejected rather than
+// turned into a program that misbehaves.  The program is checked for consistency too,
+// but not thoroughly enough to load one from an untrusted source.
+const (
+	codeMagic   = "binexp code"
+	codeVersion = 3
+)
+
+// Encoder builds a binary encoding out of integers, strings, runes and the like
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the encoding so far, followed by its checksum
+func (e *Encoder) Bytes() []byte {
+	b := make([]byte, len(e.buf), len(e.buf)+crc32.Size)
+	copy(b, e.buf)
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(e.buf))
+}
+
+// Int adds an integer
+func (e *Encoder) Int(i int) {
+	e.buf = binary.AppendVarint(e.buf, int64(i))
+}
+
+// Bool adds a bool
+func (e *Encoder) Bool(b bool) {
+	if b {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// String adds a string
+func (e *Encoder) String(s string) {
+	e.Int(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// Runes adds a slice of runes
+func (e *Encoder) Runes(r []rune) {
+	e.Int(len(r))
+	for _, ch := range r {
+		e.Int(int(ch))
+	}
+}
+
+// Decoder reads back what an Encoder wrote.  The first error is kept, after which
+// everything reads as zero, so it only needs checking at the end.
+type Decoder struct {
+	buf    []byte
+	err    error
+	badSum bool
+}
+
+// NewDecoder returns a Decoder reading from data, which ends with the checksum added
+// by Encoder.Bytes
+func NewDecoder(data []byte) *Decoder {
+	if len(data) < crc32.Size {
+		return &Decoder{err: errors.New("encoding is cut short")}
+	}
+	n := len(data) - crc32.Size
+	return &Decoder{
+		buf:    data[:n],
+		badSum: binary.LittleEndian.Uint32(data[n:]) != crc32.ChecksumIEEE(data[:n]),
+	}
+}
+
+// Err returns the first error, or an error if there's data left over or the checksum
+// doesn't match
+func (d *Decoder) Err() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.buf) > 0 {
+		return errors.New("unexpected data after the end of the encoding")
+	}
+	if d.badSum {
+		return errors.New("checksum mismatch, the encoding is damaged")
+	}
+	return nil
+}
+
+// Fail records an error in the data, unless there already is one
+func (d *Decoder) Fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+// Int reads an integer
+func (d *Decoder) Int() int {
+	if d.err != nil {
+		return 0
+	}
+	i, n := binary.Varint(d.buf)
+	if n <= 0 || int64(int(i)) != i {
+		d.Fail("invalid integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int(i)
+}
+
+// Len reads the length of a list, checking it isn't negative or longer than the data
+// left could hold, given that each item takes at least one byte
+func (d *Decoder) Len() int {
+	n := d.Int()
+	if n < 0 || n > len(d.buf) {
+		d.Fail("invalid length %v", n)
+		return 0
+	}
+	return n
+}
+
+// Bool reads a bool
+func (d *Decoder) Bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) == 0 || d.buf[0] > 1 {
+		d.Fail("invalid bool")
+		return false
+	}
+	b := d.buf[0] == 1
+	d.buf = d.buf[1:]
+	return b
+}
+
+// String reads a string
+func (d *Decoder) String() string {
+	n := d.Len()
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s
+}
+
+// Runes reads a slice of runes
+func (d *Decoder) Runes() []rune {
+	n := d.Len()
+	r := make([]rune, n)
+	for i := range r {
+		r[i] = d.rune()
+	}
+	return r
+}
+
+func (d *Decoder) rune() rune {
+	ch := d.Int()
+	if ch < 0 || ch > 0x10ffff {
+		d.Fail("invalid rune %v", ch)
+		return 0
+	}
+	return rune(ch)
+}
+
+// MarshalBinary encodes the program so it can be loaded again without parsing the pattern
+func (c *Code) MarshalBinary() ([]byte, error) {
+	e := &Encoder{}
+	e.String(codeMagic)
+	e.Int(codeVersion)
+
+	e.Int(len(c.Codes))
+	for _, op := range c.Codes {
+		e.Int(op)
+	}
+	e.Int(len(c.Strings))
+	for _, s := range c.Strings {
+		e.Runes(s)
+	}
+	e.Int(len(c.Sets))
+	for _, set := range c.Sets {
+		encodeCharSet(e, set)
+	}
+	e.Int(c.TrackCount)
+
+	// map order is random, so the slots are sorted to keep the encoding stable
+	e.Bool(c.Caps != nil)
+	keys := make([]int, 0, len(c.Caps))
+	for k := range c.Caps {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	e.Int(len(keys))
+	for _, k := range keys {
+		e.Int(k)
+		e.Int(c.Caps[k])
+	}
+	e.Int(c.Capsize)
+
+	e.Bool(c.FcPrefix != nil)
+	if c.FcPrefix != nil {
+		e.Runes(c.FcPrefix.PrefixStr)
+		encodeCharSet(e, &c.FcPrefix.PrefixSet)
+		e.Bool(c.FcPrefix.CaseInsensitive)
+	}
+
+	// the Boyer-Moore tables are rebuilt from the pattern
+	e.Bool(c.BmPrefix != nil)
+	if c.BmPrefix != nil {
+		e.Runes(c.BmPrefix.pattern)
+		e.Bool(c.BmPrefix.caseInsensitive)
+		e.Bool(c.BmPrefix.rightToLeft)
+	}
+
+	e.Bool(c.Literal != nil)
+	if c.Literal != nil {
+		e.Runes(c.Literal.BmPrefix.pattern)
+		e.Bool(c.Literal.BmPrefix.caseInsensitive)
+		e.Int(c.Literal.MinOffset)
+		e.Int(c.Literal.MaxOffset)
+	}
+
+	e.Bool(c.NFA != nil)
+	if c.NFA != nil {
+		e.Int(c.NFA.Start)
+		e.Bool(c.NFA.Asserts)
+		e.Int(len(c.NFA.Insts))
+		for _, inst := range c.NFA.Insts {
+			e.Int(int(inst.Op))
+			e.Int(inst.Out)
+			e.Int(inst.Out1)
+			e.Int(int(inst.Ch))
+			e.Bool(inst.Set != nil)
+			if inst.Set != nil {
+				encodeCharSet(e, inst.Set)
+			}
+			e.Bool(inst.Fold)
+			e.Int(inst.Cap)
+			e.Int(int(inst.Assertion))
+		}
+	}
+
+	e.Int(int(c.Anchors))
+	e.Int(c.Lookbehind)
+	e.Int(c.Lookahead)
+	e.Bool(c.RightToLeft)
+
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary loads a program encoded by MarshalBinary.  It returns an error if the
+// data was encoded by a different version of the package, or isn't a valid program.
+func (c *Code) UnmarshalBinary(data []byte) error {
+	d := NewDecoder(data)
+	if d.String() != codeMagic || d.err != nil {
+		return errors.New("not an encoded program")
+	}
+	if v := d.Int(); v != codeVersion {
+		return fmt.Errorf("unsupported encoding version %v, expected %v", v, codeVersion)
+	}
+
+	var code Code
+	code.Codes = make([]int, d.Len())
+	for i := range code.Codes {
+		code.Codes[i] = d.Int()
+	}
+	code.Strings = make([][]rune, d.Len())
+	for i := range code.Strings {
+		code.Strings[i] = d.Runes()
+	}
+	code.Sets = make([]*CharSet, d.Len())
+	for i := range code.Sets {
+		code.Sets[i] = decodeCharSet(d, 0)
+	}
+	code.TrackCount = d.Int()
+
+	hasCaps := d.Bool()
+	n := d.Len()
+	if hasCaps {
+		code.Caps = make(map[int]int, n)
+	} else if n > 0 {
+		d.Fail("unexpected group slots")
+		n = 0
+	}
+	for i := 0; i < n; i++ {
+		k := d.Int()
+		code.Caps[k] = d.Int()
+	}
+	code.Capsize = d.Int()
+
+	if d.Bool() {
+		code.FcPrefix = &Prefix{PrefixStr: d.Runes()}
+		code.FcPrefix.PrefixSet = *decodeCharSet(d, 0)
+		code.FcPrefix.CaseInsensitive = d.Bool()
+	}
+
+	if d.Bool() {
+		pattern, ci, rtl := d.Runes(), d.Bool(), d.Bool()
+		if len(pattern) == 0 {
+			d.Fail("empty prefix")
+		}
+		if d.err == nil {
+			if code.BmPrefix = newBmPrefix(pattern, ci, rtl); code.BmPrefix == nil {
+				d.Fail("invalid prefix")
+			}
+		}
+	}
+
+	if d.Bool() {
+		pattern, ci := d.Runes(), d.Bool()
+		code.Literal = &RequiredLiteral{MinOffset: d.Int(), MaxOffset: d.Int()}
+		if len(pattern) == 0 || code.Literal.MinOffset < 0 || code.Literal.MaxOffset < code.Literal.MinOffset {
+			d.Fail("invalid literal")
+		}
+		if d.err == nil {
+			if code.Literal.BmPrefix = newBmPrefix(pattern, ci, false); code.Literal.BmPrefix == nil {
+				d.Fail("invalid literal")
+			}
+		}
+	}
+
+	if d.Bool() {
+		nfa := &NFA{Start: d.Int(), Asserts: d.Bool()}
+		nfa.Insts = make([]NFAInst, d.Len())
+		for i := range nfa.Insts {
+			inst := &nfa.Insts[i]
+			inst.Op = NFAOp(d.Int())
+			inst.Out = d.Int()
+			inst.Out1 = d.Int()
+			inst.Ch = d.rune()
+			if d.Bool() {
+				inst.Set = decodeCharSet(d, 0)
+			}
+			inst.Fold = d.Bool()
+			inst.Cap = d.Int()
+			inst.Assertion = NFAAssertion(d.Int())
+		}
+		code.NFA = nfa
+	}
+
+	code.Anchors = AnchorLoc(d.Int())
+	if code.Lookbehind = d.Int(); code.Lookbehind < -1 {
+		d.Fail("invalid lookbehind")
+	}
+	if code.Lookahead = d.Int(); code.Lookahead < -1 {
+		d.Fail("invalid lookahead")
+	}
+	code.RightToLeft = d.Bool()
+
+	if err := d.Err(); err != nil {
+		return fmt.Errorf("invalid encoded program: %w", err)
+	}
+	if err := code.validate(); err != nil {
+		return fmt.Errorf("invalid encoded program: %w", err)
+	}
+	*c = code
+	return nil
+}
+
+// validate checks that the instructions only refer to strings, sets, instructions and
+// capture slots that exist, and that the counts the runner relies on are right, so running
+// a damaged program can't panic
+func (c *Code) validate() error {
+	if c.Capsize < 1 {
+		return fmt.Errorf("invalid number of group slots %v", c.Capsize)
+	}
+	for _, slot := range c.Caps {
+		if slot < 0 || slot >= c.Capsize {
+			return fmt.Errorf("group slot %v is out of range", slot)
+		}
+	}
+
+	jump := func(pos int) error {
+		if pos < 0 || pos >= len(c.Codes) {
+			return fmt.Errorf("jump to %v is out of range", pos)
+		}
+		return nil
+	}
+
+	trackCount := 0
+	for pc := 0; pc < len(c.Codes); {
+		op := InstOp(c.Codes[pc])
+		if op < 0 || op&Mask > NonECMABoundary || op&^(Mask|Rtl|Ci) != 0 {
+			return fmt.Errorf("unknown instruction %v at %v", op, pc)
+		}
+		size := opcodeSize(op)
+		if pc+size > len(c.Codes) {
+			return fmt.Errorf("instruction at %v is cut short", pc)
+		}
+		operand := 0
+		if size > 1 {
+			operand = c.Codes[pc+1]
+		}
+
+		switch op & Mask {
+		case Multi:
+			if operand < 0 || operand >= len(c.Strings) {
+				return fmt.Errorf("string %v at %v is out of range", operand, pc)
+			}
+		case Set, Setrep, Setloop, Setlazy:
+			if operand < 0 || operand >= len(c.Sets) {
+				return fmt.Errorf("set %v at %v is out of range", operand, pc)
+			}
+		case Lazybranch, Branchmark, Lazybranchmark, Branchcount, Lazybranchcount, Goto:
+			if err := jump(operand); err != nil {
+				return err
+			}
+		case Ref, Testref:
+			if operand < 0 || operand >= c.Capsize {
+				return fmt.Errorf("group %v at %v is out of range", operand, pc)
+			}
+		case Capturemark:
+			// a balancing group may only uncapture, leaving the first group as -1
+			uncap := c.Codes[pc+2]
+			if operand < -1 || operand >= c.Capsize || uncap < -1 || uncap >= c.Capsize || operand == -1 && uncap == -1 {
+				return fmt.Errorf("groups %v and %v at %v are out of range", operand, uncap, pc)
+			}
+		}
+		if opcodeBacktracks(op) {
+			trackCount++
+		}
+		pc += size
+	}
+	if trackCount != c.TrackCount {
+		return fmt.Errorf("track count %v doesn't match the instructions", c.TrackCount)
+	}
+
+	if c.NFA != nil {
+		insts := len(c.NFA.Insts)
+		if c.NFA.Start < 0 || c.NFA.Start >= insts {
+			return errors.New("NFA start is out of range")
+		}
+		for i, inst := range c.NFA.Insts {
+			if inst.Op > NFAMatch || inst.Assertion > AssertEnd {
+				return fmt.Errorf("unknown NFA instruction at %v", i)
+			}
+			if inst.Out < 0 || inst.Out >= insts || inst.Out1 < 0 || inst.Out1 >= insts {
+				return fmt.Errorf("NFA instruction %v leads out of range", i)
+			}
+			if inst.Op == NFASet && inst.Set == nil {
+				return fmt.Errorf("NFA instruction %v has no set", i)
+			}
+			if (inst.Op == NFACaptureStart || inst.Op == NFACaptureEnd) && (inst.Cap < 0 || inst.Cap >= c.Capsize) {
+				return fmt.Errorf("NFA instruction %v captures out of range", i)
+			}
+		}
+	}
+	return nil
+}
+
+// maxSubtractions limits how deeply nested the subtracted sets in an encoding can be
+const maxSubtractions = 100
+
+func encodeCharSet(e *Encoder, c *CharSet) {
+	e.Bool(c.negate)
+	e.Bool(c.anything)
+	e.Int(len(c.ranges))
+	for _, r := range c.ranges {
+		e.Int(int(r.first))
+		e.Int(int(r.last))
+	}
+	e.Int(len(c.categories))
+	for _, ct := range c.categories {
+		e.Bool(ct.negate)
+		e.String(ct.cat)
+	}
+	e.Int(len(c.masks))
+	for _, m := range c.masks {
+		e.buf = append(e.buf, m.mask, m.value)
+	}
+	e.Bool(c.sub != nil)
+	if c.sub != nil {
+		encodeCharSet(e, c.sub)
+	}
+}
+
+func decodeCharSet(d *Decoder, depth int) *CharSet {
+	c := &CharSet{negate: d.Bool(), anything: d.Bool()}
+	c.ranges = make([]singleRange, d.Len())
+	for i := range c.ranges {
+		c.ranges[i] = singleRange{first: d.rune(), last: d.rune()}
+	}
+	c.categories = make([]category, d.Len())
+	for i := range c.categories {
+		c.categories[i] = category{negate: d.Bool(), cat: d.String()}
+		if cat := c.categories[i].cat; cat != spaceCategoryText && cat != wordCategoryText && unicodeCategories[cat] == nil {
+			d.Fail("unknown category %q", cat)
+		}
+	}
+	c.masks = make([]byteMask, d.Len())
+	for i := range c.masks {
+		if d.err != nil || len(d.buf) < 2 {
+			d.Fail("invalid byte mask")
+			break
+		}
+		c.masks[i] = byteMask{mask: d.buf[0], value: d.buf[1]}
+		d.buf = d.buf[2:]
+	}
+	if d.Bool() {
+		if depth >= maxSubtractions {
+			d.Fail("set subtractions are nested too deeply")
+			return c
+		}
+		c.sub = decodeCharSet(d, depth+1)
+	}
+	return c
+}
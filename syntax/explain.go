@@ -0,0 +1,432 @@
+package syntax
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Explanation describes a node of a parsed pattern for people reviewing it rather than for
+// maintainers of the parser, which is what RegexTree.Dump is for.  It's built from the tree
+// after the parser's optimizations, so runs of characters are merged and simple repeats are
+// folded into the node they repeat.  The fields are tagged so it can be sent as JSON.
+type Explanation struct {
+	// Kind is the kind of node: char, any, set, literal, backreference, anchor, empty, nothing,
+	// sequence, alternation, repeat, capture, group, lookaround, atomic or conditional
+	Kind string `json:"kind"`
+	// Label describes just this node, such as "captured as 'rel32'" or "2 to 4 times"
+	Label string `json:"label"`
+	// Description describes this node and everything under it in words, such as
+	// "byte 0xe8, then any 4 bytes captured as 'rel32'"
+	Description string `json:"description"`
+	// Repeat gives the number of times a repeat matches
+	Repeat *ExplainRepeat `json:"repeat,omitempty"`
+	// Group is the name, or number, of the group a capture, backreference or conditional refers to
+	Group string `json:"group,omitempty"`
+	// Children are the nodes under this one
+	Children []*Explanation `json:"children,omitempty"`
+}
+
+// ExplainRepeat is how many times a repeat matches
+type ExplainRepeat struct {
+	Min int `json:"min"`
+	// Max is -1 if there's no limit
+	Max int `json:"max"`
+	// Lazy is true if as few repeats as possible are tried first
+	Lazy bool `json:"lazy,omitempty"`
+}
+
+// Explain describes the parsed pattern.  The implicit capture of the whole match is left out.
+func (t *RegexTree) Explain() *Explanation {
+	names := make(map[int]string, len(t.Capnames))
+	for name, num := range t.Capnames {
+		names[num] = name
+	}
+	ex := &explainer{names: names, bytes: t.options&ByteRunes != 0}
+
+	root := t.root
+	if root.t == ntCapture && root.m == 0 && len(root.children) == 1 {
+		root = root.children[0]
+	}
+	return ex.explain(root)
+}
+
+// String returns the description of the whole pattern followed by an outline of its nodes
+func (e *Explanation) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(e.Description)
+	sb.WriteByte('\n')
+	e.outline(sb, 0)
+	return sb.String()
+}
+
+func (e *Explanation) outline(sb *strings.Builder, depth int) {
+	fmt.Fprintf(sb, "%v%v: %v\n", strings.Repeat("  ", depth), e.Kind, e.Label)
+	for _, c := range e.Children {
+		c.outline(sb, depth+1)
+	}
+}
+
+// explainer builds the Explanations of the nodes of a tree
+type explainer struct {
+	// names are the names of the named groups, by number
+	names map[int]string
+	// bytes is true for a pattern parsed with ByteRunes, whose characters are bytes
+	bytes bool
+}
+
+func (ex *explainer) explain(n *regexNode) *Explanation {
+	e := &Explanation{}
+	var children []*Explanation
+	for _, c := range n.children {
+		children = append(children, ex.explain(c))
+	}
+	e.Children = children
+
+	switch n.t {
+	case ntOne, ntNotone, ntSet:
+		e.Kind, e.Label = ex.single(n)
+		e.Description = e.Label
+
+	case ntOnerep, ntNotonerep, ntSetrep, ntOneloop, ntNotoneloop, ntSetloop, ntOnelazy, ntNotonelazy, ntSetlazy:
+		e.Kind = "repeat"
+		e.Repeat = ex.repeat(n)
+		kind, one := ex.single(n)
+		e.Children = []*Explanation{{Kind: kind, Label: one, Description: one}}
+		e.Label = ex.times(e.Repeat)
+		if kind == "any" {
+			// any 4 bytes reads better than any byte 4 times
+			e.Description = ex.anyTimes(e.Repeat)
+		} else {
+			e.Description = one + " " + e.Label
+		}
+
+	case ntMulti:
+		e.Kind = "literal"
+		if ex.bytes {
+			hex := make([]string, len(n.str))
+			for i, ch := range n.str {
+				hex[i] = ex.char(ch)
+			}
+			e.Label = "bytes " + strings.Join(hex, " ")
+		} else {
+			e.Label = "the text " + strconv.Quote(string(n.str))
+		}
+		e.Label += ex.caseNote(n)
+		e.Description = e.Label
+
+	case ntRef:
+		e.Kind = "backreference"
+		e.Group = ex.group(n.m)
+		e.Label = fmt.Sprintf("the same %v as group %v", ex.unit(true), ex.groupName(n.m))
+		e.Label += ex.caseNote(n)
+		e.Description = e.Label
+
+	case ntBol, ntEol, ntBoundary, ntNonboundary, ntECMABoundary, ntNonECMABoundary,
+		ntBeginning, ntStart, ntEndZ, ntEnd:
+		e.Kind = "anchor"
+		e.Label = anchorLabels[n.t]
+		e.Description = e.Label
+
+	case ntEmpty:
+		e.Kind, e.Label = "empty", "nothing, which always matches"
+		e.Description = e.Label
+
+	case ntNothing:
+		e.Kind, e.Label = "nothing", "nothing that can match"
+		e.Description = e.Label
+
+	case ntConcatenate:
+		// right to left, the parser stores the sequence back to front
+		if n.options&RightToLeft != 0 {
+			slices.Reverse(children)
+		}
+		e.Kind, e.Label = "sequence", fmt.Sprintf("%v in a row", len(children))
+		e.Description = joinDescriptions(children, ", then ")
+
+	case ntAlternate:
+		e.Kind, e.Label = "alternation", fmt.Sprintf("one of %v", len(children))
+		e.Description = "either " + joinDescriptions(children, ", or ")
+
+	case ntLoop, ntLazyloop:
+		e.Kind = "repeat"
+		e.Repeat = ex.repeat(n)
+		e.Label = ex.times(e.Repeat)
+		e.Description = wrap(children[0]) + " " + e.Label
+
+	case ntCapture:
+		e.Kind = "capture"
+		e.Group = ex.group(n.m)
+		e.Label = "captured as " + ex.groupName(n.m)
+		if n.n != -1 {
+			e.Label += ", and removing the last capture of " + ex.groupName(n.n)
+		}
+		e.Description = wrap(children[0]) + " " + e.Label
+
+	case ntGroup:
+		e.Kind, e.Label = "group", "a group"
+		e.Description = children[0].Description
+
+	case ntRequire, ntPrevent:
+		e.Kind = "lookaround"
+		e.Label = lookaroundLabel(n.t == ntRequire, n.options&RightToLeft != 0)
+		e.Description = e.Label + " " + wrap(children[0])
+		if n.t == ntPrevent && children[0].Kind == "empty" {
+			// (?!) is the usual way to write a pattern that fails
+			e.Label = "never matches"
+			e.Description = "a lookaround that never matches"
+		}
+
+	case ntGreedy:
+		e.Kind, e.Label = "atomic", "without backtracking into it"
+		e.Description = wrap(children[0]) + " " + e.Label
+
+	case ntTestref:
+		e.Kind = "conditional"
+		e.Group = ex.group(n.m)
+		e.Label = "if group " + ex.groupName(n.m) + " has captured"
+		e.Description = fmt.Sprintf("if group %v has captured then %v", ex.groupName(n.m), wrap(children[0]))
+		if len(children) > 1 {
+			e.Description += " else " + wrap(children[1])
+		}
+
+	case ntTestgroup:
+		e.Kind, e.Label = "conditional", "if the first part matches here"
+		cond := wrap(children[0]) + " matches here"
+		if children[0].Kind == "lookaround" {
+			cond = children[0].Description
+		}
+		e.Description = fmt.Sprintf("if %v then %v", cond, wrap(children[1]))
+		if len(children) > 2 {
+			e.Description += " else " + wrap(children[2])
+		}
+
+	default:
+		e.Kind, e.Label = "unknown", typeStr[n.t]
+		e.Description = e.Label
+	}
+	return e
+}
+
+var anchorLabels = map[nodeType]string{
+	ntBol:             "the start of a line",
+	ntEol:             "the end of a line",
+	ntBoundary:        "a word boundary",
+	ntNonboundary:     "anywhere but a word boundary",
+	ntECMABoundary:    "a word boundary",
+	ntNonECMABoundary: "anywhere but a word boundary",
+	ntBeginning:       "the start of the input",
+	ntStart:           "where the search started",
+	ntEndZ:            "the end of the input or a final newline",
+	ntEnd:             "the end of the input",
+}
+
+// lookaroundLabel describes a lookahead, or a lookbehind when behind is true
+func lookaroundLabel(require, behind bool) string {
+	switch {
+	case require && behind:
+		return "preceded by"
+	case require:
+		return "followed by"
+	case behind:
+		return "not preceded by"
+	}
+	return "not followed by"
+}
+
+// single describes the character a one, notone or set node, or a repeat of one, matches
+func (ex *explainer) single(n *regexNode) (kind, label string) {
+	switch n.t {
+	case ntOne, ntOnerep, ntOneloop, ntOnelazy:
+		return "char", ex.unit(false) + " " + ex.char(n.ch) + ex.caseNote(n)
+	case ntNotone, ntNotonerep, ntNotoneloop, ntNotonelazy:
+		return "set", "any " + ex.unit(false) + " but " + ex.char(n.ch) + ex.caseNote(n)
+	}
+	if n.set.String() == AnyClass().String() {
+		return "any", "any " + ex.unit(false)
+	}
+	if n.set.IsSingletonInverse() {
+		return "set", "any " + ex.unit(false) + " but " + ex.char(n.set.SingletonChar()) + ex.caseNote(n)
+	}
+	if len(n.set.masks) == 1 && len(n.set.ranges) == 0 && len(n.set.categories) == 0 && !n.set.IsNegated() && n.set.sub == nil {
+		return "set", fmt.Sprintf("a %v whose %v", ex.unit(false), maskWords(n.set.masks[0])) + ex.caseNote(n)
+	}
+	return "set", fmt.Sprintf("a %v in %v", ex.unit(false), ex.set(n.set)) + ex.caseNote(n)
+}
+
+// set formats set as CharSet.String does, but with the bytes of a pattern parsed with
+// ByteRunes in hex, and byte masks in words
+func (ex *explainer) set(set *CharSet) string {
+	if !ex.bytes && len(set.masks) == 0 {
+		return set.String()
+	}
+
+	var parts []string
+	for _, r := range set.ranges {
+		if r.first == r.last {
+			parts = append(parts, ex.char(r.first))
+		} else {
+			parts = append(parts, ex.char(r.first)+"-"+ex.char(r.last))
+		}
+	}
+	for _, c := range set.categories {
+		parts = append(parts, c.String())
+	}
+	for _, m := range set.masks {
+		parts = append(parts, "("+maskWords(m)+")")
+	}
+	s := strings.Join(parts, " ")
+	if set.IsNegated() {
+		s = "^" + s
+	}
+	if set.sub != nil {
+		s += " -" + ex.set(set.sub)
+	}
+	return "[" + s + "]"
+}
+
+// maskWords describes the bytes m matches, such as "bits under 0xf0 are 0x30 (0x30-0x3f)"
+func maskWords(m byteMask) string {
+	free := ^m.mask
+	first, last := m.value, m.value|free
+	values := fmt.Sprintf("%v bytes from 0x%02x to 0x%02x", 1<<bits.OnesCount8(free), first, last)
+	if free&(free+1) == 0 {
+		// the bits that can be anything are the low ones, so the bytes are a range
+		values = fmt.Sprintf("0x%02x-0x%02x", first, last)
+	}
+	return fmt.Sprintf("bits under 0x%02x are 0x%02x (%v)", m.mask, m.value, values)
+}
+
+// char formats ch as a byte in hex, or as a quoted character
+func (ex *explainer) char(ch rune) string {
+	if ex.bytes {
+		return fmt.Sprintf("0x%02x", ch)
+	}
+	return strconv.QuoteRune(ch)
+}
+
+// unit is what the pattern's characters are called
+func (ex *explainer) unit(plural bool) string {
+	switch {
+	case ex.bytes && plural:
+		return "bytes"
+	case ex.bytes:
+		return "byte"
+	case plural:
+		return "text"
+	}
+	return "character"
+}
+
+func (ex *explainer) caseNote(n *regexNode) string {
+	if n.options&IgnoreCase != 0 {
+		return " in any case"
+	}
+	return ""
+}
+
+// repeat returns how many times the loop or repeat n matches
+func (ex *explainer) repeat(n *regexNode) *ExplainRepeat {
+	r := &ExplainRepeat{Min: n.m, Max: n.n}
+	switch n.t {
+	case ntOnerep, ntNotonerep, ntSetrep:
+		r.Max = n.m
+	case ntOnelazy, ntNotonelazy, ntSetlazy, ntLazyloop:
+		r.Lazy = true
+	}
+	if r.Max == math.MaxInt32 {
+		r.Max = -1
+	}
+	return r
+}
+
+// times describes a number of repeats, as in "2 to 4 times"
+func (ex *explainer) times(r *ExplainRepeat) string {
+	var s string
+	switch {
+	case r.Min == r.Max:
+		s = timesCount(r.Min)
+	case r.Max == -1 && r.Min == 0:
+		s = "any number of times"
+	case r.Max == -1:
+		s = "at least " + timesCount(r.Min)
+	case r.Min == 0 && r.Max == 1:
+		s = "optionally"
+	case r.Min == 0:
+		s = "up to " + timesCount(r.Max)
+	default:
+		s = fmt.Sprintf("%v to %v times", r.Min, r.Max)
+	}
+	if r.Lazy {
+		s += ", as few as possible"
+	}
+	return s
+}
+
+func timesCount(n int) string {
+	if n == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%v times", n)
+}
+
+// anyTimes describes a number of any characters, as in "any 4 bytes"
+func (ex *explainer) anyTimes(r *ExplainRepeat) string {
+	var s string
+	switch {
+	case r.Max == -1 && r.Min == 0:
+		s = "any number of"
+	case r.Max == -1:
+		s = fmt.Sprintf("at least %v", r.Min)
+	case r.Min == r.Max:
+		s = fmt.Sprintf("any %v", r.Min)
+	case r.Min == 0:
+		s = fmt.Sprintf("up to %v", r.Max)
+	default:
+		s = fmt.Sprintf("any %v to %v", r.Min, r.Max)
+	}
+	unit := ex.unit(false) + "s"
+	if r.Min == 1 && r.Max == 1 {
+		unit = ex.unit(false)
+	}
+	s += " " + unit
+	if r.Lazy {
+		s += ", as few as possible"
+	}
+	return s
+}
+
+// group returns the name of group num, or its number if it isn't named
+func (ex *explainer) group(num int) string {
+	if name, ok := ex.names[num]; ok {
+		return name
+	}
+	return strconv.Itoa(num)
+}
+
+// groupName is group quoted for a description, as in 'rel32' or 1
+func (ex *explainer) groupName(num int) string {
+	if name, ok := ex.names[num]; ok {
+		return "'" + name + "'"
+	}
+	return strconv.Itoa(num)
+}
+
+// wrap returns the description of e, in parentheses if it's made up of several parts
+func wrap(e *Explanation) string {
+	if e.Kind == "sequence" || e.Kind == "alternation" || e.Kind == "conditional" {
+		return "(" + e.Description + ")"
+	}
+	return e.Description
+}
+
+func joinDescriptions(es []*Explanation, sep string) string {
+	ds := make([]string, len(es))
+	for i, e := range es {
+		ds[i] = wrap(e)
+	}
+	return strings.Join(ds, sep)
+}
@@ -0,0 +1,244 @@
+package syntax
+
+import (
+	"math"
+	"os"
+)
+
+// ParseHex converts a YARA-style hex string into a parse tree.  The string is
+// a sequence of bytes written as pairs of hex digits, separated by optional whitespace:
+//
+//	CA ?? ?? [2-4] (E8 | E9) ?? ?? ?? ??
+//
+// A ? in place of either digit matches any value of that nibble, so ?? matches any byte.
+// ~XX matches any byte but XX (the digits may be wildcards too).  A jump [n] matches any n
+// bytes, [n-m] between n and m of them, [n-] at least n and [-] any number.  Alternatives are
+// written (AA | BB CC), always in parentheses, and may be nested; they don't capture.
+//
+// The tree always uses the ByteRunes option, so it should be matched against bytes.
+func ParseHex(hex string, op RegexOptions) (*RegexTree, error) {
+	op = (op | ByteRunes) &^ (IgnoreCase | IgnorePatternWhitespace)
+	p := parser{
+		options: op,
+		caps:    make(map[int]int),
+	}
+	p.setPattern(hex)
+
+	// only the whole match is captured
+	p.noteCaptureSlot(0, 0)
+	p.assignNameSlots()
+
+	p.reset(op)
+	root, err := p.scanHexString()
+
+	if err != nil {
+		return nil, err
+	}
+	tree := &RegexTree{
+		root:       root,
+		caps:       p.caps,
+		capnumlist: p.capnumlist,
+		captop:     p.captop,
+		Capnames:   p.capnames,
+		Caplist:    p.capnamelist,
+		options:    op,
+	}
+
+	if tree.options&Debug > 0 {
+		os.Stdout.WriteString(tree.Dump())
+	}
+
+	return tree, nil
+}
+
+// scanHexString is the hex string counterpart of scanRegex
+func (p *parser) scanHexString() (*regexNode, error) {
+	p.startGroup(newRegexNodeMN(ntCapture, p.options, 0, -1))
+
+	for {
+		p.scanHexBlank()
+		if p.charsRight() == 0 {
+			break
+		}
+
+		switch ch := p.moveRightGetChar(); ch {
+		case '(':
+			p.pushGroup()
+			p.startGroup(newRegexNode(ntGroup, p.options))
+			continue
+
+		case '|':
+			// as in YARA, alternatives have to be in parentheses
+			if p.emptyStack() {
+				return nil, p.getErr(ErrUnexpectedHexChar, "|")
+			}
+			p.addAlternate()
+			continue
+
+		case ')':
+			if p.emptyStack() {
+				return nil, p.getErr(ErrUnexpectedParen)
+			}
+			if err := p.addGroup(); err != nil {
+				return nil, err
+			}
+			if err := p.popGroup(); err != nil {
+				return nil, err
+			}
+
+		case '[':
+			min, max, err := p.scanHexJump()
+			if err != nil {
+				return nil, err
+			}
+			p.addUnitSet(hexByteSet(0, 0, false))
+			p.addConcatenate3(false, min, max)
+			continue
+
+		case '~':
+			if err := p.scanHexByte(true); err != nil {
+				return nil, err
+			}
+
+		case '?':
+			if n := p.scanHexWildcards(); n > 1 {
+				// a run of ?? is written as one loop, the same as [\x00-\xff]{n}
+				p.addUnitSet(hexByteSet(0, 0, false))
+				p.addConcatenate3(false, n, n)
+				continue
+			}
+			p.moveLeft()
+			if err := p.scanHexByte(false); err != nil {
+				return nil, err
+			}
+
+		default:
+			p.moveLeft()
+			if err := p.scanHexByte(false); err != nil {
+				return nil, err
+			}
+		}
+
+		p.addConcatenate()
+	}
+
+	if !p.emptyStack() {
+		return nil, p.getErr(ErrMissingParen)
+	}
+
+	if err := p.addGroup(); err != nil {
+		return nil, err
+	}
+
+	return p.unit, nil
+}
+
+// scanHexBlank skips the whitespace between the tokens of a hex string
+func (p *parser) scanHexBlank() {
+	for p.charsRight() > 0 && isSpace(p.rightChar(0)) {
+		p.moveRight(1)
+	}
+}
+
+// scanHexWildcards counts a run of ?? bytes, the first ? having already been read.  If
+// there are two or more it moves past them, otherwise it leaves the position alone.
+func (p *parser) scanHexWildcards() int {
+	startpos := p.textpos()
+	n := 0
+	for p.charsRight() > 0 && p.rightChar(0) == '?' {
+		p.moveRight(1)
+		n++
+		endpos := p.textpos()
+		p.scanHexBlank()
+		if p.charsRight() < 2 || p.rightChar(0) != '?' || p.rightChar(1) != '?' {
+			p.textto(endpos)
+			break
+		}
+		p.moveRight(1)
+	}
+	if n < 2 {
+		p.textto(startpos)
+	}
+	return n
+}
+
+// scanHexByte sets the current unit to a byte written as two hex digits, either of which may be a ?
+func (p *parser) scanHexByte(negate bool) error {
+	if p.charsRight() < 2 {
+		return p.getErr(ErrIncompleteHexByte)
+	}
+
+	var mask, value byte
+	for i := 0; i < 2; i++ {
+		ch := p.moveRightGetChar()
+		mask <<= 4
+		value <<= 4
+		if ch == '?' {
+			continue
+		}
+		d := hexDigit(ch)
+		if d < 0 {
+			return p.getErr(ErrUnexpectedHexChar, string(ch))
+		}
+		mask |= 0xf
+		value |= byte(d)
+	}
+
+	if mask == 0xff && !negate {
+		p.addUnitOne(rune(value))
+		return nil
+	}
+	if mask == 0 && negate {
+		// ~?? would never match
+		return p.getErr(ErrUnexpectedHexChar, "~")
+	}
+	p.addUnitSet(hexByteSet(mask, value, negate))
+	return nil
+}
+
+// scanHexJump scans the rest of a [n], [n-m], [n-] or [-] jump, after the [
+func (p *parser) scanHexJump() (min, max int, err error) {
+	p.scanHexBlank()
+	startpos := p.textpos()
+	if min, err = p.scanDecimal(); err != nil {
+		return 0, 0, err
+	}
+	hasMin := startpos < p.textpos()
+	max = min
+
+	p.scanHexBlank()
+	if p.charsRight() > 0 && p.rightChar(0) == '-' {
+		p.moveRight(1)
+		p.scanHexBlank()
+
+		max = math.MaxInt32
+		if p.charsRight() > 0 && p.rightChar(0) != ']' {
+			startpos = p.textpos()
+			if max, err = p.scanDecimal(); err != nil {
+				return 0, 0, err
+			}
+			if startpos == p.textpos() {
+				return 0, 0, p.getErr(ErrMalformedHexJump)
+			}
+			p.scanHexBlank()
+		}
+	} else if !hasMin {
+		return 0, 0, p.getErr(ErrMalformedHexJump)
+	}
+
+	if p.charsRight() == 0 || p.moveRightGetChar() != ']' {
+		return 0, 0, p.getErr(ErrMalformedHexJump)
+	}
+	if min > max {
+		return 0, 0, p.getErr(ErrInvalidRepeatSize)
+	}
+	return min, max, nil
+}
+
+// hexByteSet returns the set of bytes b where b&mask == value (or, if negate is
+// set, everything else).  A zero mask gives the set of all bytes.
+func hexByteSet(mask, value byte, negate bool) *CharSet {
+	c := &CharSet{negate: negate}
+	c.addMask(mask, value)
+	return c
+}
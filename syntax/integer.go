@@ -0,0 +1,164 @@
+package syntax
+
+import (
+	"strconv"
+)
+
+// byteRange is an inclusive range of byte values
+type byteRange struct {
+	first, last byte
+}
+
+// isIntegerEscape returns true if the pattern continues with the 16le{, 32be{, etc. of an
+// integer escape such as \u32le{0xdeadbeef}.  These only mean something when matching
+// bytes, otherwise \u32be is the unicode escape it has always been.
+func (p *parser) isIntegerEscape() bool {
+	if p.options&ByteRunes == 0 || p.charsRight() < 6 || p.rightChar(0) != 'u' {
+		return false
+	}
+	switch string([]rune{p.rightChar(1), p.rightChar(2)}) {
+	case "16", "32", "64":
+	default:
+		return false
+	}
+	switch string([]rune{p.rightChar(3), p.rightChar(4)}) {
+	case "le", "be":
+	default:
+		return false
+	}
+	return p.rightChar(5) == '{'
+}
+
+// scanIntegerEscape scans \u16le{n}, \u32be{n-m} and so on (starting after the \) into the
+// bytes of the integer, or for a range an alternation of the byte sequences covering it.
+// The result is a single unit, so a quantifier after it repeats the whole integer.
+func (p *parser) scanIntegerEscape() (*regexNode, error) {
+	p.moveRight(1)
+	bits, _ := strconv.Atoi(string([]rune{p.moveRightGetChar(), p.moveRightGetChar()}))
+	littleEndian := p.moveRightGetChar() == 'l'
+	p.moveRight(2)
+
+	lo, err := p.scanIntegerValue(bits, '-')
+	if err != nil {
+		return nil, err
+	}
+	hi := lo
+	if p.rightChar(0) == '-' {
+		p.moveRight(1)
+		if hi, err = p.scanIntegerValue(bits, '}'); err != nil {
+			return nil, err
+		}
+		if lo > hi {
+			return nil, p.getErr(ErrReversedIntRange)
+		}
+	}
+	p.moveRight(1)
+
+	// the bytes are matched exactly
+	opts := p.options &^ IgnoreCase
+	n := bits / 8
+
+	seqs := splitIntegerRange(lo, hi, n)
+	if littleEndian {
+		for _, seq := range seqs {
+			for left, right := 0, len(seq)-1; left < right; left, right = left+1, right-1 {
+				seq[left], seq[right] = seq[right], seq[left]
+			}
+		}
+	}
+
+	if len(seqs) == 1 && lo == hi {
+		str := make([]rune, n)
+		for i, r := range seqs[0] {
+			str[i] = rune(r.first)
+		}
+		return newRegexNodeStr(ntMulti, opts, str), nil
+	}
+
+	alternation := newRegexNode(ntAlternate, opts)
+	for _, seq := range seqs {
+		concatenation := newRegexNode(ntConcatenate, opts)
+		for _, r := range seq {
+			if r.first == r.last {
+				concatenation.addChild(newRegexNodeCh(ntOne, opts, rune(r.first)))
+			} else {
+				cc := &CharSet{}
+				cc.addRange(rune(r.first), rune(r.last))
+				concatenation.addChild(newRegexNodeSet(ntSet, opts, cc))
+			}
+		}
+		alternation.addChild(concatenation.reverseLeft())
+	}
+
+	group := newRegexNode(ntGroup, opts)
+	group.addChild(alternation)
+	return group, nil
+}
+
+// scanIntegerValue scans an unsigned integer (decimal, or hex with a 0x prefix) that
+// fits in bits, up to either the closing } or the given separator
+func (p *parser) scanIntegerValue(bits int, sep rune) (uint64, error) {
+	startpos := p.textpos()
+	for p.charsRight() > 0 && p.rightChar(0) != '}' && p.rightChar(0) != sep {
+		p.moveRight(1)
+	}
+	if p.charsRight() == 0 {
+		return 0, p.getErr(ErrMalformedSlashU)
+	}
+
+	v, err := strconv.ParseUint(string(p.pattern[startpos:p.textpos()]), 0, bits)
+	if err != nil {
+		return 0, p.getErr(ErrMalformedSlashU)
+	}
+	return v, nil
+}
+
+// splitIntegerRange splits the range of n-byte integers between lo and hi into sequences of
+// byte ranges, most significant byte first, that together match exactly the integers in it.
+func splitIntegerRange(lo, hi uint64, n int) [][]byteRange {
+	if n == 1 {
+		return [][]byteRange{{{byte(lo), byte(hi)}}}
+	}
+
+	shift := uint(8 * (n - 1))
+	mask := uint64(1)<<shift - 1
+	loTop, hiTop := lo>>shift, hi>>shift
+	loRest, hiRest := lo&mask, hi&mask
+
+	prefixed := func(top byteRange, rest [][]byteRange) [][]byteRange {
+		for i := range rest {
+			rest[i] = append([]byteRange{top}, rest[i]...)
+		}
+		return rest
+	}
+
+	if loTop == hiTop {
+		return prefixed(byteRange{byte(loTop), byte(loTop)}, splitIntegerRange(loRest, hiRest, n-1))
+	}
+
+	var seqs [][]byteRange
+
+	// the partial range under the lowest top byte
+	if loRest != 0 {
+		seqs = append(seqs, prefixed(byteRange{byte(loTop), byte(loTop)}, splitIntegerRange(loRest, mask, n-1))...)
+		loTop++
+	}
+
+	// the partial range under the highest top byte
+	var tail [][]byteRange
+	if hiRest != mask {
+		tail = prefixed(byteRange{byte(hiTop), byte(hiTop)}, splitIntegerRange(0, hiRest, n-1))
+		hiTop--
+	}
+
+	// every value under the top bytes in between
+	if loTop <= hiTop {
+		seq := []byteRange{{byte(loTop), byte(hiTop)}}
+		for i := 1; i < n; i++ {
+			seq = append(seq, byteRange{0, 0xff})
+		}
+		seqs = append(seqs, seq)
+	}
+
+	return append(seqs, tail...)
+}
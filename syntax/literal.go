@@ -0,0 +1,228 @@
+package syntax
+
+import (
+	"math"
+)
+
+// RequiredLiteral is a string that every match contains, along with how far from the
+// start of the match it can begin.  It lets a search skip straight to the places where
+// the string occurs, then back off to where a match containing it could start.
+type RequiredLiteral struct {
+	BmPrefix  *BmPrefix // the string as a Boyer-Moore machine
+	MinOffset int       // the least distance from the start of a match to the string
+	MaxOffset int       // the greatest distance, or -1 if it's unbounded
+}
+
+// literalInfo describes what a node can match: the range of lengths of the text it
+// matches, and the best literal it must contain with the offsets it can start at.
+type literalInfo struct {
+	min, max        int // max is -1 if unbounded
+	lit             []rune
+	caseInsensitive bool
+	lo, hi          int // hi is -1 if unbounded
+}
+
+// unknownLiteralInfo is what we know about a node we can't analyze: nothing
+var unknownLiteralInfo = literalInfo{min: 0, max: -1}
+
+// getRequiredLiteral finds the longest literal string that every match of the tree must
+// contain, for patterns whose prefix isn't literal (such as .{4}\xE8....\xC3).  It returns
+// nil if there's no such string or it can be arbitrarily far from the start of a match.
+func getRequiredLiteral(tree *RegexTree) *RequiredLiteral {
+	if tree.options&RightToLeft != 0 {
+		return nil
+	}
+
+	info := literalFromNode(tree.root)
+	if info.lit == nil || info.hi < 0 {
+		return nil
+	}
+
+	// the Boyer-Moore machine lowercases the string it's given
+	lit := make([]rune, len(info.lit))
+	copy(lit, info.lit)
+	if len(lit) > MaxPrefixSize {
+		lit = lit[:MaxPrefixSize]
+	}
+
+	// which can't take every string
+	bm := newBmPrefix(lit, info.caseInsensitive, false)
+	if bm == nil {
+		return nil
+	}
+
+	return &RequiredLiteral{
+		BmPrefix:  bm,
+		MinOffset: info.lo,
+		MaxOffset: info.hi,
+	}
+}
+
+func literalFromNode(node *regexNode) literalInfo {
+	caseInsensitive := node.options&IgnoreCase != 0
+
+	switch node.t {
+	case ntOne:
+		return literalInfo{min: 1, max: 1, lit: []rune{node.ch}, caseInsensitive: caseInsensitive}
+
+	case ntMulti:
+		return literalInfo{min: len(node.str), max: len(node.str), lit: node.str, caseInsensitive: caseInsensitive}
+
+	case ntNotone, ntSet:
+		return literalInfo{min: 1, max: 1}
+
+	case ntOnerep, ntOneloop, ntOnelazy:
+		info := literalInfo{min: node.m, max: repeatMax(node.n)}
+		if node.m > 0 {
+			info.lit = repeat(node.ch, node.m)
+			info.caseInsensitive = caseInsensitive
+		}
+		return info
+
+	case ntNotonerep, ntNotoneloop, ntNotonelazy, ntSetrep, ntSetloop, ntSetlazy:
+		return literalInfo{min: node.m, max: repeatMax(node.n)}
+
+	case ntBol, ntEol, ntBoundary, ntNonboundary, ntECMABoundary, ntNonECMABoundary,
+		ntBeginning, ntStart, ntEndZ, ntEnd, ntEmpty, ntNothing, ntRequire, ntPrevent:
+		// zero width
+		return literalInfo{}
+
+	case ntCapture, ntGroup, ntGreedy:
+		return literalFromNode(node.children[0])
+
+	case ntLoop, ntLazyloop:
+		child := literalFromNode(node.children[0])
+		info := literalInfo{min: child.min * node.m, max: -1}
+		if child.max >= 0 && node.n != math.MaxInt32 {
+			info.max = child.max * node.n
+		}
+		if node.m > 0 && child.lit != nil {
+			// the first time round is required
+			info.lit, info.caseInsensitive, info.lo, info.hi = child.lit, child.caseInsensitive, child.lo, child.hi
+		}
+		return info
+
+	case ntConcatenate:
+		info := literalInfo{}
+		for _, n := range node.children {
+			child := literalFromNode(n)
+
+			// a literal that can be any distance in is no use
+			if child.lit != nil && len(child.lit) > len(info.lit) && info.max >= 0 && child.hi >= 0 {
+				info.lit, info.caseInsensitive = child.lit, child.caseInsensitive
+				info.lo, info.hi = info.min+child.lo, info.max+child.hi
+			}
+
+			info.min += child.min
+			if info.max >= 0 && child.max >= 0 {
+				info.max += child.max
+			} else {
+				info.max = -1
+			}
+		}
+		return info
+
+	case ntAlternate:
+		info := literalFromNode(node.children[0])
+		info.lit = nil
+		for _, n := range node.children[1:] {
+			child := literalFromNode(n)
+			info.min = min(info.min, child.min)
+			if info.max >= 0 && child.max >= 0 {
+				info.max = max(info.max, child.max)
+			} else {
+				info.max = -1
+			}
+		}
+		return info
+	}
+
+	// backreferences and conditionals
+	return unknownLiteralInfo
+}
+
+// getLookbehind returns how many characters before the start of a match a search for the
+// tree can look at, through its lookbehinds and the anchors and boundaries that test the
+// character before them, or -1 if a lookbehind can match text of any length.  It's measured
+// from the start for a left-to-right tree; a RightToLeft one reads backwards anyway.
+func getLookbehind(tree *RegexTree) int {
+	return lookbehindFromNode(tree.root)
+}
+
+func lookbehindFromNode(node *regexNode) int {
+	switch node.t {
+	case ntBol, ntBoundary, ntNonboundary, ntECMABoundary, ntNonECMABoundary, ntBeginning:
+		return 1
+
+	case ntRequire, ntPrevent:
+		if node.options&RightToLeft != 0 {
+			// a lookbehind starts wherever it's reached, which is never before the start
+			// of the match, and anything inside it looks back from where it got to
+			width := literalFromNode(node.children[0]).max
+			inner := lookbehindFromNode(node.children[0])
+			if width < 0 || inner < 0 {
+				return -1
+			}
+			return width + inner
+		}
+	}
+
+	reach := 0
+	for _, n := range node.children {
+		r := lookbehindFromNode(n)
+		if r < 0 {
+			return -1
+		}
+		reach = max(reach, r)
+	}
+	return reach
+}
+
+// getLookahead returns how many characters past the end of a match a search for the tree
+// can look at, through its lookaheads and the anchors and boundaries that test the characters
+// after them, or -1 if a lookahead can match text of any length.  Like getLookbehind it's
+// measured for a left-to-right tree.
+func getLookahead(tree *RegexTree) int {
+	return lookaheadFromNode(tree.root)
+}
+
+func lookaheadFromNode(node *regexNode) int {
+	switch node.t {
+	case ntEol, ntBoundary, ntNonboundary, ntECMABoundary, ntNonECMABoundary, ntEnd:
+		return 1
+
+	case ntEndZ:
+		// a final newline is followed by the end
+		return 2
+
+	case ntRequire, ntPrevent:
+		if node.options&RightToLeft == 0 {
+			// a lookahead starts wherever it's reached, which is never past the end of the
+			// match, and anything inside it looks ahead from where it got to
+			width := literalFromNode(node.children[0]).max
+			inner := lookaheadFromNode(node.children[0])
+			if width < 0 || inner < 0 {
+				return -1
+			}
+			return width + inner
+		}
+	}
+
+	reach := 0
+	for _, n := range node.children {
+		r := lookaheadFromNode(n)
+		if r < 0 {
+			return -1
+		}
+		reach = max(reach, r)
+	}
+	return reach
+}
+
+// repeatMax converts the upper bound of a repetition to a length, -1 if it's unbounded
+func repeatMax(n int) int {
+	if n == math.MaxInt32 {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,304 @@
+package syntax
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// NFAOp is the operation of an NFA instruction
+type NFAOp uint8
+
+const (
+	NFAChar         NFAOp = iota // consume Ch
+	NFANotChar                   // consume any character but Ch
+	NFASet                       // consume a character in Set
+	NFAFail                      // never matches
+	NFASplit                     // continue at Out, or failing that at Out1
+	NFACaptureStart              // note where group Cap starts
+	NFACaptureEnd                // capture group Cap from where it started
+	NFAAssert                    // check the zero width Assertion
+	NFAMatch                     // the pattern has matched
+)
+
+// NFAAssertion is a zero width assertion, such as ^ or \b
+type NFAAssertion uint8
+
+const (
+	AssertBol NFAAssertion = iota
+	AssertEol
+	AssertBoundary
+	AssertNonBoundary
+	AssertECMABoundary
+	AssertNonECMABoundary
+	AssertBeginning
+	AssertStart
+	AssertEndZ
+	AssertEnd
+)
+
+// NFAInst is an instruction of an NFA
+type NFAInst struct {
+	Op        NFAOp
+	Out, Out1 int
+	Ch        rune
+	Set       *CharSet
+	Fold      bool // the character is lowercased before it's compared
+	Cap       int  // the capture slot, after mapping sparse group numbers
+	Assertion NFAAssertion
+}
+
+// NFA is a Thompson NFA for a pattern that doesn't need backtracking: it has no
+// backreferences, lookarounds, conditionals, atomic groups or balancing groups, and isn't
+// RightToLeft.  Simulating it takes time linear in the length of the text, and finds the
+// same match (and captures) as the backtracking program would.
+type NFA struct {
+	Insts []NFAInst
+	Start int
+	// Asserts is true if any instruction is an NFAAssert
+	Asserts bool
+}
+
+// MaxNFASize limits the number of instructions in an NFA.  Counted repetitions are
+// expanded, so a pattern such as (?:abc){1000} would need too many and is left to the
+// backtracking program.
+var MaxNFASize = 10000
+
+// errNFATooBig stops the compile when the NFA outgrows MaxNFASize
+var errNFATooBig = fmt.Errorf("NFA is larger than %v instructions", MaxNFASize)
+
+type nfaCompiler struct {
+	nfa       *NFA
+	mapCapnum func(int) int
+}
+
+// compileNFA compiles the tree to an NFA, or returns nil if the pattern needs backtracking
+// or the NFA would be too big.  mapCapnum maps group numbers to capture slots as they are
+// in the program.
+func compileNFA(tree *RegexTree, mapCapnum func(int) int) *NFA {
+	if tree.options&RightToLeft != 0 || !isLinear(tree.root) {
+		return nil
+	}
+
+	c := &nfaCompiler{nfa: &NFA{}, mapCapnum: mapCapnum}
+	match, err := c.emit(NFAInst{Op: NFAMatch})
+	if err != nil {
+		return nil
+	}
+	if c.nfa.Start, err = c.compile(tree.root, match); err != nil {
+		return nil
+	}
+	return c.nfa
+}
+
+// isLinear returns true if the node and everything under it can be matched by an NFA
+func isLinear(node *regexNode) bool {
+	switch node.t {
+	case ntRef, ntRequire, ntPrevent, ntGreedy, ntTestref, ntTestgroup:
+		return false
+	case ntCapture:
+		// balancing groups uncapture
+		if node.n != -1 {
+			return false
+		}
+	case ntLoop, ntLazyloop:
+		// the backtracking program stops a loop when an iteration matches nothing, which
+		// doesn't translate to an NFA, so the loops have to consume something
+		if literalFromNode(node.children[0]).min == 0 {
+			return false
+		}
+	}
+	if node.options&RightToLeft != 0 {
+		return false
+	}
+	for _, child := range node.children {
+		if !isLinear(child) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *nfaCompiler) emit(inst NFAInst) (int, error) {
+	if len(c.nfa.Insts) >= MaxNFASize {
+		return 0, errNFATooBig
+	}
+	c.nfa.Insts = append(c.nfa.Insts, inst)
+	return len(c.nfa.Insts) - 1, nil
+}
+
+// compile emits the instructions for node, which continue at next, returning where they start
+func (c *nfaCompiler) compile(node *regexNode, next int) (int, error) {
+	fold := node.options&IgnoreCase != 0
+
+	switch node.t {
+	case ntOne:
+		return c.emit(NFAInst{Op: NFAChar, Out: next, Ch: node.ch, Fold: fold})
+	case ntNotone:
+		return c.emit(NFAInst{Op: NFANotChar, Out: next, Ch: node.ch, Fold: fold})
+	case ntSet:
+		return c.emit(NFAInst{Op: NFASet, Out: next, Set: node.set, Fold: fold})
+
+	case ntMulti:
+		var err error
+		for i := len(node.str) - 1; i >= 0 && err == nil; i-- {
+			next, err = c.emit(NFAInst{Op: NFAChar, Out: next, Ch: node.str[i], Fold: fold})
+		}
+		return next, err
+
+	case ntOnerep, ntOneloop, ntOnelazy:
+		return c.repeat(NFAInst{Op: NFAChar, Ch: node.ch, Fold: fold}, nil, node.m, node.n, node.t == ntOnelazy, next)
+	case ntNotonerep, ntNotoneloop, ntNotonelazy:
+		return c.repeat(NFAInst{Op: NFANotChar, Ch: node.ch, Fold: fold}, nil, node.m, node.n, node.t == ntNotonelazy, next)
+	case ntSetrep, ntSetloop, ntSetlazy:
+		return c.repeat(NFAInst{Op: NFASet, Set: node.set, Fold: fold}, nil, node.m, node.n, node.t == ntSetlazy, next)
+	case ntLoop, ntLazyloop:
+		return c.repeat(NFAInst{}, node.children[0], node.m, node.n, node.t == ntLazyloop, next)
+
+	case ntEmpty:
+		return next, nil
+	case ntNothing:
+		return c.emit(NFAInst{Op: NFAFail})
+
+	case ntConcatenate:
+		var err error
+		for i := len(node.children) - 1; i >= 0 && err == nil; i-- {
+			next, err = c.compile(node.children[i], next)
+		}
+		return next, err
+
+	case ntAlternate:
+		// a chain of splits, trying each branch in order
+		last, err := c.compile(node.children[len(node.children)-1], next)
+		for i := len(node.children) - 2; i >= 0 && err == nil; i-- {
+			var branch int
+			if branch, err = c.compile(node.children[i], next); err == nil {
+				last, err = c.emit(NFAInst{Op: NFASplit, Out: branch, Out1: last})
+			}
+		}
+		return last, err
+
+	case ntGroup:
+		return c.compile(node.children[0], next)
+
+	case ntCapture:
+		capnum := c.mapCapnum(node.m)
+		end, err := c.emit(NFAInst{Op: NFACaptureEnd, Out: next, Cap: capnum})
+		if err != nil {
+			return 0, err
+		}
+		body, err := c.compile(node.children[0], end)
+		if err != nil {
+			return 0, err
+		}
+		return c.emit(NFAInst{Op: NFACaptureStart, Out: body, Cap: capnum})
+	}
+
+	if a, ok := nfaAssertions[node.t]; ok {
+		c.nfa.Asserts = true
+		return c.emit(NFAInst{Op: NFAAssert, Out: next, Assertion: a})
+	}
+
+	// isLinear should have ruled everything else out
+	return 0, fmt.Errorf("unexpected node type %v in an NFA", node.t)
+}
+
+var nfaAssertions = map[nodeType]NFAAssertion{
+	ntBol:             AssertBol,
+	ntEol:             AssertEol,
+	ntBoundary:        AssertBoundary,
+	ntNonboundary:     AssertNonBoundary,
+	ntECMABoundary:    AssertECMABoundary,
+	ntNonECMABoundary: AssertNonECMABoundary,
+	ntBeginning:       AssertBeginning,
+	ntStart:           AssertStart,
+	ntEndZ:            AssertEndZ,
+	ntEnd:             AssertEnd,
+}
+
+// repeat emits min to max repetitions of either the single character instruction ch
+// or, if body isn't nil, of the body node
+func (c *nfaCompiler) repeat(ch NFAInst, body *regexNode, min, max int, lazy bool, next int) (int, error) {
+	one := func(next int) (int, error) {
+		if body != nil {
+			return c.compile(body, next)
+		}
+		inst := ch
+		inst.Out = next
+		return c.emit(inst)
+	}
+	split := func(take, skip int) NFAInst {
+		if lazy {
+			return NFAInst{Op: NFASplit, Out: skip, Out1: take}
+		}
+		return NFAInst{Op: NFASplit, Out: take, Out1: skip}
+	}
+
+	var err error
+	if max == math.MaxInt32 {
+		// a loop back to a split, taking one more or leaving
+		loop, err := c.emit(NFAInst{})
+		if err != nil {
+			return 0, err
+		}
+		start, err := one(loop)
+		if err != nil {
+			return 0, err
+		}
+		c.nfa.Insts[loop] = split(start, next)
+		next = loop
+		if min > 0 {
+			// the last required repetition leads into the loop
+			next = start
+			min--
+		}
+	} else {
+		// each optional repetition leads to the next, and they all skip to the end
+		end := next
+		for i := min; i < max && err == nil; i++ {
+			var start int
+			if start, err = one(next); err == nil {
+				next, err = c.emit(split(start, end))
+			}
+		}
+	}
+
+	for i := 0; i < min && err == nil; i++ {
+		next, err = one(next)
+	}
+	return next, err
+}
+
+// Dump returns the instructions as a human readable string
+func (n *NFA) Dump() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Start: %v\n", n.Start)
+	for i, inst := range n.Insts {
+		fmt.Fprintf(buf, "%4d: ", i)
+		switch inst.Op {
+		case NFAChar:
+			fmt.Fprintf(buf, "char %v -> %v", Escape(string(inst.Ch)), inst.Out)
+		case NFANotChar:
+			fmt.Fprintf(buf, "notchar %v -> %v", Escape(string(inst.Ch)), inst.Out)
+		case NFASet:
+			fmt.Fprintf(buf, "set %v -> %v", inst.Set.String(), inst.Out)
+		case NFAFail:
+			buf.WriteString("fail")
+		case NFASplit:
+			fmt.Fprintf(buf, "split %v, %v", inst.Out, inst.Out1)
+		case NFACaptureStart:
+			fmt.Fprintf(buf, "capstart %v -> %v", inst.Cap, inst.Out)
+		case NFACaptureEnd:
+			fmt.Fprintf(buf, "capend %v -> %v", inst.Cap, inst.Out)
+		case NFAAssert:
+			fmt.Fprintf(buf, "assert %v -> %v", inst.Assertion, inst.Out)
+		case NFAMatch:
+			buf.WriteString("match")
+		}
+		if inst.Fold {
+			buf.WriteString(" (ignore case)")
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
@@ -107,6 +107,12 @@ const (
 	ErrUnterminatedBracket        = "unterminated [] set"
 	ErrSubtractionMustBeLast      = "a subtraction must be the last element in a character class"
 	ErrReversedCharRange          = "[x-y] range in reverse order"
+	ErrIncompleteHexByte          = "incomplete hex byte"
+	ErrUnexpectedHexChar          = "unexpected %v in hex string"
+	ErrMalformedHexJump           = "malformed [n-m] jump in hex string"
+	ErrMalformedSlashM            = "malformed \\m{mask:value} byte mask escape"
+	ErrMalformedSlashU            = "malformed \\u32le{n} integer escape"
+	ErrReversedIntRange           = "\\u32le{n-m} range in reverse order"
 )
 
 func (e ErrorCode) String() string {
@@ -1126,6 +1132,26 @@ func (p *parser) scanBackslash() (*regexNode, error) {
 
 		return newRegexNodeSet(ntSet, p.options, cc), nil
 
+	case 'u':
+		if p.isIntegerEscape() {
+			return p.scanIntegerEscape()
+		}
+		return p.scanBasicBackslash()
+
+	case 'm':
+		p.moveRight(1)
+		mask, value, err := p.scanByteMask()
+		if err != nil {
+			return nil, err
+		}
+		cc := &CharSet{}
+		cc.addMask(mask, value)
+		if p.useOptionI() {
+			cc.addLowercase()
+		}
+
+		return newRegexNodeSet(ntSet, p.options, cc), nil
+
 	default:
 		return p.scanBasicBackslash()
 	}
@@ -1230,6 +1256,40 @@ func (p *parser) scanBasicBackslash() (*regexNode, error) {
 	return newRegexNodeCh(ntOne, p.options, ch), nil
 }
 
+// Scans {mask:value} for \m{mask:value}, both being one or two hex digits
+func (p *parser) scanByteMask() (mask, value byte, err error) {
+	if p.charsRight() == 0 || p.moveRightGetChar() != '{' {
+		return 0, 0, p.getErr(ErrMalformedSlashM)
+	}
+
+	for i, b := range []*byte{&mask, &value} {
+		digits := 0
+		for p.charsRight() > 0 && digits < 2 {
+			d := hexDigit(p.rightChar(0))
+			if d < 0 {
+				break
+			}
+			*b = *b<<4 | byte(d)
+			p.moveRight(1)
+			digits++
+		}
+
+		close := ':'
+		if i == 1 {
+			close = '}'
+		}
+		if digits == 0 || p.charsRight() == 0 || p.moveRightGetChar() != close {
+			return 0, 0, p.getErr(ErrMalformedSlashM)
+		}
+	}
+
+	if value&^mask != 0 {
+		// bits set in the value that the mask ignores can never match
+		return 0, 0, p.getErr(ErrMalformedSlashM)
+	}
+	return mask, value, nil
+}
+
 // Scans X for \p{X} or \P{X}
 func (p *parser) parseProperty() (string, error) {
 	if p.charsRight() < 3 {
@@ -1429,6 +1489,19 @@ func (p *parser) scanCharSet(caseInsensitive, scanOnly bool) (*CharSet, error) {
 
 				continue
 
+			case 'm':
+				if inRange && !scanOnly {
+					return nil, p.getErr(ErrBadClassInCharRange, ch)
+				}
+				mask, value, err := p.scanByteMask()
+				if err != nil {
+					return nil, err
+				}
+				if !scanOnly {
+					cc.addMask(mask, value)
+				}
+				continue
+
 			case '-':
 				if !scanOnly {
 					cc.addRange(ch, ch)
@@ -607,6 +607,21 @@ func (b *BmPrefix) String() string {
 	return string(b.pattern)
 }
 
+// Len returns the number of runes in the prefix
+func (b *BmPrefix) Len() int {
+	return len(b.pattern)
+}
+
+// Runes returns the prefix, which is in lowercase if it's matched case insensitively
+func (b *BmPrefix) Runes() []rune {
+	return b.pattern
+}
+
+// CaseInsensitive returns true if the prefix is matched case insensitively
+func (b *BmPrefix) CaseInsensitive() bool {
+	return b.caseInsensitive
+}
+
 // Dump returns the contents of the filter as a human readable string
 func (b *BmPrefix) Dump(indent string) string {
 	buf := &bytes.Buffer{}
@@ -638,6 +653,16 @@ func (b *BmPrefix) Dump(indent string) string {
 // The direction and case-sensitivity of the match is determined
 // by the arguments to the RegexBoyerMoore constructor.
 func (b *BmPrefix) Scan(text []rune, index, beglimit, endlimit int) int {
+	return bmScan(b, text, index, beglimit, endlimit)
+}
+
+// ScanBytes is like Scan but searches a byte slice directly, treating
+// each byte as a single rune.
+func (b *BmPrefix) ScanBytes(text []byte, index, beglimit, endlimit int) int {
+	return bmScan(b, text, index, beglimit, endlimit)
+}
+
+func bmScan[T rune | byte](b *BmPrefix, text []T, index, beglimit, endlimit int) int {
 	var (
 		defadv, test, test2         int
 		match, startmatch, endmatch int
@@ -667,7 +692,7 @@ func (b *BmPrefix) Scan(text []rune, index, beglimit, endlimit int) int {
 			return -1
 		}
 
-		chTest = text[test]
+		chTest = rune(text[test])
 
 		if b.caseInsensitive {
 			chTest = unicode.ToLower(chTest)
@@ -704,7 +729,7 @@ func (b *BmPrefix) Scan(text []rune, index, beglimit, endlimit int) int {
 				match -= bump
 				test2 -= bump
 
-				chTest = text[test2]
+				chTest = rune(text[test2])
 
 				if b.caseInsensitive {
 					chTest = unicode.ToLower(chTest)
@@ -745,22 +770,31 @@ func (b *BmPrefix) Scan(text []rune, index, beglimit, endlimit int) int {
 
 // When a regex is anchored, we can do a quick IsMatch test instead of a Scan
 func (b *BmPrefix) IsMatch(text []rune, index, beglimit, endlimit int) bool {
+	return bmIsMatch(b, text, index, beglimit, endlimit)
+}
+
+// IsMatchBytes is like IsMatch but tests a byte slice directly
+func (b *BmPrefix) IsMatchBytes(text []byte, index, beglimit, endlimit int) bool {
+	return bmIsMatch(b, text, index, beglimit, endlimit)
+}
+
+func bmIsMatch[T rune | byte](b *BmPrefix, text []T, index, beglimit, endlimit int) bool {
 	if !b.rightToLeft {
 		if index < beglimit || endlimit-index < len(b.pattern) {
 			return false
 		}
 
-		return b.matchPattern(text, index)
+		return bmMatchPattern(b, text, index)
 	} else {
 		if index > endlimit || index-beglimit < len(b.pattern) {
 			return false
 		}
 
-		return b.matchPattern(text, index-len(b.pattern))
+		return bmMatchPattern(b, text, index-len(b.pattern))
 	}
 }
 
-func (b *BmPrefix) matchPattern(text []rune, index int) bool {
+func bmMatchPattern[T rune | byte](b *BmPrefix, text []T, index int) bool {
 	if len(text)-index < len(b.pattern) {
 		return false
 	}
@@ -768,14 +802,14 @@ func (b *BmPrefix) matchPattern(text []rune, index int) bool {
 	if b.caseInsensitive {
 		for i := 0; i < len(b.pattern); i++ {
 			//Debug.Assert(textinfo.ToLower(_pattern[i]) == _pattern[i], "pattern should be converted to lower case in constructor!");
-			if unicode.ToLower(text[index+i]) != b.pattern[i] {
+			if unicode.ToLower(rune(text[index+i])) != b.pattern[i] {
 				return false
 			}
 		}
 		return true
 	} else {
 		for i := 0; i < len(b.pattern); i++ {
-			if text[index+i] != b.pattern[i] {
+			if rune(text[index+i]) != b.pattern[i] {
 				return false
 			}
 		}
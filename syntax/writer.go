@@ -137,6 +137,12 @@ func (w *writer) codeFromTree(tree *RegexTree) (*Code, error) {
 		bmPrefix = nil
 	}
 
+	// with no literal prefix, look for a literal further in
+	var literal *RequiredLiteral
+	if bmPrefix == nil {
+		literal = getRequiredLiteral(tree)
+	}
+
 	return &Code{
 		Codes:       w.emitted,
 		Strings:     w.stringtable,
@@ -146,7 +152,11 @@ func (w *writer) codeFromTree(tree *RegexTree) (*Code, error) {
 		Capsize:     capsize,
 		FcPrefix:    fcPrefix,
 		BmPrefix:    bmPrefix,
+		Literal:     literal,
+		NFA:         compileNFA(tree, w.mapCapnum),
 		Anchors:     getAnchors(tree),
+		Lookbehind:  getLookbehind(tree),
+		Lookahead:   getLookahead(tree),
 		RightToLeft: rtl,
 	}, nil
 }